@@ -0,0 +1,63 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// The endpoints of S3 Transfer Acceleration, which route requests through the nearest
+// CloudFront edge location, over IPv4 only and dual-stack (see SetDualStack).
+const (
+	accelerateHost          = "s3-accelerate.amazonaws.com"
+	accelerateDualStackHost = "s3-accelerate.dualstack.amazonaws.com"
+)
+
+type s3accelerate struct {
+	XMLName xml.Name `xml:"AccelerateConfiguration"`
+	Status  string   `xml:",omitempty"`
+}
+
+// SetAccelerate switches requests to the Transfer Acceleration endpoint
+// ("bucket.s3-accelerate.amazonaws.com"), or back to the regular one. Acceleration speeds up
+// transfers over long distances, such as uploads from another continent, at an extra cost per
+// gigabyte; it must first be enabled on the bucket with PutBucketAccelerate. It is only
+// available on AWS, for buckets whose names contain no dots, with virtual-hosted addressing.
+func (s3 *S3) SetAccelerate(accelerate bool) error {
+	if s3.express != nil || !isAWSHost(s3.baseHost) {
+		return fmt.Errorf("s3: transfer acceleration is only available for AWS general purpose buckets")
+	}
+
+	if accelerate && strings.Contains(s3.bucket, ".") {
+		return fmt.Errorf("s3: bucket %#v can't be accelerated: its name contains dots", s3.bucket)
+	}
+
+	s3.accelerate = accelerate
+	s3.baseHost = s3.awsHost()
+	s3.endpoint = s3.bucketHost()
+	s3.retarget = &retarget{}
+
+	return nil
+}
+
+// GetBucketAccelerate reports whether Transfer Acceleration is enabled on the bucket.
+func (s3 *S3) GetBucketAccelerate(ctx context.Context) (bool, error) {
+	var xmlResp s3accelerate
+	if er := s3.getConfig(ctx, "accelerate", &xmlResp); er != nil {
+		return false, er
+	}
+
+	return xmlResp.Status == "Enabled", nil
+}
+
+// PutBucketAccelerate enables or suspends Transfer Acceleration on the bucket. It can take up
+// to half an hour for a change to take effect.
+func (s3 *S3) PutBucketAccelerate(ctx context.Context, enabled bool) error {
+	body := s3accelerate{Status: "Suspended"}
+	if enabled {
+		body.Status = "Enabled"
+	}
+
+	return s3.putConfig(ctx, "accelerate", body, nil)
+}
@@ -0,0 +1,110 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+)
+
+// accessPoint is an S3 access point, or a Multi-Region Access Point, standing in for the bucket
+// an S3 addresses. Requests for it go to its own endpoint, and are always signed with Signature
+// Version 4, or Version 4A for a Multi-Region Access Point, which isn't in any one region.
+type accessPoint struct {
+	partition   string // Such as "aws" or "aws-cn".
+	region      string // Empty for a Multi-Region Access Point.
+	account     string
+	name        string // Of a Multi-Region Access Point, its alias, which ends in ".mrap".
+	multiRegion bool
+}
+
+// parseAccessPoint parses bucket if it names an access point by its ARN
+// ("arn:aws:s3:us-west-2:123456789012:accesspoint/name"), or a Multi-Region Access Point by its
+// ARN ("arn:aws:s3::123456789012:accesspoint/alias.mrap") or alias ("alias.mrap"). For ordinary
+// bucket names, including access point aliases, which S3 accepts wherever a bucket name is, it
+// returns nil.
+func parseAccessPoint(bucket string) (*accessPoint, error) {
+	if !strings.HasPrefix(bucket, "arn:") {
+		if strings.HasSuffix(bucket, ".mrap") {
+			return &accessPoint{partition: "aws", name: bucket, multiRegion: true}, nil
+		}
+
+		return nil, nil
+	}
+
+	fields := strings.SplitN(bucket, ":", 6)
+	if len(fields) != 6 || fields[2] != "s3" || fields[4] == "" {
+		return nil, fmt.Errorf("s3: invalid access point ARN %#v", bucket)
+	}
+
+	resource := strings.Split(strings.ReplaceAll(fields[5], ":", "/"), "/")
+	if len(resource) != 2 || resource[0] != "accesspoint" || resource[1] == "" {
+		return nil, fmt.Errorf("s3: unsupported ARN %#v: only access points are supported", bucket)
+	}
+
+	ap := &accessPoint{
+		partition:   fields[1],
+		region:      fields[3],
+		account:     fields[4],
+		name:        resource[1],
+		multiRegion: fields[3] == "",
+	}
+
+	if ap.multiRegion != strings.HasSuffix(ap.name, ".mrap") {
+		return nil, fmt.Errorf("s3: invalid access point ARN %#v: only Multi-Region Access Points have no region", bucket)
+	}
+
+	return ap, nil
+}
+
+// dnsSuffix returns the domain of the AWS endpoints of the access point's partition.
+func (ap *accessPoint) dnsSuffix() string {
+	if ap.partition == "aws-cn" {
+		return "amazonaws.com.cn"
+	}
+
+	return "amazonaws.com"
+}
+
+// host returns the host that requests for the access point are sent to. Unless baseHost, the
+// endpoint of the S3, is one of AWS's, the access point is addressed as a subdomain of it.
+func (ap *accessPoint) host(baseHost string, dualStack bool) string {
+	if ap.multiRegion {
+		return ap.name + ".accesspoint.s3-global." + ap.dnsSuffix()
+	}
+
+	prefix := ap.name + "-" + ap.account
+	if !isAWSHost(baseHost) && !strings.HasSuffix(baseHost, "."+ap.dnsSuffix()) {
+		return prefix + "." + baseHost
+	}
+
+	if dualStack {
+		return prefix + ".s3-accesspoint.dualstack." + ap.region + "." + ap.dnsSuffix()
+	}
+
+	return prefix + ".s3-accesspoint." + ap.region + "." + ap.dnsSuffix()
+}
+
+// setBucket makes the S3 address bucket, which may also name an access point or a directory
+// bucket (see New). An invalid ARN is reported by every request made.
+func (s3 *S3) setBucket(bucket string) {
+	s3.bucket = bucket
+	s3.accessPoint, s3.bucketErr = parseAccessPoint(bucket)
+
+	switch {
+	case strings.HasSuffix(bucket, expressSuffix):
+		s3.setExpressBucket(bucket)
+
+	case s3.express != nil:
+		/* A copy of an S3 for a directory bucket, addressing a general purpose one */
+		s3.express = nil
+		s3.baseHost = s3.awsHost()
+	}
+
+	if ap := s3.accessPoint; ap != nil {
+		s3.pathStyle = false
+		if ap.region != "" {
+			s3.region = ap.region
+		}
+	}
+
+	s3.endpoint = s3.bucketHost()
+}
@@ -0,0 +1,218 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Canned ACLs, which grant a predefined set of permissions. See WithACL.
+const (
+	ACLPrivate                = "private"
+	ACLPublicRead             = "public-read"
+	ACLPublicReadWrite        = "public-read-write"
+	ACLAuthenticatedRead      = "authenticated-read"
+	ACLAwsExecRead            = "aws-exec-read"
+	ACLBucketOwnerRead        = "bucket-owner-read"
+	ACLBucketOwnerFullControl = "bucket-owner-full-control"
+)
+
+// Permissions that can be granted on an object.
+const (
+	PermissionFullControl = "FULL_CONTROL"
+	PermissionRead        = "READ"
+	PermissionReadACP     = "READ_ACP" // Permission to read the object's ACL.
+	PermissionWriteACP    = "WRITE_ACP"
+)
+
+// Kinds of Grantee.
+const (
+	GranteeCanonicalUser = "CanonicalUser"
+	GranteeGroup         = "Group"
+	GranteeEmail         = "AmazonCustomerByEmail"
+)
+
+// URIs of the predefined groups that permissions can be granted to.
+const (
+	GroupAllUsers           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	GroupAuthenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+	GroupLogDelivery        = "http://acs.amazonaws.com/groups/s3/LogDelivery"
+)
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// ACL is the access control list of an object: its owner, and the permissions granted on it.
+type ACL struct {
+	Owner  Owner
+	Grants []Grant
+}
+
+// Owner identifies the AWS account that owns an object.
+type Owner struct {
+	ID          string // The canonical user ID of the account.
+	DisplayName string // Not returned in every region; S3 ignores it when an ACL is put.
+}
+
+// Grant gives a Grantee a permission, such as PermissionRead.
+type Grant struct {
+	Grantee    Grantee
+	Permission string
+}
+
+// Grantee is who a Grant is for. Type says which of the other fields identifies them: ID for
+// GranteeCanonicalUser, URI for GranteeGroup (such as GroupAllUsers), or EmailAddress for
+// GranteeEmail.
+type Grantee struct {
+	Type         string
+	ID           string
+	DisplayName  string
+	URI          string
+	EmailAddress string
+}
+
+type s3owner struct {
+	ID          string
+	DisplayName string `xml:",omitempty"`
+}
+
+/* The grantee's type is an xsi:type attribute, whose namespace has to be spelled out by hand
+ * when marshalling, but is resolved when unmarshalling; hence separate request and response
+ * types */
+
+type s3grantReq struct {
+	Grantee struct {
+		XSI          string `xml:"xmlns:xsi,attr"`
+		Type         string `xml:"xsi:type,attr"`
+		ID           string `xml:",omitempty"`
+		DisplayName  string `xml:",omitempty"`
+		URI          string `xml:",omitempty"`
+		EmailAddress string `xml:",omitempty"`
+	}
+	Permission string
+}
+
+type s3aclReq struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	Owner   s3owner
+	Grants  []s3grantReq `xml:"AccessControlList>Grant"`
+}
+
+type s3aclResp struct {
+	XMLName xml.Name `xml:"AccessControlPolicy"`
+	Owner   s3owner
+	Grants  []struct {
+		Grantee struct {
+			Type         string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+			ID           string
+			DisplayName  string
+			URI          string
+			EmailAddress string
+		}
+		Permission string
+	} `xml:"AccessControlList>Grant"`
+}
+
+// WithACL applies a canned ACL, such as ACLPublicRead or ACLBucketOwnerFullControl, to the object
+// being created. It applies to Put, Copy and StartMultipart. Buckets with Object Ownership set to
+// "bucket owner enforced" (the default for new buckets) reject every ACL but ACLPrivate and
+// ACLBucketOwnerFullControl.
+func WithACL(acl string) RequestOption {
+	return WithHeader("x-amz-acl", acl)
+}
+
+// GetObjectACL returns the access control list of the object at path.
+func (s3 *S3) GetObjectACL(ctx context.Context, path string) (*ACL, error) {
+	values := url.Values{}
+	values.Set("acl", "")
+
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource(path, values), nil)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	xmlBytes, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return nil, er
+	}
+
+	var xmlResp s3aclResp
+	if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+		return nil, fmt.Errorf("s3: invalid ACL for %s: %w", path, er)
+	}
+
+	acl := &ACL{Owner: Owner(xmlResp.Owner)}
+	for _, grant := range xmlResp.Grants {
+		acl.Grants = append(acl.Grants, Grant{
+			Grantee:    Grantee(grant.Grantee),
+			Permission: grant.Permission,
+		})
+	}
+
+	return acl, nil
+}
+
+// PutObjectACL replaces the access control list of the object at path with acl. The owner must
+// be the object's current owner; the simplest way to get it right is to modify the ACL returned
+// by GetObjectACL. Canned ACLs are applied to new objects with WithACL.
+func (s3 *S3) PutObjectACL(ctx context.Context, path string, acl *ACL) (er error) {
+	defer func(start time.Time) {
+		s3.audit("PutObjectACL", path, 0, start, er)
+	}(time.Now())
+
+	body := s3aclReq{Owner: s3owner(acl.Owner)}
+	body.Grants = make([]s3grantReq, len(acl.Grants))
+
+	for i, grant := range acl.Grants {
+		body.Grants[i].Grantee.XSI = xsiNamespace
+		body.Grants[i].Grantee.Type = grant.Grantee.Type
+		body.Grants[i].Grantee.ID = grant.Grantee.ID
+		body.Grants[i].Grantee.DisplayName = grant.Grantee.DisplayName
+		body.Grants[i].Grantee.URI = grant.Grantee.URI
+		body.Grants[i].Grantee.EmailAddress = grant.Grantee.EmailAddress
+		body.Grants[i].Permission = grant.Permission
+	}
+
+	xmlBody, er := xml.Marshal(body)
+	if er != nil {
+		return er
+	}
+
+	md5sum := md5.Sum(xmlBody)
+
+	values := url.Values{}
+	values.Set("acl", "")
+
+	req, er := http.NewRequestWithContext(ctx, "PUT", s3.resource(path, values), bytes.NewReader(xmlBody))
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md5sum[:]))
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(xmlBody)))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Host", req.URL.Host)
+	req.ContentLength = int64(len(xmlBody))
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
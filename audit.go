@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"time"
+)
+
+// AuditEvent describes a single mutating operation performed against S3.
+type AuditEvent struct {
+	Time      time.Time     // When the operation started.
+	Duration  time.Duration // How long the operation took.
+	AccessId  string        // The access key the operation was signed with.
+	Bucket    string
+	Operation string // e.g. "Put", "Copy", "CompleteMultipart", "PutBucketPolicy".
+	Key       string
+	Bytes     int64 // Bytes uploaded by the operation, if any.
+	Err       error // nil if the operation succeeded.
+
+	// Nested is set on the events of the multipart upload that an operation such as Put, PutFile
+	// or Copy makes on its own behalf. The bytes of its parts are also counted by the event of
+	// the enclosing operation, so totals should be taken over events without Nested set.
+	Nested bool
+}
+
+// AuditSink receives an AuditEvent for every mutating operation performed by an S3. Audit is
+// called synchronously once the operation finishes, and may be called from several goroutines
+// at once; implementations that do anything slow should hand the event off elsewhere.
+type AuditSink interface {
+	Audit(event AuditEvent)
+}
+
+// AuditFunc adapts an ordinary function to the AuditSink interface.
+type AuditFunc func(event AuditEvent)
+
+// Audit calls fn(event).
+func (fn AuditFunc) Audit(event AuditEvent) {
+	fn(event)
+}
+
+// SetAuditSink registers sink to receive an AuditEvent for each operation that modifies the
+// bucket: those that create, copy or delete objects (including the multipart operations, which
+// are flagged as Nested when Put or Copy makes them on its own behalf), change their tags, ACLs,
+// retention or legal holds, or restore them, and those that create or delete the bucket or
+// change its configuration (such as PutBucketPolicy and PutBucketVersioning), whose events have
+// an empty Key. Passing nil disables auditing.
+func (s3 *S3) SetAuditSink(sink AuditSink) {
+	s3.auditSink = sink
+}
+
+func (s3 *S3) audit(operation, key string, bytes int64, start time.Time, er error) {
+	s3.auditNested(operation, key, bytes, start, er, false)
+}
+
+// auditNested is like audit, but flags the event as Nested if nested is set.
+func (s3 *S3) auditNested(operation, key string, bytes int64, start time.Time, er error, nested bool) {
+	if s3.auditSink == nil {
+		return
+	}
+
+	s3.auditSink.Audit(AuditEvent{
+		Time:      start,
+		Duration:  time.Since(start),
+		AccessId:  s3.creds.peek().AccessId,
+		Bucket:    s3.bucket,
+		Operation: operation,
+		Key:       key,
+		Bytes:     bytes,
+		Err:       er,
+		Nested:    nested,
+	})
+}
+
+// nestedAudit marks a multipart upload as started by another operation, so that the audit events
+// of its requests are flagged as Nested.
+func nestedAudit() RequestOption {
+	return func(config *requestConfig) {
+		config.nestedAudit = true
+	}
+}
@@ -0,0 +1,267 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// defaultBatchConcurrency is the number of operations Batch runs at once unless BatchConcurrency
+// says otherwise.
+const defaultBatchConcurrency = 8
+
+// DefaultBatchRetryPolicy is the policy for retrying the operations of a Batch unless
+// BatchRetryPolicy says otherwise.
+var DefaultBatchRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.5,
+}
+
+// BatchOp is one operation of a Batch, made by BatchPut, BatchGet, BatchDelete or BatchCopy.
+type BatchOp struct {
+	kind      string // "put", "get", "delete" or "copy".
+	key       string // The key put, got, deleted or copied to.
+	local     string // The local file put or got.
+	srcBucket string // The bucket copied from, or empty for the same one.
+	srcKey    string
+	opts      []RequestOption
+}
+
+// BatchPut uploads the file at localPath to key, as PutFile does.
+func BatchPut(localPath, key string, opts ...RequestOption) BatchOp {
+	return BatchOp{kind: "put", key: key, local: localPath, opts: opts}
+}
+
+// BatchGet downloads the object at key to the file at localPath, as GetToFile does.
+func BatchGet(key, localPath string, opts ...RequestOption) BatchOp {
+	return BatchOp{kind: "get", key: key, local: localPath, opts: opts}
+}
+
+// BatchDelete deletes the object at key, as Delete does.
+func BatchDelete(key string, opts ...RequestOption) BatchOp {
+	return BatchOp{kind: "delete", key: key, opts: opts}
+}
+
+// BatchCopy copies the object at srcKey in srcBucket to dstKey, as CopyFrom does. An empty
+// srcBucket copies from the bucket of the S3 running the batch.
+func BatchCopy(srcBucket, srcKey, dstKey string, opts ...RequestOption) BatchOp {
+	return BatchOp{kind: "copy", key: dstKey, srcBucket: srcBucket, srcKey: srcKey, opts: opts}
+}
+
+// Key returns the key the operation puts, gets, deletes or copies to.
+func (op BatchOp) Key() string {
+	return op.key
+}
+
+func (op BatchOp) String() string {
+	switch op.kind {
+	case "put":
+		return fmt.Sprintf("put %s to %s", op.local, op.key)
+	case "get":
+		return fmt.Sprintf("get %s to %s", op.key, op.local)
+	case "copy":
+		if op.srcBucket != "" {
+			return fmt.Sprintf("copy %s/%s to %s", op.srcBucket, op.srcKey, op.key)
+		}
+
+		return fmt.Sprintf("copy %s to %s", op.srcKey, op.key)
+	}
+
+	return fmt.Sprintf("%s %s", op.kind, op.key)
+}
+
+// BatchResult is the outcome of one operation of a Batch.
+type BatchResult struct {
+	Op       BatchOp
+	Attempts int   // How many times the operation was run; 0 if the batch was cancelled first.
+	Err      error // Why the last attempt failed, or nil if the operation succeeded.
+}
+
+// BatchError is returned by Batch when any of its operations failed, listing them.
+type BatchError struct {
+	Failed []BatchResult // In the order the operations were passed to Batch.
+	Total  int
+}
+
+func (err *BatchError) Error() string {
+	first := err.Failed[0]
+	return fmt.Sprintf("s3: %d of %d batch operations failed, the first to %s: %v", len(err.Failed), err.Total, first.Op, first.Err)
+}
+
+// Unwrap returns the errors of the failed operations, so that errors.Is and errors.As match any
+// of them.
+func (err *BatchError) Unwrap() []error {
+	errs := make([]error, len(err.Failed))
+	for i, result := range err.Failed {
+		errs[i] = result.Err
+	}
+
+	return errs
+}
+
+type batchOptions struct {
+	concurrency int
+	retry       RetryPolicy
+	progress    func(done, total int, result BatchResult)
+}
+
+// BatchOption customizes the behavior of Batch.
+type BatchOption func(*batchOptions)
+
+// BatchConcurrency runs up to n operations of a batch at once, rather than 8.
+func BatchConcurrency(n int) BatchOption {
+	return func(opts *batchOptions) {
+		opts.concurrency = n
+	}
+}
+
+// BatchRetryPolicy retries the operations of a batch according to policy, rather than
+// DefaultBatchRetryPolicy. A MaxAttempts of 1 runs each operation once.
+func BatchRetryPolicy(policy RetryPolicy) BatchOption {
+	return func(opts *batchOptions) {
+		opts.retry = policy
+	}
+}
+
+// BatchProgress has fn called as each operation of a batch finishes, for good, with the number of
+// operations finished so far, including result, and the total. fn is never called concurrently,
+// so it needn't lock anything itself; it should return quickly.
+func BatchProgress(fn func(done, total int, result BatchResult)) BatchOption {
+	return func(opts *batchOptions) {
+		opts.progress = fn
+	}
+}
+
+// Batch runs ops, up to 8 at once (see BatchConcurrency), for backfills, migrations and other bulk
+// transfers. An operation that fails is run again on its own, as DefaultBatchRetryPolicy (or
+// BatchRetryPolicy) allows, once the retries of its requests (see SetRetryPolicy) are exhausted;
+// the failure of one operation doesn't stop the others.
+//
+// The outcome of each operation is returned in the same order as ops. If any failed, a *BatchError
+// listing them is returned too. Cancelling ctx stops the batch: operations not yet started fail
+// with the error of ctx.
+func (s3 *S3) Batch(ctx context.Context, ops []BatchOp, opts ...BatchOption) ([]BatchResult, error) {
+	options := batchOptions{concurrency: defaultBatchConcurrency, retry: DefaultBatchRetryPolicy}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+
+	results := make([]BatchResult, len(ops))
+
+	var (
+		wg           sync.WaitGroup
+		progressLock sync.Mutex
+		done         int
+	)
+
+	finish := func(i int, result BatchResult) {
+		results[i] = result
+
+		if options.progress != nil {
+			progressLock.Lock()
+			done++
+			options.progress(done, len(ops), result)
+			progressLock.Unlock()
+		}
+	}
+
+	indexes := make(chan int)
+
+	for w := 0; w < options.concurrency; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				finish(i, s3.runBatchOp(ctx, ops[i], options.retry))
+			}
+		}()
+	}
+
+	for i := range ops {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			finish(i, BatchResult{Op: ops[i], Err: ctx.Err()})
+		}
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	var failed []BatchResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, &BatchError{Failed: failed, Total: len(ops)}
+	}
+
+	return results, nil
+}
+
+// runBatchOp runs op, running it again when it fails, as policy allows.
+func (s3 *S3) runBatchOp(ctx context.Context, op BatchOp, policy RetryPolicy) BatchResult {
+	result := BatchResult{Op: op}
+
+	for {
+		result.Attempts++
+
+		result.Err = s3.batchOp(ctx, op)
+		if result.Err == nil || result.Attempts >= policy.MaxAttempts || !batchRetryable(result.Err) {
+			return result
+		}
+
+		select {
+		case <-time.After(policy.delay(result.Attempts)):
+		case <-ctx.Done():
+			return result
+		}
+	}
+}
+
+// batchOp runs op once.
+func (s3 *S3) batchOp(ctx context.Context, op BatchOp) error {
+	switch op.kind {
+	case "put":
+		return s3.PutFile(ctx, op.local, op.key, op.opts...)
+	case "get":
+		return s3.GetToFile(ctx, op.key, op.local, op.opts...)
+	case "delete":
+		return s3.Delete(ctx, op.key, op.opts...)
+	case "copy":
+		srcBucket := op.srcBucket
+		if srcBucket == "" {
+			srcBucket = s3.bucket
+		}
+
+		return s3.CopyFrom(ctx, srcBucket, op.srcKey, op.key, op.opts...)
+	}
+
+	return fmt.Errorf("s3: invalid batch operation %#v", op.kind)
+}
+
+// batchRetryable reports whether an operation that failed with er may succeed if it is run again:
+// as for parts (see partRetryable), except that a local file that can't be read or written won't
+// be any different the next time, nor will a missing object.
+func batchRetryable(er error) bool {
+	var pathErr *fs.PathError
+	if errors.As(er, &pathErr) || errors.Is(er, ErrNoSuchKey) {
+		return false
+	}
+
+	return partRetryable(er)
+}
@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+)
+
+// errBodyAbandoned is returned to the transport by a watchedBody once S3 has rejected the
+// request, so that it stops sending the body.
+var errBodyAbandoned = errors.New("s3: request rejected before its body was sent")
+
+// watchedBody wraps the body of a request to track whether all of it has been sent, and to let
+// the rest be abandoned when S3 responds early.
+type watchedBody struct {
+	io.ReadCloser
+	finished  atomic.Bool
+	abandoned atomic.Bool
+}
+
+// watchBody wraps the body of req in a watchedBody, returning nil if it has none.
+func watchBody(req *http.Request) *watchedBody {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	wb := &watchedBody{ReadCloser: req.Body}
+	req.Body = wb
+
+	return wb
+}
+
+func (wb *watchedBody) Read(p []byte) (int, error) {
+	if wb.abandoned.Load() {
+		return 0, errBodyAbandoned
+	}
+
+	n, er := wb.ReadCloser.Read(p)
+	if er == io.EOF {
+		wb.finished.Store(true)
+	}
+
+	return n, er
+}
+
+// abandon makes the transport stop sending whatever is left of the body. It may be called on a
+// nil watchedBody.
+func (wb *watchedBody) abandon() {
+	if wb != nil {
+		wb.abandoned.Store(true)
+	}
+}
+
+// cutShort reports whether er means the connection was closed by the server while the body was
+// still being sent.
+func (wb *watchedBody) cutShort(er error) bool {
+	return wb != nil && !wb.finished.Load() &&
+		(errors.Is(er, syscall.EPIPE) || errors.Is(er, syscall.ECONNRESET))
+}
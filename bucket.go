@@ -0,0 +1,394 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// getConfig fetches the sub-resource named by subresource of the bucket (such as "versioning"),
+// unmarshalling the XML document S3 responds with into v.
+func (s3 *S3) getConfig(ctx context.Context, subresource string, v interface{}) error {
+	xmlBytes, er := s3.getConfigBody(ctx, subresource)
+	if er != nil {
+		return er
+	}
+
+	if er := xml.Unmarshal(xmlBytes, v); er != nil {
+		return fmt.Errorf("s3: invalid %s configuration: %w", subresource, er)
+	}
+
+	return nil
+}
+
+// getConfigBody fetches the sub-resource named by subresource of the bucket as it is.
+func (s3 *S3) getConfigBody(ctx context.Context, subresource string) ([]byte, error) {
+	values := url.Values{}
+	values.Set(subresource, "")
+
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource("", values), nil)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// putConfig replaces the sub-resource named by subresource of the bucket with v, marshalled as
+// XML. Any opts are applied to the request.
+func (s3 *S3) putConfig(ctx context.Context, subresource string, v interface{}, opts []RequestOption) error {
+	xmlBody, er := xml.Marshal(v)
+	if er != nil {
+		return er
+	}
+
+	return s3.putConfigBody(ctx, subresource, xmlBody, "application/xml", opts)
+}
+
+// configNames name the sub-resources of a bucket that hold its configuration, as the audited
+// operations that change them do (such as "PutBucketPolicy").
+var configNames = map[string]string{
+	"accelerate":   "Accelerate",
+	"cors":         "Cors",
+	"lifecycle":    "Lifecycle",
+	"notification": "Notification",
+	"policy":       "Policy",
+	"versioning":   "Versioning",
+	"website":      "Website",
+}
+
+// putConfigBody replaces the sub-resource named by subresource of the bucket with body, a
+// document of contentType. Any opts are applied to the request.
+func (s3 *S3) putConfigBody(ctx context.Context, subresource string, body []byte, contentType string, opts []RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("PutBucket"+configNames[subresource], "", int64(len(body)), start, er)
+	}(time.Now())
+
+	md5sum := md5.Sum(body)
+
+	values := url.Values{}
+	values.Set(subresource, "")
+
+	req, er := http.NewRequestWithContext(ctx, "PUT", s3.resource("", values), bytes.NewReader(body))
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md5sum[:]))
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(body)))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Host", req.URL.Host)
+	req.ContentLength = int64(len(body))
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// deleteConfig removes the sub-resource named by subresource of the bucket.
+func (s3 *S3) deleteConfig(ctx context.Context, subresource string) (er error) {
+	defer func(start time.Time) {
+		s3.audit("DeleteBucket"+configNames[subresource], "", 0, start, er)
+	}(time.Now())
+
+	values := url.Values{}
+	values.Set(subresource, "")
+
+	req, er := http.NewRequestWithContext(ctx, "DELETE", s3.resource("", values), nil)
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// isNoConfig reports whether er is S3 saying the bucket has no configuration of the kind
+// requested, which it reports with a 404 and a code such as NoSuchLifecycleConfiguration.
+func isNoConfig(er error) bool {
+	var s3er *S3Error
+	return errors.As(er, &s3er) && s3er.Code == http.StatusNotFound && s3er.ErrorCode != "NoSuchBucket"
+}
+
+// Versioning states of a bucket, as in BucketVersioning.Status.
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+)
+
+// MFA delete states of a bucket, as in BucketVersioning.MFADelete.
+const (
+	MFADeleteEnabled  = "Enabled"
+	MFADeleteDisabled = "Disabled"
+)
+
+// BucketVersioning is the versioning configuration of a bucket. Empty fields mean the bucket has
+// never been configured, when returned by GetBucketVersioning, and leave the setting unchanged,
+// when passed to PutBucketVersioning.
+type BucketVersioning struct {
+	Status    string // VersioningEnabled or VersioningSuspended.
+	MFADelete string // MFADeleteEnabled or MFADeleteDisabled.
+}
+
+type s3versioning struct {
+	XMLName   xml.Name `xml:"VersioningConfiguration"`
+	Status    string   `xml:",omitempty"`
+	MFADelete string   `xml:"MfaDelete,omitempty"`
+}
+
+// WithMFA authenticates a request with the current code of the MFA device with serial number
+// serial, as S3 requires to change the MFA delete setting of a bucket, and to permanently delete
+// versions from a bucket that has it enabled, with Delete, DeleteVersions or DeletePrefix. Requests
+// with it must be sent over HTTPS.
+func WithMFA(serial, code string) RequestOption {
+	return WithHeader("x-amz-mfa", serial+" "+code)
+}
+
+// GetBucketVersioning returns the versioning configuration of the bucket.
+func (s3 *S3) GetBucketVersioning(ctx context.Context) (*BucketVersioning, error) {
+	var xmlResp s3versioning
+	if er := s3.getConfig(ctx, "versioning", &xmlResp); er != nil {
+		return nil, er
+	}
+
+	return &BucketVersioning{Status: xmlResp.Status, MFADelete: xmlResp.MFADelete}, nil
+}
+
+// PutBucketVersioning changes the versioning configuration of the bucket. Once versioning has
+// been enabled, it can only be suspended, never turned off. Changing MFADelete requires the
+// root account's credentials and WithMFA in opts.
+func (s3 *S3) PutBucketVersioning(ctx context.Context, versioning BucketVersioning, opts ...RequestOption) error {
+	body := s3versioning{Status: versioning.Status, MFADelete: versioning.MFADelete}
+	return s3.putConfig(ctx, "versioning", body, opts)
+}
+
+type s3createBucketReq struct {
+	XMLName            xml.Name `xml:"CreateBucketConfiguration"`
+	LocationConstraint string
+}
+
+type s3locationResp struct {
+	XMLName            xml.Name `xml:"LocationConstraint"`
+	LocationConstraint string   `xml:",chardata"`
+}
+
+// bucketRequest returns a request with method for the bucket itself.
+func (s3 *S3) bucketRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, er := http.NewRequestWithContext(ctx, method, s3.resource("", nil), r)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+
+	if body != nil {
+		req.Header.Set("Content-Length", fmt.Sprintf("%d", len(body)))
+		req.Header.Set("Content-Type", "application/xml")
+		req.ContentLength = int64(len(body))
+	}
+
+	return req, nil
+}
+
+// CreateBucket creates the bucket in region, or in us-east-1 if region is empty. Any opts are
+// applied to the request; WithACL, for example, sets the bucket's canned ACL. Creating a bucket
+// that already exists is an error, with the code BucketAlreadyOwnedByYou if it is yours, and
+// BucketAlreadyExists if it isn't.
+func (s3 *S3) CreateBucket(ctx context.Context, region string, opts ...RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("CreateBucket", "", 0, start, er)
+	}(time.Now())
+
+	/* us-east-1 is where buckets are created without a constraint, and naming it is an error */
+	var body []byte
+	if region != "" && region != "us-east-1" {
+		body, er = xml.Marshal(s3createBucketReq{LocationConstraint: region})
+		if er != nil {
+			return er
+		}
+	}
+
+	req, er := s3.bucketRequest(ctx, "PUT", body)
+	if er != nil {
+		return er
+	}
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// DeleteBucket deletes the bucket, which must be empty, including of old versions of objects and
+// incomplete multipart uploads.
+func (s3 *S3) DeleteBucket(ctx context.Context) (er error) {
+	defer func(start time.Time) {
+		s3.audit("DeleteBucket", "", 0, start, er)
+	}(time.Now())
+
+	req, er := s3.bucketRequest(ctx, "DELETE", nil)
+	if er != nil {
+		return er
+	}
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// HeadBucket checks that the bucket exists and that the credentials may access it, returning the
+// region it is in. A missing bucket is an error matching ErrNoSuchBucket.
+func (s3 *S3) HeadBucket(ctx context.Context) (string, error) {
+	req, er := s3.bucketRequest(ctx, "HEAD", nil)
+	if er != nil {
+		return "", er
+	}
+
+	resp, er := s3.do(req)
+
+	/* A bucket in another region is still there, whether or not the redirect was followed */
+	var s3er *S3Error
+	if errors.As(er, &s3er) && s3er.Code == http.StatusMovedPermanently && s3er.Header.Get("x-amz-bucket-region") != "" {
+		return s3er.Header.Get("x-amz-bucket-region"), nil
+
+	} else if errors.As(er, &s3er) && s3er.Code == http.StatusNotFound {
+		s3er.ErrorCode = "NoSuchBucket"
+		return "", s3er
+
+	} else if er != nil {
+		return "", er
+	}
+	resp.Body.Close()
+
+	return resp.Header.Get("x-amz-bucket-region"), nil
+}
+
+// GetBucketLocation returns the region the bucket is in, such as "eu-west-1".
+func (s3 *S3) GetBucketLocation(ctx context.Context) (string, error) {
+	var xmlResp s3locationResp
+	if er := s3.getConfig(ctx, "location", &xmlResp); er != nil {
+		return "", er
+	}
+
+	/* Buckets in us-east-1 have no constraint, and the oldest in eu-west-1 have its old name */
+	switch xmlResp.LocationConstraint {
+	case "":
+		return "us-east-1", nil
+	case "EU":
+		return "eu-west-1", nil
+	}
+
+	return xmlResp.LocationConstraint, nil
+}
+
+// BucketSummary describes one of the buckets returned by ListBuckets.
+type BucketSummary struct {
+	Name         string
+	CreationDate time.Time
+	Region       string // Empty unless the service reports it, as AWS does for paginated listings.
+}
+
+type s3listBucketsResp struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Buckets []struct {
+		Name         string
+		CreationDate time.Time
+		BucketRegion string
+	} `xml:"Buckets>Bucket"`
+	ContinuationToken string
+}
+
+// ListBuckets returns every bucket owned by the account of the credentials, in the order the
+// service lists them (by name, on AWS). It is an account-level request, which is made the same
+// way whatever bucket the S3 is for; an S3 for none is made with NewAccountS3.
+func (s3 *S3) ListBuckets(ctx context.Context) ([]BucketSummary, error) {
+	var buckets []BucketSummary
+	token := ""
+
+	for {
+		values := url.Values{}
+		if token != "" {
+			values.Set("continuation-token", token)
+		}
+
+		listing, er := s3.listBucketsPage(ctx, values)
+		if er != nil {
+			return nil, er
+		}
+
+		for _, b := range listing.Buckets {
+			buckets = append(buckets, BucketSummary{Name: b.Name, CreationDate: b.CreationDate, Region: b.BucketRegion})
+		}
+
+		/* Only paginated listings have a token; repeating one would loop forever */
+		if listing.ContinuationToken == "" || listing.ContinuationToken == token {
+			return buckets, nil
+		}
+		token = listing.ContinuationToken
+	}
+}
+
+// listBucketsPage requests one page of ListBuckets from the service endpoint, which is the
+// endpoint of an S3 for no bucket.
+func (s3 *S3) listBucketsPage(ctx context.Context, values url.Values) (*s3listBucketsResp, error) {
+	account := s3.WithBucket("")
+
+	req, er := http.NewRequestWithContext(ctx, "GET", account.resource("", values), nil)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+
+	resp, er := account.do(req)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	var xmlResp s3listBucketsResp
+	if er := xml.NewDecoder(resp.Body).Decode(&xmlResp); er != nil {
+		return nil, fmt.Errorf("s3: invalid bucket listing: %w", er)
+	}
+
+	return &xmlResp, nil
+}
@@ -0,0 +1,43 @@
+package s3
+
+import (
+	"bytes"
+	"sync"
+)
+
+// partBuffers holds buffers for the parts of uploads, which are reused across parts and uploads so
+// that services uploading continuously don't allocate (and collect) a buffer for every part.
+// Buffers are kept as pointers, to avoid allocating when they are returned.
+var partBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, partSize)
+		return &buf
+	},
+}
+
+// getPartBuffer returns a buffer of size bytes from the pool, whose content is arbitrary. Pooled
+// buffers too small for a part size set with SetPartSize are left in the pool, and a new one is
+// allocated instead.
+func getPartBuffer(size int64) []byte {
+	pooled := partBuffers.Get().(*[]byte)
+	if int64(cap(*pooled)) < size {
+		partBuffers.Put(pooled)
+		return make([]byte, size)
+	}
+
+	return (*pooled)[:size]
+}
+
+// putPartBuffer returns buf, from getPartBuffer, to the pool. It must no longer be used, not even
+// by a request still being sent.
+func putPartBuffer(buf []byte) {
+	buf = buf[:cap(buf)]
+	partBuffers.Put(&buf)
+}
+
+// putBuffers holds the buffers Put reads content of unknown length into.
+var putBuffers = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Checksum algorithms S3 can check uploads against and store with objects, for
+// SetChecksumAlgorithm. The CRCs are much cheaper to compute than the digests.
+const (
+	ChecksumCRC32  = "CRC32"
+	ChecksumCRC32C = "CRC32C"
+	ChecksumSHA1   = "SHA1"
+	ChecksumSHA256 = "SHA256"
+)
+
+// checksumAlgorithms lists the checksum algorithms, in the order objects are checked for them.
+var checksumAlgorithms = []string{ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256}
+
+// newChecksum returns a hash computing the checksum algorithm, or nil if there is no such
+// algorithm.
+func newChecksum(algorithm string) hash.Hash {
+	switch algorithm {
+	case ChecksumCRC32:
+		return crc32.NewIEEE()
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumSHA1:
+		return sha1.New()
+	case ChecksumSHA256:
+		return sha256.New()
+	}
+
+	return nil
+}
+
+// checksumHeaderName returns the header a checksum computed with algorithm is sent in.
+func checksumHeaderName(algorithm string) string {
+	return "x-amz-checksum-" + strings.ToLower(algorithm)
+}
+
+// get returns the checksum computed with algorithm.
+func (c Checksums) get(algorithm string) string {
+	switch algorithm {
+	case ChecksumCRC32:
+		return c.CRC32
+	case ChecksumCRC32C:
+		return c.CRC32C
+	case ChecksumSHA1:
+		return c.SHA1
+	case ChecksumSHA256:
+		return c.SHA256
+	}
+
+	return ""
+}
+
+// SetChecksumAlgorithm makes uploads carry a checksum computed with algorithm (ChecksumCRC32,
+// ChecksumCRC32C, ChecksumSHA1 or ChecksumSHA256), which S3 checks the content it receives
+// against and stores with the object, and makes Get verify downloads against the checksum S3
+// returns. Unlike Content-MD5, the checksum outlives the upload, so the same check can be made end
+// to end: by GetObjectAttributes, by Get, or by anything else reading the object. Passing an empty
+// algorithm disables both.
+//
+// Put sends the checksum of content it can read twice, when r is an io.ReadSeeker or when Put
+// buffers r itself; other single-request uploads are sent without one. Multipart uploads started
+// with the algorithm set need a checksum for every part, so AddPart fails for a part it can't
+// read twice. Streamed uploads (see SetStreamingUploads) compute the checksum as they are sent,
+// so they have neither limitation.
+//
+// Get verifies objects as they are read, whatever algorithm they were uploaded with: the returned
+// reader reports an error wrapping ErrVerification instead of io.EOF if the content doesn't
+// match. Objects uploaded in parts only have a checksum of the checksums of their parts, and are
+// not verified, nor are partial (ranged) downloads.
+func (s3 *S3) SetChecksumAlgorithm(algorithm string) error {
+	if algorithm != "" && newChecksum(algorithm) == nil {
+		return fmt.Errorf("s3: unknown checksum algorithm %#v", algorithm)
+	}
+
+	s3.checksumAlgorithm = algorithm
+	return nil
+}
+
+// computeChecksum returns the base64-encoded checksum of the next size bytes of r, computed with
+// algorithm, and rewinds r back to where it was. It returns an empty checksum if r can't be
+// rewound.
+func computeChecksum(algorithm string, r io.Reader, size int64) (string, error) {
+	h := newChecksum(algorithm)
+
+	if ok, er := hashRewind(r, size, h); !ok {
+		return "", er
+	}
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyObjectChecksum returns body, the body of resp, wrapped to verify it against the checksum
+// S3 returned with it, if it returned one of the whole object.
+func (s3 *S3) verifyObjectChecksum(resp *http.Response, body io.ReadCloser, path string) io.ReadCloser {
+	if s3.checksumAlgorithm == "" || resp.StatusCode != http.StatusOK || resp.Uncompressed {
+		return body
+	}
+
+	if resp.Header.Get("x-amz-checksum-type") == "COMPOSITE" {
+		return body
+	}
+
+	for _, algorithm := range checksumAlgorithms {
+		header := checksumHeaderName(algorithm)
+
+		/* The checksums of multipart objects end with the number of parts, like their ETags */
+		value := resp.Header.Get(header)
+		if value == "" || strings.Contains(value, "-") {
+			continue
+		}
+
+		expected, er := base64.StdEncoding.DecodeString(value)
+		if er != nil {
+			continue
+		}
+
+		return &verifyingReader{
+			ReadCloser: body,
+			hash:       newChecksum(algorithm),
+			expected:   expected,
+			path:       path,
+			source:     "its " + header + " checksum",
+		}
+	}
+
+	return body
+}
@@ -0,0 +1,168 @@
+package s3
+
+import (
+	"net/http"
+)
+
+// Option configures the S3 made by New. Each corresponds to one of the Set methods, which can
+// still be called afterwards; options that fail return the error the Set method would.
+type Option func(*S3) error
+
+// New allocates a new S3 for bucket, configured by opts in order. Unless WithCredentials,
+// WithCredentialsProvider or WithAnonymous says otherwise, its credentials are found the way the
+// AWS tools find them (see DefaultCredentialsChain); it is otherwise configured as NewS3
+// configures one. An error is returned if any of the options is invalid.
+//
+// bucket may also be the ARN of an access point ("arn:aws:s3:us-west-2:123456789012:accesspoint/name"),
+// whose requests are sent to its endpoint and signed for its region with Signature Version 4, or
+// the ARN or alias of a Multi-Region Access Point ("alias.mrap"), whose requests are signed for
+// every region with Signature Version 4A. Access point aliases are used like bucket names. An
+// ARN of anything else is an error. An empty bucket makes an S3 for account-level requests, such
+// as ListBuckets (see NewAccountS3).
+func New(bucket string, opts ...Option) (*S3, error) {
+	s3 := newS3(bucket)
+
+	for _, opt := range opts {
+		if er := opt(s3); er != nil {
+			return nil, er
+		}
+	}
+
+	if s3.bucketErr != nil {
+		return nil, s3.bucketErr
+	}
+
+	if s3.creds == nil {
+		s3.creds = newCredentialStore("", "")
+		s3.SetCredentialsProvider(DefaultCredentialsChain())
+	}
+
+	return s3, nil
+}
+
+// WithCredentials signs requests with an access key, and the session token of temporary
+// credentials if token isn't empty (see SetCredentials).
+func WithCredentials(accessId, secret, token string) Option {
+	return func(s3 *S3) error {
+		s3.creds = newCredentialStore(accessId, secret)
+		s3.SetCredentials(accessId, secret, token)
+
+		return nil
+	}
+}
+
+// WithCredentialsProvider gets the credentials requests are signed with from provider (see
+// SetCredentialsProvider).
+func WithCredentialsProvider(provider CredentialsProvider) Option {
+	return func(s3 *S3) error {
+		s3.creds = newCredentialStore("", "")
+		s3.SetCredentialsProvider(provider)
+
+		return nil
+	}
+}
+
+// WithAnonymous sends requests unsigned, as NewAnonymousS3 does.
+func WithAnonymous() Option {
+	return func(s3 *S3) error {
+		s3.creds = newCredentialStore("", "")
+		s3.anonymous = true
+
+		return nil
+	}
+}
+
+// WithRegion addresses a bucket in region, as the WithRegion method does.
+func WithRegion(region string) Option {
+	return func(s3 *S3) error {
+		*s3 = *s3.WithRegion(region)
+		return nil
+	}
+}
+
+// WithEndpoint sends requests to an S3-compatible service (see SetEndpoint).
+func WithEndpoint(endpoint string) Option {
+	return func(s3 *S3) error {
+		return s3.SetEndpoint(endpoint)
+	}
+}
+
+// WithPathStyle puts the bucket in the path of requests rather than the host (see SetPathStyle).
+func WithPathStyle() Option {
+	return func(s3 *S3) error {
+		s3.SetPathStyle(true)
+		return nil
+	}
+}
+
+// WithHTTPClient sends requests with client (see SetHTTPClient).
+func WithHTTPClient(client *http.Client) Option {
+	return func(s3 *S3) error {
+		s3.SetHTTPClient(client)
+		return nil
+	}
+}
+
+// WithRetry retries failed requests according to policy (see SetRetryPolicy).
+func WithRetry(policy RetryPolicy) Option {
+	return func(s3 *S3) error {
+		s3.SetRetryPolicy(policy)
+		return nil
+	}
+}
+
+// WithTimeouts bounds the phases of each request (see SetTimeouts).
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(s3 *S3) error {
+		s3.SetTimeouts(timeouts)
+		return nil
+	}
+}
+
+// WithPartSize splits multipart uploads into parts of size bytes (see SetPartSize).
+func WithPartSize(size int64) Option {
+	return func(s3 *S3) error {
+		return s3.SetPartSize(size)
+	}
+}
+
+// WithUploadConcurrency sends up to n parts of a multipart upload at once (see
+// SetUploadConcurrency).
+func WithUploadConcurrency(n int) Option {
+	return func(s3 *S3) error {
+		s3.SetUploadConcurrency(n)
+		return nil
+	}
+}
+
+// WithSignatureV4 signs requests with Signature Version 4 (see SetSignatureV4).
+func WithSignatureV4() Option {
+	return func(s3 *S3) error {
+		s3.SetSignatureV4(true)
+		return nil
+	}
+}
+
+// WithSigner signs requests with signer (see SetSigner).
+func WithSigner(signer Signer) Option {
+	return func(s3 *S3) error {
+		s3.SetSigner(signer)
+		return nil
+	}
+}
+
+// WithUserAgent appends suffix to the User-Agent of requests (see SetUserAgent).
+func WithUserAgent(suffix string) Option {
+	return func(s3 *S3) error {
+		s3.SetUserAgent(suffix)
+		return nil
+	}
+}
+
+// WithMiddleware wraps the transport requests are sent with in mw (see SetMiddleware).
+func WithMiddleware(mw ...Middleware) Option {
+	return func(s3 *S3) error {
+		s3.SetMiddleware(mw...)
+		return nil
+	}
+}
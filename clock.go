@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// clock tracks how far the local clock is from S3's. It is shared by every copy of an S3.
+type clock struct {
+	offset int64 // Nanoseconds to add to the local time; accessed atomically.
+}
+
+// now returns the current time, corrected by any offset measured with SyncClock.
+func (s3 *S3) now() time.Time {
+	return time.Now().Add(time.Duration(atomic.LoadInt64(&s3.clock.offset)))
+}
+
+// ClockOffset returns the correction currently applied to the local clock when signing requests
+// and presigned URLs.
+func (s3 *S3) ClockOffset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s3.clock.offset))
+}
+
+// SyncClock measures the difference between the local clock and S3's by making a request to
+// the bucket and reading the Date header of the response. From then on, requests are signed and
+// presigned URLs expire relative to S3's notion of the time, which keeps hosts with badly set
+// clocks from having their requests rejected or their URLs expire at the wrong time. The offset
+// is only accurate to about a second.
+//
+// Calling SyncClock is seldom necessary: a request that S3 rejects with RequestTimeTooSkewed
+// corrects the offset from the time S3 reports with the error, and is signed and sent again.
+func (s3 *S3) SyncClock(ctx context.Context) error {
+	req, er := http.NewRequestWithContext(ctx, "HEAD", s3.resource("", nil), nil)
+	if er != nil {
+		return er
+	}
+
+	s3.applySigningHost(req)
+
+	before := time.Now()
+
+	/* The request isn't signed (the local clock may be too far off for that to work),
+	 * but S3 dates its response even when it rejects a request */
+	resp, er := s3.httpClient().Do(req)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	after := time.Now()
+
+	serverTime, er := http.ParseTime(resp.Header.Get("Date"))
+	if er != nil {
+		return er
+	}
+
+	local := before.Add(after.Sub(before) / 2)
+	atomic.StoreInt64(&s3.clock.offset, int64(serverTime.Sub(local)))
+
+	return nil
+}
+
+// s3skewError is the part of the error document S3 returns for RequestTimeTooSkewed that says
+// what time it is.
+type s3skewError struct {
+	ServerTime string
+}
+
+// correctClock measures the offset of the local clock from S3's given err, a RequestTimeTooSkewed
+// error, from the ServerTime of its body or else its Date header, as SyncClock would have. It
+// reports false if err says neither, leaving the offset as it was.
+func (s3 *S3) correctClock(err *S3Error) bool {
+	body := s3skewError{}
+	xml.Unmarshal(err.Body, &body)
+
+	serverTime, er := time.Parse(time.RFC3339, body.ServerTime)
+	if er != nil {
+		if serverTime, er = http.ParseTime(err.Header.Get("Date")); er != nil {
+			return false
+		}
+	}
+
+	atomic.StoreInt64(&s3.clock.offset, int64(time.Until(serverTime)))
+	return true
+}
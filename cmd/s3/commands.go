@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lye/s3/v2"
+)
+
+func runPut(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error {
+	contentType := flags.String("content-type", "", "the Content-Type of the object; guessed from the file if empty")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		return errUsage
+	}
+
+	local := flags.Arg(0)
+
+	client, key, er := opts.clientFor(flags.Arg(1))
+	if er != nil {
+		return er
+	}
+
+	if local == "-" {
+		if key == "" || strings.HasSuffix(key, "/") {
+			return fmt.Errorf("s3: a key is needed to upload standard input")
+		}
+
+		return client.PutStream(ctx, os.Stdin, key, *contentType)
+	}
+
+	/* Like cp, a key ending in a slash names a "directory" to upload into */
+	if key == "" || strings.HasSuffix(key, "/") {
+		key += filepath.Base(local)
+	}
+
+	var putOpts []s3.RequestOption
+	if *contentType != "" {
+		putOpts = append(putOpts, s3.WithHeader("Content-Type", *contentType))
+	}
+
+	return client.PutFile(ctx, local, key, putOpts...)
+}
+
+func runGet(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error {
+	flags.Parse(args)
+
+	if flags.NArg() != 1 && flags.NArg() != 2 {
+		return errUsage
+	}
+
+	client, key, er := opts.clientFor(flags.Arg(0))
+	if er != nil {
+		return er
+	}
+
+	if key == "" || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("s3: %#v names no object", flags.Arg(0))
+	}
+
+	local := flags.Arg(1)
+	if local == "" {
+		local = pathpkg.Base(key)
+	}
+
+	if local != "-" {
+		if info, er := os.Stat(local); er == nil && info.IsDir() {
+			local = filepath.Join(local, pathpkg.Base(key))
+		}
+
+		return client.GetToFile(ctx, key, local)
+	}
+
+	r, _, er := client.Get(ctx, key)
+	if er != nil {
+		return er
+	}
+	defer r.Close()
+
+	_, er = io.Copy(os.Stdout, r)
+	return er
+}
+
+// printObject prints a line of the output of ls describing obj.
+func printObject(obj s3.ObjectSummary) {
+	fmt.Printf("%s %12d %s\n", obj.LastModified.Local().Format("2006-01-02 15:04:05"), obj.Size, obj.Key)
+}
+
+func runList(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error {
+	recursive := flags.Bool("r", false, "list every object under the prefix, rather than rolling up \"directories\"")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 && !*recursive {
+		return listBuckets(ctx, opts)
+	} else if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	client, prefix, er := opts.clientFor(flags.Arg(0))
+	if er != nil {
+		return er
+	}
+
+	if *recursive {
+		return client.Walk(ctx, prefix, func(obj s3.ObjectSummary) error {
+			printObject(obj)
+			return nil
+		})
+	}
+
+	objects, prefixes, er := client.List(ctx, prefix, "/")
+	if er != nil {
+		return er
+	}
+
+	for _, prefix := range prefixes {
+		fmt.Printf("%32s %s\n", "PRE", prefix)
+	}
+
+	for _, obj := range objects {
+		printObject(obj)
+	}
+
+	return nil
+}
+
+// listBuckets prints the buckets of the account, as ls does without a URL.
+func listBuckets(ctx context.Context, opts *options) error {
+	client, er := opts.client("")
+	if er != nil {
+		return er
+	}
+
+	buckets, er := client.ListBuckets(ctx)
+	if er != nil {
+		return er
+	}
+
+	for _, b := range buckets {
+		fmt.Printf("%s %s\n", b.CreationDate.Local().Format("2006-01-02 15:04:05"), b.Name)
+	}
+
+	return nil
+}
+
+func runRemove(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error {
+	recursive := flags.Bool("r", false, "delete every object under each prefix")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+
+	/* The keys to delete are gathered by bucket, to delete them in batches */
+	keys := map[string][]string{}
+	clients := map[string]*s3.S3{}
+	buckets := []string{}
+
+	for _, arg := range flags.Args() {
+		bucket, key, er := parseURL(arg)
+		if er != nil {
+			return er
+		}
+
+		if clients[bucket] == nil {
+			if clients[bucket], er = opts.client(bucket); er != nil {
+				return er
+			}
+
+			buckets = append(buckets, bucket)
+		}
+
+		if !*recursive {
+			if key == "" {
+				return fmt.Errorf("s3: %#v names no object; use -r to delete a prefix", arg)
+			}
+
+			keys[bucket] = append(keys[bucket], key)
+			continue
+		}
+
+		er = clients[bucket].Walk(ctx, key, func(obj s3.ObjectSummary) error {
+			keys[bucket] = append(keys[bucket], obj.Key)
+			return nil
+		})
+
+		if er != nil {
+			return er
+		}
+	}
+
+	failed := 0
+
+	for _, bucket := range buckets {
+		results, er := clients[bucket].DeleteMulti(ctx, keys[bucket])
+		if er != nil {
+			return er
+		}
+
+		for _, result := range results {
+			if result.Deleted {
+				fmt.Printf("deleted s3://%s/%s\n", bucket, result.Key)
+			} else {
+				fmt.Fprintf(os.Stderr, "s3: deleting s3://%s/%s failed: %s: %s\n", bucket, result.Key, result.Code, result.Message)
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("s3: %d objects could not be deleted", failed)
+	}
+
+	return nil
+}
+
+func runSync(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error {
+	deleteExtraneous := flags.Bool("delete", false, "delete what exists at the destination but not the source")
+	dryRun := flags.Bool("dryrun", false, "print what would be done without doing it")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		return errUsage
+	}
+
+	var syncOpts []s3.SyncOption
+	if *deleteExtraneous {
+		syncOpts = append(syncOpts, s3.SyncDelete())
+	}
+
+	if *dryRun {
+		syncOpts = append(syncOpts, s3.SyncDryRun())
+	}
+
+	source, destination := flags.Arg(0), flags.Arg(1)
+
+	var result *s3.SyncResult
+
+	switch {
+	case strings.HasPrefix(source, "s3://") && !strings.HasPrefix(destination, "s3://"):
+		client, prefix, er := opts.clientFor(source)
+		if er != nil {
+			return er
+		}
+
+		if result, er = client.SyncDown(ctx, prefix, destination, syncOpts...); er != nil {
+			return er
+		}
+
+	case !strings.HasPrefix(source, "s3://") && strings.HasPrefix(destination, "s3://"):
+		client, prefix, er := opts.clientFor(destination)
+		if er != nil {
+			return er
+		}
+
+		if result, er = client.SyncUp(ctx, source, prefix, syncOpts...); er != nil {
+			return er
+		}
+
+	default:
+		return fmt.Errorf("s3: sync needs one local directory and one s3:// URL")
+	}
+
+	verb := ""
+	if *dryRun {
+		verb = "(dry run) "
+	}
+
+	for _, path := range result.Transferred {
+		fmt.Printf("%scopied %s\n", verb, path)
+	}
+
+	for _, path := range result.Deleted {
+		fmt.Printf("%sdeleted %s\n", verb, path)
+	}
+
+	return nil
+}
+
+func runPresign(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error {
+	expires := flags.Duration("expires", time.Hour, "how long the URL is valid for")
+	put := flags.Bool("put", false, "presign an upload rather than a download")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	client, key, er := opts.clientFor(flags.Arg(0))
+	if er != nil {
+		return er
+	}
+
+	var url string
+	if *put {
+		url, er = client.SignedPutURL(key, "", *expires)
+	} else {
+		url, er = client.SignedURL(key, *expires)
+	}
+
+	if er != nil {
+		return er
+	}
+
+	fmt.Println(url)
+	return nil
+}
+
+func runStat(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error {
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	client, key, er := opts.clientFor(flags.Arg(0))
+	if er != nil {
+		return er
+	}
+
+	info, er := client.Stat(ctx, key)
+	if er != nil {
+		return er
+	}
+
+	fmt.Printf("Key:           %s\n", info.Key)
+	fmt.Printf("Size:          %d\n", info.Size)
+	fmt.Printf("ETag:          %s\n", info.ETag)
+	fmt.Printf("Content-Type:  %s\n", info.ContentType)
+	fmt.Printf("Last-Modified: %s\n", info.LastModified.Format(time.RFC3339))
+
+	if info.StorageClass != "" {
+		fmt.Printf("Storage class: %s\n", info.StorageClass)
+	}
+
+	if info.VersionId != "" {
+		fmt.Printf("Version:       %s\n", info.VersionId)
+	}
+
+	if info.PartsCount > 0 {
+		fmt.Printf("Parts:         %d\n", info.PartsCount)
+	}
+
+	for name, value := range info.Metadata {
+		fmt.Printf("Metadata:      %s=%s\n", name, value)
+	}
+
+	return nil
+}
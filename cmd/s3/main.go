@@ -0,0 +1,170 @@
+// Command s3 copies files to and from S3 and manages the objects in a bucket, for smoke-testing
+// and scripting without installing the AWS CLI:
+//
+//	s3 [flags] <command> [arguments]
+//
+// Objects are named by URLs of the form s3://bucket/key. The commands are:
+//
+//	put [-content-type type] <file|-> s3://bucket/key   upload a file, or standard input
+//	get s3://bucket/key [file|-]                        download an object
+//	ls [-r] s3://bucket[/prefix]                        list objects, or buckets without a URL
+//	rm [-r] s3://bucket/key...                          delete objects, or all under a prefix
+//	sync [-delete] [-dryrun] <source> <destination>     mirror a directory to a prefix, or back
+//	presign [-expires d] [-put] s3://bucket/key         print a presigned URL
+//	stat s3://bucket/key                                describe an object
+//
+// Credentials are found as the AWS tools find them (see s3.DefaultCredentialsChain): in the
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables, the shared credentials file,
+// or the role of the container or instance. The region is taken from -region, AWS_REGION or
+// AWS_DEFAULT_REGION, and the endpoint of S3-compatible services from -endpoint or
+// AWS_ENDPOINT_URL_S3.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/lye/s3/v2"
+)
+
+// options are the flags that apply to every command.
+type options struct {
+	endpoint  string
+	region    string
+	pathStyle bool
+	anonymous bool
+}
+
+// command is a subcommand, which is given its own flags and the arguments that follow its name.
+type command struct {
+	usage string
+	run   func(ctx context.Context, opts *options, flags *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"put":     {"put [-content-type type] <file|-> s3://bucket/key", runPut},
+	"get":     {"get s3://bucket/key [file|-]", runGet},
+	"ls":      {"ls [-r] [s3://bucket[/prefix]]", runList},
+	"rm":      {"rm [-r] s3://bucket/key...", runRemove},
+	"sync":    {"sync [-delete] [-dryrun] <source> <destination>", runSync},
+	"presign": {"presign [-expires duration] [-put] s3://bucket/key", runPresign},
+	"stat":    {"stat s3://bucket/key", runStat},
+}
+
+// errUsage is returned by commands given the wrong arguments.
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	opts := &options{}
+
+	flag.StringVar(&opts.endpoint, "endpoint", os.Getenv("AWS_ENDPOINT_URL_S3"), "the base URL of an S3-compatible service")
+	flag.StringVar(&opts.region, "region", defaultRegion(), "the region of the bucket")
+	flag.BoolVar(&opts.pathStyle, "path-style", false, "address buckets in the path rather than the host name")
+	flag.BoolVar(&opts.anonymous, "anonymous", false, "send requests without credentials, for public buckets")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "s3: unknown command %#v\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: s3 %s\n", cmd.usage)
+		flags.PrintDefaults()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if er := cmd.run(ctx, opts, flags, flag.Args()[1:]); errors.Is(er, errUsage) {
+		flags.Usage()
+		os.Exit(2)
+	} else if er != nil {
+		fmt.Fprintln(os.Stderr, er)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: s3 [flags] <command> [arguments]\n\ncommands:\n")
+	for _, name := range []string{"put", "get", "ls", "rm", "sync", "presign", "stat"} {
+		fmt.Fprintf(os.Stderr, "  s3 %s\n", commands[name].usage)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func defaultRegion() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// parseURL splits an s3://bucket/key URL into the bucket and key.
+func parseURL(arg string) (bucket, key string, er error) {
+	rest, ok := strings.CutPrefix(arg, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("s3: %#v is not an s3://bucket/key URL", arg)
+	}
+
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("s3: %#v names no bucket", arg)
+	}
+
+	return bucket, key, nil
+}
+
+// client returns an S3 for bucket, configured by opts.
+func (opts *options) client(bucket string) (*s3.S3, error) {
+	client := s3.NewS3FromProvider(bucket, s3.DefaultCredentialsChain())
+	if opts.anonymous {
+		client = s3.NewAnonymousS3(bucket)
+	}
+
+	client.SetSignatureV4(true)
+	client.SetPathStyle(opts.pathStyle)
+	client.SetUserAgent("cmd/s3")
+
+	if opts.endpoint != "" {
+		if er := client.SetEndpoint(opts.endpoint); er != nil {
+			return nil, er
+		}
+	}
+
+	if opts.region != "" {
+		client = client.WithRegion(opts.region)
+	}
+
+	return client, nil
+}
+
+// clientFor parses an s3://bucket/key URL, returning an S3 for the bucket and the key.
+func (opts *options) clientFor(arg string) (*s3.S3, string, error) {
+	bucket, key, er := parseURL(arg)
+	if er != nil {
+		return nil, "", er
+	}
+
+	client, er := opts.client(bucket)
+	return client, key, er
+}
@@ -0,0 +1,130 @@
+package s3
+
+import (
+	"strings"
+	"time"
+)
+
+// ClientConfig is a snapshot of how an S3 is configured, as returned by Config. It can be
+// marshalled (to JSON, say) and logged or dumped by support tooling; secrets are never included,
+// and the access key ID is redacted to its last four characters.
+type ClientConfig struct {
+	Bucket      string `json:"bucket"`
+	Endpoint    string `json:"endpoint"`
+	Scheme      string `json:"scheme"`
+	Region      string `json:"region,omitempty"`
+	PathStyle   bool   `json:"path_style"`
+	Accelerate  bool   `json:"accelerate"`
+	DualStack   bool   `json:"dual_stack"`
+	SigningHost string `json:"signing_host,omitempty"`
+	Express     bool   `json:"express"`
+
+	Anonymous            bool   `json:"anonymous"`
+	AccessId             string `json:"access_id,omitempty"`
+	SessionToken         bool   `json:"session_token"`
+	CredentialsRefresh   bool   `json:"credentials_refresh"`
+	SecondaryCredentials bool   `json:"secondary_credentials"`
+
+	RetryPolicy       RetryPolicy   `json:"retry_policy"`
+	PartRetryPolicy   RetryPolicy   `json:"part_retry_policy"`
+	RedirectPolicy    bool          `json:"redirect_policy"` // Whether a custom redirect policy is set.
+	Logger            bool          `json:"logger"`          // Whether requests are logged.
+	Metrics           bool          `json:"metrics"`         // Whether a metrics collector is set.
+	Tracer            bool          `json:"tracer"`          // Whether operations are traced.
+	Timeout           time.Duration `json:"timeout"`         // The timeout of the HTTP client, if any.
+	Timeouts          Timeouts      `json:"timeouts"`
+	PutBufferLimit    int64         `json:"put_buffer_limit"`
+	ExpectContinue    int64         `json:"expect_continue"` // The smallest upload sent with Expect: 100-continue, or 0 if none are.
+	UploadConcurrency int           `json:"upload_concurrency"`
+	PartSize          int64         `json:"part_size"`
+	MaxInFlight       int           `json:"max_in_flight"` // Zero if requests aren't limited.
+	ChecksumHash      string        `json:"checksum_hash,omitempty"`
+	ChecksumAlgorithm string        `json:"checksum_algorithm,omitempty"`
+	SignatureV4       bool          `json:"signature_v4"`
+	Signer            bool          `json:"signer"` // Whether SetSigner replaced the S3's own signatures.
+	StreamingUploads  bool          `json:"streaming_uploads"`
+	Strict            bool          `json:"strict"`
+	DefaultOptions    int           `json:"default_options"` // How many default request options are set.
+	Middleware        int           `json:"middleware"`      // How many middleware are set.
+	UserAgent         string        `json:"user_agent"`
+	ClockOffset       time.Duration `json:"clock_offset"`
+}
+
+// Config returns a snapshot of the effective configuration of the S3, with defaults filled in,
+// for diagnosing how a client is set up.
+func (s3 *S3) Config() ClientConfig {
+	config := ClientConfig{
+		Bucket:      s3.bucket,
+		Endpoint:    s3.currentEndpoint(),
+		Scheme:      s3.scheme,
+		Region:      s3.currentRegion(),
+		PathStyle:   s3.pathStyle,
+		Accelerate:  s3.accelerate,
+		DualStack:   s3.dualStack,
+		SigningHost: s3.signingHost,
+		Express:     s3.express != nil,
+
+		Anonymous: s3.anonymous,
+
+		RetryPolicy:       s3.retryPolicy(),
+		PartRetryPolicy:   s3.partRetryPolicy(),
+		RedirectPolicy:    s3.redirectPolicy != nil,
+		Logger:            s3.logger != nil,
+		Metrics:           s3.metrics != nil,
+		Tracer:            s3.tracer != nil,
+		Timeout:           s3.httpClient().Timeout,
+		Timeouts:          s3.timeouts,
+		PutBufferLimit:    s3.putBufferLimit,
+		ExpectContinue:    max(s3.expectContinueThreshold(), 0),
+		UploadConcurrency: s3.uploadConcurrency,
+		PartSize:          s3.uploadPartSize(),
+		MaxInFlight:       cap(s3.inFlight),
+		Strict:            s3.strict,
+		ChecksumAlgorithm: s3.checksumAlgorithm,
+		SignatureV4:       s3.sigV4,
+		Signer:            s3.signer != nil,
+		StreamingUploads:  s3.streaming,
+		DefaultOptions:    len(s3.defaultOpts),
+		Middleware:        len(s3.middleware),
+		UserAgent:         s3.userAgent(),
+		ClockOffset:       s3.ClockOffset(),
+	}
+
+	if config.Scheme == "" {
+		config.Scheme = "https"
+	}
+
+	if config.PutBufferLimit == 0 {
+		config.PutBufferLimit = defaultPutBufferLimit
+	}
+
+	if config.UploadConcurrency < 1 {
+		config.UploadConcurrency = 1
+	}
+
+	if s3.checksum != nil {
+		config.ChecksumHash = s3.checksum.name
+	}
+
+	if !s3.anonymous {
+		s3.creds.lock.RLock()
+		creds := s3.creds.current
+		config.CredentialsRefresh = s3.creds.refresh != nil
+		config.SecondaryCredentials = s3.creds.secondary != nil
+		s3.creds.lock.RUnlock()
+
+		config.AccessId = redact(creds.AccessId)
+		config.SessionToken = creds.Token != ""
+	}
+
+	return config
+}
+
+// redact hides all but the last four characters of s.
+func redact(s string) string {
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
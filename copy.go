@@ -0,0 +1,224 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The limits copies are made within are variables only so that tests can copy objects in parts
+// without them being gigabytes in size.
+var (
+	// maxCopySize is the largest object S3 will duplicate with a single copy request.
+	maxCopySize int64 = 5 * 1024 * 1024 * 1024
+
+	// copyPartSize is the size of each range copied with UploadPartCopy when an object is too
+	// large for a single copy request. At 1GB per part, the 10,000 part limit covers the
+	// 5TB maximum object size.
+	copyPartSize int64 = 1024 * 1024 * 1024
+
+	// copyConcurrency is the number of UploadPartCopy requests kept in flight at once.
+	copyConcurrency = 4
+)
+
+// copiedHeaders are the response headers that S3 keeps, along with the user metadata, when it
+// copies an object in a single request, and so that copies made in parts carry over themselves.
+var copiedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Expires",
+}
+
+// copySource returns the value of the x-amz-copy-source header referring to path in bucket.
+func copySource(bucket, path string) string {
+	return uriEncode("/"+bucket+"/"+path, false)
+}
+
+// forBucket returns s3 itself, or if bucket is another one, a copy that operates on it with
+// redirect state of its own (see WithBucket).
+func (s3 *S3) forBucket(bucket string) *S3 {
+	if bucket == s3.bucket {
+		return s3
+	}
+
+	return s3.WithBucket(bucket)
+}
+
+// Copy duplicates the object at srcPath to dstPath without the data passing through the
+// client. The Content-Type of the source object is preserved.
+//
+// S3 refuses to copy objects larger than 5GB in a single request. Copy checks the size of the
+// source with Head first, and for large objects performs a multipart upload whose parts are
+// copied from ranges of the source, several at a time.
+//
+// Any opts are applied to the copy request (or the initiation of the multipart upload). The new
+// object keeps the user metadata and response headers (such as Cache-Control) of the source,
+// unless WithReplaceMetadata is passed to set new ones; headers given in opts replace those of
+// the source either way.
+func (s3 *S3) Copy(ctx context.Context, srcPath, dstPath string, opts ...RequestOption) error {
+	return s3.CopyFrom(ctx, s3.bucket, srcPath, dstPath, opts...)
+}
+
+// CopyFrom is like Copy, but copies srcPath from srcBucket, which may be any bucket that the
+// credentials can read. Cross-bucket copies are not supported for directory buckets.
+func (s3 *S3) CopyFrom(ctx context.Context, srcBucket, srcPath, dstPath string, opts ...RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("Copy", dstPath, 0, start, er)
+	}(time.Now())
+
+	ctx, endSpan := s3.startSpan(ctx, "Copy", dstPath)
+	defer func() {
+		endSpan(er)
+	}()
+
+	if s3.express != nil && srcBucket != s3.bucket {
+		return fmt.Errorf("s3: cannot copy between directory buckets")
+	}
+
+	header, er := s3.forBucket(srcBucket).Head(ctx, srcPath)
+	if er != nil {
+		return er
+	}
+
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if size > maxCopySize {
+		return s3.copyMultipart(ctx, srcBucket, srcPath, dstPath, size, header, opts)
+	}
+
+	req, er := http.NewRequestWithContext(ctx, "PUT", s3.resource(dstPath, nil), nil)
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("x-amz-copy-source", copySource(srcBucket, srcPath))
+
+	config := newRequestConfig(opts)
+
+	/* Replacing the metadata replaces the Content-Type too, unless it is carried over */
+	if config.header.Get("x-amz-metadata-directive") == "REPLACE" {
+		req.Header.Set("Content-Type", header.Get("Content-Type"))
+	}
+
+	/* S3 ignores the tags of a copy unless told to replace those of the source */
+	if config.header.Get("x-amz-tagging") != "" {
+		req.Header.Set("x-amz-tagging-directive", "REPLACE")
+	}
+
+	resp, er := s3.do(req, append(append([]RequestOption{}, opts...), withEmbeddedErrors())...)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// copyMultipart copies the object at srcPath in srcBucket, of size bytes and described by srcHeader
+// (the response to Head), to dstPath in parts, several at a time.
+func (s3 *S3) copyMultipart(ctx context.Context, srcBucket, srcPath, dstPath string, size int64, srcHeader http.Header, opts []RequestOption) (er error) {
+	/* S3 only carries over the metadata of the source when it copies it in one request, so
+	 * copies in parts carry it over themselves; headers in opts are applied after these, and so
+	 * replace them */
+	header := http.Header{}
+	if contentType := srcHeader.Get("Content-Type"); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	if newRequestConfig(opts).header.Get("x-amz-metadata-directive") != "REPLACE" {
+		for _, name := range copiedHeaders {
+			if value := srcHeader.Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+
+		for name, value := range Metadata(srcHeader) {
+			header.Set(metaPrefix+name, value)
+		}
+	}
+
+	contentType := header.Get("Content-Type")
+
+	mp, er := s3.startMultipart(ctx, dstPath, header, append(opts, nestedAudit()))
+	if er != nil {
+		return er
+	}
+	defer func() {
+		if er != nil {
+			er = mp.abandon(er)
+		}
+	}()
+
+	nParts := int((size + copyPartSize - 1) / copyPartSize)
+	mp.etags = make([]string, nParts)
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+
+	parts := make(chan int)
+
+	for i := 0; i < copyConcurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for partNumber := range parts {
+				start := int64(partNumber-1) * copyPartSize
+				end := start + copyPartSize - 1
+
+				if end >= size {
+					end = size - 1
+				}
+
+				if er := mp.copyPart(partNumber, srcBucket, srcPath, start, end); er != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = er
+					}
+					errLock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for partNumber := 1; partNumber <= nParts; partNumber++ {
+		errLock.Lock()
+		failed := firstErr != nil
+		errLock.Unlock()
+
+		if failed {
+			break
+		}
+
+		parts <- partNumber
+	}
+
+	close(parts)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return mp.Complete(contentType)
+}
+
+// Move renames srcPath to dstPath by copying it on the S3 side and then deleting the original.
+// The original is only deleted if the copy succeeds; if the delete fails, the object will exist
+// at both paths when Move returns its error. Any opts are applied to the copy.
+func (s3 *S3) Move(ctx context.Context, srcPath, dstPath string, opts ...RequestOption) error {
+	if er := s3.Copy(ctx, srcPath, dstPath, opts...); er != nil {
+		return er
+	}
+
+	return s3.Delete(ctx, srcPath)
+}
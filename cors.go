@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+)
+
+// CORSRule lets browsers on other origins make requests to the bucket, such as uploads with
+// presigned URLs or POST policies from a web page.
+type CORSRule struct {
+	ID             string
+	AllowedOrigins []string // Origins such as "https://example.com", or "*" for any.
+	AllowedMethods []string // Any of GET, PUT, POST, DELETE and HEAD.
+	AllowedHeaders []string // Request headers the browser may send, such as "Content-Type", or "*".
+	ExposeHeaders  []string // Response headers scripts may read, such as "ETag".
+	MaxAgeSeconds  int      // How long browsers may cache the response to a preflight request.
+}
+
+type s3corsRule struct {
+	ID             string   `xml:",omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  int      `xml:",omitempty"`
+}
+
+type s3cors struct {
+	XMLName xml.Name     `xml:"CORSConfiguration"`
+	Rules   []s3corsRule `xml:"CORSRule"`
+}
+
+// GetBucketCORS returns the CORS rules of the bucket, which are empty if it has none.
+func (s3 *S3) GetBucketCORS(ctx context.Context) ([]CORSRule, error) {
+	var xmlResp s3cors
+	if er := s3.getConfig(ctx, "cors", &xmlResp); isNoConfig(er) {
+		return []CORSRule{}, nil
+
+	} else if er != nil {
+		return nil, er
+	}
+
+	rules := make([]CORSRule, 0, len(xmlResp.Rules))
+	for _, rule := range xmlResp.Rules {
+		rules = append(rules, CORSRule(rule))
+	}
+
+	return rules, nil
+}
+
+// PutBucketCORS replaces the CORS rules of the bucket with rules.
+func (s3 *S3) PutBucketCORS(ctx context.Context, rules []CORSRule) error {
+	body := s3cors{}
+	for _, rule := range rules {
+		body.Rules = append(body.Rules, s3corsRule(rule))
+	}
+
+	return s3.putConfig(ctx, "cors", body, nil)
+}
+
+// DeleteBucketCORS removes every CORS rule from the bucket.
+func (s3 *S3) DeleteBucketCORS(ctx context.Context) error {
+	return s3.deleteConfig(ctx, "cors")
+}
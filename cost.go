@@ -0,0 +1,207 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CostModel prices S3 usage, in whatever currency its figures are given in. Requests are billed
+// in two classes: Tier 1 covers PUT, COPY, POST and LIST requests, Tier 2 covers GET and most
+// others, and DELETE is free.
+type CostModel struct {
+	Tier1PerThousand float64 // Price of 1,000 Tier 1 requests.
+	Tier2PerThousand float64 // Price of 1,000 Tier 2 requests.
+	EgressPerGB      float64 // Price of transferring a GB out of S3.
+
+	// StoragePerGBMonth prices a GB stored for a month in each storage class, by the name S3
+	// uses for it ("STANDARD", "STANDARD_IA", ...). It is used to price the bytes uploaded.
+	StoragePerGBMonth map[string]float64
+}
+
+// DefaultCostModel holds the published on-demand prices of S3 in us-east-1, in US dollars.
+// Egress is priced at the first tier of transfer out to the internet; transfer within the region
+// is free, so it overestimates the cost of traffic from EC2.
+var DefaultCostModel = CostModel{
+	Tier1PerThousand: 0.005,
+	Tier2PerThousand: 0.0004,
+	EgressPerGB:      0.09,
+	StoragePerGBMonth: map[string]float64{
+		"STANDARD":            0.023,
+		"INTELLIGENT_TIERING": 0.023,
+		"STANDARD_IA":         0.0125,
+		"ONEZONE_IA":          0.01,
+		"GLACIER_IR":          0.004,
+		"GLACIER":             0.0036,
+		"DEEP_ARCHIVE":        0.00099,
+	},
+}
+
+// CostTally accumulates the billable usage of one group of objects. Parts of multipart uploads
+// don't carry the storage class of the object, so they are counted as STANDARD.
+type CostTally struct {
+	Tier1Requests int64
+	Tier2Requests int64
+	BytesOut      int64            // Bytes downloaded.
+	BytesIn       map[string]int64 // Bytes uploaded, by storage class.
+}
+
+// Cost estimates what the usage in tally costs under model: the requests and transfer, plus a
+// month of storing the bytes uploaded.
+func (tally CostTally) Cost(model CostModel) float64 {
+	cost := float64(tally.Tier1Requests)/1000*model.Tier1PerThousand +
+		float64(tally.Tier2Requests)/1000*model.Tier2PerThousand +
+		float64(tally.BytesOut)/(1<<30)*model.EgressPerGB
+
+	for class, bytes := range tally.BytesIn {
+		cost += float64(bytes) / (1 << 30) * model.StoragePerGBMonth[class]
+	}
+
+	return cost
+}
+
+// CostAccountant tallies the requests an S3 makes, grouped by key prefix, so that spending can be
+// attributed to the features of an application as it happens. It is safe for concurrent use, and
+// may be shared by several S3s.
+type CostAccountant struct {
+	group func(key string) string
+
+	lock    sync.Mutex
+	tallies map[string]*CostTally
+}
+
+// NewCostAccountant returns a CostAccountant that tallies requests under the group returned by
+// group for the key they address (which is empty for requests about the bucket, such as
+// listings). If group is nil, everything is tallied under the first element of the key's path.
+func NewCostAccountant(group func(key string) string) *CostAccountant {
+	if group == nil {
+		group = func(key string) string {
+			if idx := strings.Index(key, "/"); idx >= 0 {
+				return key[:idx+1]
+			}
+
+			return ""
+		}
+	}
+
+	return &CostAccountant{group: group, tallies: map[string]*CostTally{}}
+}
+
+// SetCostAccountant registers acct to tally every request the S3 sends, including retries.
+// Passing nil disables accounting.
+func (s3 *S3) SetCostAccountant(acct *CostAccountant) {
+	s3.costs = acct
+}
+
+// Snapshot returns a copy of the tallies so far, by group.
+func (acct *CostAccountant) Snapshot() map[string]CostTally {
+	acct.lock.Lock()
+	defer acct.lock.Unlock()
+
+	snapshot := make(map[string]CostTally, len(acct.tallies))
+
+	for group, tally := range acct.tallies {
+		copied := *tally
+		copied.BytesIn = make(map[string]int64, len(tally.BytesIn))
+
+		for class, bytes := range tally.BytesIn {
+			copied.BytesIn[class] = bytes
+		}
+
+		snapshot[group] = copied
+	}
+
+	return snapshot
+}
+
+// Reset clears the tallies, as at the start of a new accounting period.
+func (acct *CostAccountant) Reset() {
+	acct.lock.Lock()
+	acct.tallies = map[string]*CostTally{}
+	acct.lock.Unlock()
+}
+
+// tally returns the tally for the group key belongs to; the lock must be held.
+func (acct *CostAccountant) tally(key string) *CostTally {
+	group := acct.group(key)
+
+	tally, ok := acct.tallies[group]
+	if !ok {
+		tally = &CostTally{BytesIn: map[string]int64{}}
+		acct.tallies[group] = tally
+	}
+
+	return tally
+}
+
+// requestKey returns the key that req addresses.
+func (s3 *S3) requestKey(req *http.Request) string {
+	key := strings.TrimPrefix(req.URL.Path, "/")
+
+	if s3.pathStyle {
+		key = strings.TrimPrefix(strings.TrimPrefix(key, s3.bucket), "/")
+	}
+
+	return key
+}
+
+// account tallies req, which has been sent, and arranges for the bytes read from the body of
+// resp (which may be nil if the request failed) to be tallied too.
+func (s3 *S3) account(req *http.Request, resp *http.Response) {
+	acct := s3.costs
+	if acct == nil {
+		return
+	}
+
+	key := s3.requestKey(req)
+
+	acct.lock.Lock()
+	defer acct.lock.Unlock()
+
+	tally := acct.tally(key)
+
+	switch req.Method {
+	case "DELETE":
+	case "PUT", "POST":
+		tally.Tier1Requests++
+	case "GET":
+		/* Listings are billed as Tier 1; only listings of parts address an object */
+		if key == "" || req.URL.Query().Has("uploadId") {
+			tally.Tier1Requests++
+		} else {
+			tally.Tier2Requests++
+		}
+	default:
+		tally.Tier2Requests++
+	}
+
+	if size := payloadLength(req); req.Method == "PUT" && size > 0 && resp != nil {
+		class := StorageClass(req.Header)
+
+		tally.BytesIn[class] += size
+	}
+
+	if resp != nil && req.Method == "GET" {
+		resp.Body = &countingBody{ReadCloser: resp.Body, count: func(n int64) {
+			acct.lock.Lock()
+			acct.tally(key).BytesOut += n
+			acct.lock.Unlock()
+		}}
+	}
+}
+
+// countingBody reports the number of bytes read through it to count.
+type countingBody struct {
+	io.ReadCloser
+	count func(n int64)
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, er := cb.ReadCloser.Read(p)
+	if n > 0 {
+		cb.count(int64(n))
+	}
+
+	return n, er
+}
@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"sync"
+	"time"
+)
+
+// credentialsRefreshWindow is how long before their expiration credentials are refreshed.
+const credentialsRefreshWindow = 5 * time.Minute
+
+// Credentials is a set of AWS credentials used to sign requests.
+type Credentials struct {
+	AccessId string
+	Secret   string
+
+	// Token is the session token issued along with temporary credentials; it is empty for
+	// long-term access keys.
+	Token string
+
+	// Expiration is when temporary credentials stop working. The zero value means the
+	// credentials don't expire.
+	Expiration time.Time
+}
+
+// needsRefresh reports whether c should be replaced before signing a request at now.
+func (c Credentials) needsRefresh(now time.Time) bool {
+	if c.AccessId == "" {
+		return true
+	}
+
+	return !c.Expiration.IsZero() && now.Add(credentialsRefreshWindow).After(c.Expiration)
+}
+
+// credentialStore holds the credentials for an S3. It is shared by every copy of the S3, so
+// that rotating the credentials of one rotates them for all.
+type credentialStore struct {
+	lock    sync.RWMutex
+	current Credentials
+	refresh func() (Credentials, error)
+
+	/* secondary is switched to when current is rejected by S3 */
+	secondary *Credentials
+}
+
+func newCredentialStore(accessId, secret string) *credentialStore {
+	return &credentialStore{
+		current: Credentials{AccessId: accessId, Secret: secret},
+	}
+}
+
+// peek returns the current credentials without attempting to refresh them.
+func (store *credentialStore) peek() Credentials {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	return store.current
+}
+
+// get returns the credentials to sign a request with, calling the refresh callback first if
+// the current credentials are missing or about to expire.
+func (store *credentialStore) get() (Credentials, error) {
+	store.lock.RLock()
+	current, refresh := store.current, store.refresh
+	store.lock.RUnlock()
+
+	if refresh == nil || !current.needsRefresh(time.Now()) {
+		return current, nil
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	/* Somebody else may have refreshed while we were waiting for the lock */
+	if !store.current.needsRefresh(time.Now()) {
+		return store.current, nil
+	}
+
+	fresh, er := store.refresh()
+	if er != nil {
+		/* Credentials are refreshed ahead of their expiration, so if that fails the old ones
+		 * can keep being used until they actually expire (and refresh is retried meanwhile) */
+		if store.current.AccessId != "" && time.Now().Before(store.current.Expiration) {
+			return store.current, nil
+		}
+
+		return store.current, er
+	}
+
+	store.current = fresh
+	return fresh, nil
+}
+
+// SetCredentials replaces the credentials used to sign requests. token is the session token for
+// temporary credentials, and should be empty for long-term access keys. It is safe to call
+// SetCredentials while other requests are in flight; requests that have already been signed
+// complete with the old credentials.
+//
+// Copies of an S3 share their credentials, so this affects every copy.
+func (s3 *S3) SetCredentials(accessId, secret, token string) {
+	s3.creds.lock.Lock()
+	s3.creds.current = Credentials{AccessId: accessId, Secret: secret, Token: token}
+	s3.creds.lock.Unlock()
+
+	s3.anonymous = false
+
+	s3.expireExpressSession()
+}
+
+// SetSecondaryCredentials registers a second credential pair to fail over to if S3 rejects the
+// current credentials as invalid or expired (with InvalidAccessKeyId or ExpiredToken). The
+// request that was rejected is retried with the secondary credentials when its body can be
+// replayed, and they are used for every request afterwards. This allows access keys to be
+// rotated without downtime: configure the new key as secondary before deactivating the old one.
+//
+// Failover happens at most once per call to SetSecondaryCredentials; passing an empty accessId
+// removes the secondary credentials.
+func (s3 *S3) SetSecondaryCredentials(accessId, secret, token string) {
+	s3.creds.lock.Lock()
+	defer s3.creds.lock.Unlock()
+
+	if accessId == "" {
+		s3.creds.secondary = nil
+		return
+	}
+
+	s3.creds.secondary = &Credentials{AccessId: accessId, Secret: secret, Token: token}
+}
+
+// failoverCredentials switches to the secondary credentials after a request signed with the
+// access key failedId was rejected, reporting whether the request should be retried. If another
+// request already switched away from failedId, it can be retried without switching again.
+func (s3 *S3) failoverCredentials(failedId string) bool {
+	s3.creds.lock.Lock()
+
+	if s3.creds.current.AccessId != failedId {
+		s3.creds.lock.Unlock()
+		return true
+	}
+
+	if s3.creds.secondary == nil {
+		s3.creds.lock.Unlock()
+		return false
+	}
+
+	s3.creds.current = *s3.creds.secondary
+	s3.creds.secondary = nil
+	s3.creds.lock.Unlock()
+
+	s3.expireExpressSession()
+	return true
+}
+
+// expireExpressSession forces a new directory bucket session to be created before the next
+// request, such as after the credentials it was created with have changed.
+func (s3 *S3) expireExpressSession() {
+	if s3.express != nil {
+		s3.express.lock.Lock()
+		s3.express.expiration = time.Time{}
+		s3.express.lock.Unlock()
+	}
+}
+
+// SetCredentialsRefresh registers refresh to supply new credentials whenever the current ones
+// are within five minutes of their Expiration (or were never set). refresh is called before
+// signing the request that needs them, and no more than once at a time. If it fails, the
+// current credentials keep being used until they actually expire, and refresh is tried again
+// on the next request; only once they have expired is the error returned from the request that
+// needed them. Passing nil removes the callback.
+func (s3 *S3) SetCredentialsRefresh(refresh func() (Credentials, error)) {
+	s3.creds.lock.Lock()
+	defer s3.creds.lock.Unlock()
+
+	s3.creds.refresh = refresh
+}
@@ -0,0 +1,292 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxDeleteKeys is the largest number of keys S3 accepts in one multi-object delete request.
+const maxDeleteKeys = 1000
+
+// DeleteResult reports the outcome of deleting a single key with DeleteMulti, a single version
+// with DeleteVersions, or either with DeletePrefix.
+type DeleteResult struct {
+	Key       string
+	VersionId string // The version deleted, if a version was; only by DeleteVersions and DeletePrefix.
+	Deleted   bool
+	Code      string // The S3 error code if the key could not be deleted, e.g. "AccessDenied".
+	Message   string
+}
+
+type s3deleteObject struct {
+	Key       string
+	VersionId string `xml:",omitempty"`
+}
+
+type s3deleteReq struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool
+	Objects []s3deleteObject `xml:"Object"`
+}
+
+type s3deleteResp struct {
+	XMLName xml.Name `xml:"DeleteResult"`
+	Deleted []struct {
+		Key       string
+		VersionId string
+	}
+	Errors []struct {
+		Key       string
+		VersionId string
+		Code      string
+		Message   string
+	} `xml:"Error"`
+}
+
+// Delete removes the object at path. S3 does not treat deleting a nonexistent object as an
+// error. Any opts are applied to the request; deleting a version (see WithVersionId) from a bucket
+// with MFA delete enabled takes WithMFA.
+func (s3 *S3) Delete(ctx context.Context, path string, opts ...RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("Delete", path, 0, start, er)
+	}(time.Now())
+
+	ctx, endSpan := s3.startSpan(ctx, "Delete", path)
+	defer func() {
+		endSpan(er)
+	}()
+
+	req, er := http.NewRequestWithContext(ctx, "DELETE", s3.resource(path, nil), nil)
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// DeleteMulti removes every object in paths using S3's multi-object delete API, which handles
+// up to 1000 keys per request. The outcome for each key is returned in the same order as paths.
+// An error is returned only if a request as a whole fails, in which case the results for keys
+// that were already processed are returned along with it. Any opts are applied to every request.
+func (s3 *S3) DeleteMulti(ctx context.Context, paths []string, opts ...RequestOption) ([]DeleteResult, error) {
+	results := make([]DeleteResult, 0, len(paths))
+
+	for len(paths) > 0 {
+		batch := paths
+		if len(batch) > maxDeleteKeys {
+			batch = batch[:maxDeleteKeys]
+		}
+		paths = paths[len(batch):]
+
+		objects := make([]s3deleteObject, len(batch))
+		for i, path := range batch {
+			objects[i] = s3deleteObject{Key: path}
+		}
+
+		batchResults, er := s3.deleteBatch(ctx, objects, opts)
+		results = append(results, batchResults...)
+
+		if er != nil {
+			return results, er
+		}
+	}
+
+	return results, nil
+}
+
+// DeleteVersions permanently removes each of versions (as listed by ListObjectVersions, by key and
+// version ID) in batches, as DeleteMulti does for objects, returning the outcome for each in the
+// same order. In a bucket with MFA delete enabled, this takes WithMFA in opts, which are applied
+// to every request.
+func (s3 *S3) DeleteVersions(ctx context.Context, versions []ObjectVersion, opts ...RequestOption) ([]DeleteResult, error) {
+	results := make([]DeleteResult, 0, len(versions))
+
+	for len(versions) > 0 {
+		batch := versions
+		if len(batch) > maxDeleteKeys {
+			batch = batch[:maxDeleteKeys]
+		}
+		versions = versions[len(batch):]
+
+		objects := make([]s3deleteObject, len(batch))
+		for i, version := range batch {
+			objects[i] = s3deleteObject{Key: version.Key, VersionId: version.VersionId}
+		}
+
+		batchResults, er := s3.deleteBatch(ctx, objects, opts)
+		results = append(results, batchResults...)
+
+		if er != nil {
+			return results, er
+		}
+	}
+
+	return results, nil
+}
+
+// deleteBatch deletes up to maxDeleteKeys objects, or versions of them, in one request, returning
+// the outcome for each in the same order. Any opts are applied to the request.
+func (s3 *S3) deleteBatch(ctx context.Context, objects []s3deleteObject, opts []RequestOption) ([]DeleteResult, error) {
+	start := time.Now()
+
+	body := s3deleteReq{Objects: objects}
+
+	xmlBody, er := xml.Marshal(body)
+	if er != nil {
+		return nil, er
+	}
+
+	md5sum := md5.Sum(xmlBody)
+
+	values := url.Values{}
+	values.Set("delete", "")
+
+	req, er := http.NewRequestWithContext(ctx, "POST", s3.resource("", values), bytes.NewReader(xmlBody))
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md5sum[:]))
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(xmlBody)))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Host", req.URL.Host)
+	req.ContentLength = int64(len(xmlBody))
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		for _, obj := range objects {
+			s3.audit("Delete", obj.Key, 0, start, er)
+		}
+
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	xmlBytes, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return nil, er
+	}
+
+	var xmlResp s3deleteResp
+	if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+		return nil, er
+	}
+
+	/* Results are matched by version as well as key, as a batch may delete several versions of
+	 * the same key */
+	byKey := map[s3deleteObject]DeleteResult{}
+
+	for _, deleted := range xmlResp.Deleted {
+		obj := s3deleteObject{Key: deleted.Key, VersionId: deleted.VersionId}
+		byKey[obj] = DeleteResult{Key: deleted.Key, VersionId: deleted.VersionId, Deleted: true}
+	}
+
+	for _, failed := range xmlResp.Errors {
+		obj := s3deleteObject{Key: failed.Key, VersionId: failed.VersionId}
+		byKey[obj] = DeleteResult{Key: failed.Key, VersionId: failed.VersionId, Code: failed.Code, Message: failed.Message}
+	}
+
+	results := make([]DeleteResult, len(objects))
+
+	for i, obj := range objects {
+		result, ok := byKey[obj]
+		if !ok {
+			result = DeleteResult{Key: obj.Key, VersionId: obj.VersionId, Code: "MissingResult", Message: "S3 did not report a result for this key"}
+		}
+
+		results[i] = result
+
+		var keyErr error
+		if !result.Deleted {
+			keyErr = fmt.Errorf("s3: failed to delete %#v: %s %s", obj.Key, result.Code, result.Message)
+		}
+
+		s3.audit("Delete", obj.Key, 0, start, keyErr)
+	}
+
+	return results, nil
+}
+
+// DeletePrefix deletes every object whose key begins with prefix, as "aws s3 rm --recursive" does,
+// paging through the listing and deleting each page with a single multi-object delete. An empty
+// prefix empties the whole bucket. If the bucket has (or has had) versioning enabled, every version
+// of the objects and every delete marker is deleted for good, so that nothing under prefix
+// remains; in a bucket with MFA delete enabled, that takes WithMFA in opts, which are applied to
+// the delete requests.
+//
+// It returns how many objects (or versions) were deleted, and the outcome for each that couldn't
+// be, such as for lack of permission. An error is returned if a request as a whole fails, along
+// with what was deleted until then.
+func (s3 *S3) DeletePrefix(ctx context.Context, prefix string, opts ...RequestOption) (deleted int, failed []DeleteResult, er error) {
+	ctx, endSpan := s3.startSpan(ctx, "DeletePrefix", prefix)
+	defer func() {
+		endSpan(er)
+	}()
+
+	versioned := false
+	if s3.express == nil {
+		versioning, er := s3.GetBucketVersioning(ctx)
+		if er != nil {
+			return 0, nil, er
+		}
+
+		versioned = versioning.Status != ""
+	}
+
+	var pending []s3deleteObject
+
+	flush := func() error {
+		results, er := s3.deleteBatch(ctx, pending, opts)
+		pending = pending[:0]
+
+		for _, result := range results {
+			if result.Deleted {
+				deleted++
+			} else {
+				failed = append(failed, result)
+			}
+		}
+
+		return er
+	}
+
+	queue := func(obj s3deleteObject) error {
+		if pending = append(pending, obj); len(pending) < maxDeleteKeys {
+			return nil
+		}
+
+		return flush()
+	}
+
+	if versioned {
+		er = s3.walkVersions(ctx, prefix, func(version ObjectVersion) error {
+			return queue(s3deleteObject{Key: version.Key, VersionId: version.VersionId})
+		})
+	} else {
+		er = s3.Walk(ctx, prefix, func(obj ObjectSummary) error {
+			return queue(s3deleteObject{Key: obj.Key})
+		})
+	}
+
+	if er == nil && len(pending) > 0 {
+		er = flush()
+	}
+
+	return deleted, failed, er
+}
@@ -0,0 +1,115 @@
+package s3
+
+import (
+	"sync"
+	"time"
+)
+
+// retarget holds the endpoint and region that an S3 has been redirected to (see RedirectPolicy),
+// which replace the configured ones for subsequent requests. It is the only addressing state that
+// changes while requests are in flight, so it is kept behind a lock, and shared by the copies an S3
+// is derived into as long as they address the same bucket.
+type retarget struct {
+	lock     sync.RWMutex
+	endpoint string
+	region   string
+}
+
+// currentEndpoint returns the host requests are sent to, taking redirects into account.
+func (s3 *S3) currentEndpoint() string {
+	if rt := s3.retarget; rt != nil {
+		rt.lock.RLock()
+		defer rt.lock.RUnlock()
+
+		if rt.endpoint != "" {
+			return rt.endpoint
+		}
+	}
+
+	return s3.endpoint
+}
+
+// currentRegion returns the region requests are signed for, taking redirects into account.
+func (s3 *S3) currentRegion() string {
+	if rt := s3.retarget; rt != nil {
+		rt.lock.RLock()
+		defer rt.lock.RUnlock()
+
+		if rt.region != "" {
+			return rt.region
+		}
+	}
+
+	return s3.region
+}
+
+// retargetTo sends subsequent requests to host, signed for region unless it is empty.
+func (s3 *S3) retargetTo(host, region string) {
+	if s3.retarget == nil {
+		return
+	}
+
+	s3.retarget.lock.Lock()
+	defer s3.retarget.lock.Unlock()
+
+	s3.retarget.endpoint = host
+	if region != "" {
+		s3.retarget.region = region
+	}
+}
+
+// clone returns a shallow copy of the S3 for the With* methods to modify. The copy shares the
+// credentials, clock, and the other state meant to be shared, but not the slices of its
+// configuration, so that modifying one doesn't affect the other.
+func (s3 *S3) clone() *S3 {
+	copied := *s3
+	copied.defaultOpts = append([]RequestOption(nil), s3.defaultOpts...)
+	copied.middleware = append([]Middleware(nil), s3.middleware...)
+
+	return &copied
+}
+
+// WithBucket returns a copy of the S3 that addresses bucket, and is otherwise configured the
+// same. The copy shares the credentials of the original, so deriving clients for many buckets
+// is cheap. A directory bucket is addressed through the zonal endpoint of its own availability
+// zone, with a session of its own.
+func (s3 *S3) WithBucket(bucket string) *S3 {
+	copied := s3.clone()
+	copied.setBucket(bucket)
+	copied.retarget = &retarget{}
+
+	return copied
+}
+
+// WithRegion returns a copy of the S3 for a bucket in region, which requests are signed for. When
+// the S3 sends requests to AWS's global endpoint or a regional one, the copy sends them to the
+// endpoint of region instead, avoiding a redirect; the Transfer Acceleration endpoint is the same
+// for every region, and requests to other services still go to the endpoint set with SetEndpoint.
+func (s3 *S3) WithRegion(region string) *S3 {
+	copied := s3.clone()
+	copied.region = region
+	copied.retarget = &retarget{}
+
+	switch {
+	case s3.express != nil:
+		copied.setBucket(copied.bucket)
+	case isAWSHost(s3.baseHost):
+		copied.baseHost = copied.awsHost()
+		copied.endpoint = copied.bucketHost()
+	}
+
+	return copied
+}
+
+// WithTimeout returns a copy of the S3 whose requests time out after timeout, including reading
+// the response body (see http.Client.Timeout). The copy uses a copy of the original's HTTP
+// client, sharing its transport and so its pool of connections.
+func (s3 *S3) WithTimeout(timeout time.Duration) *S3 {
+	client := *s3.httpClient()
+	client.Timeout = timeout
+
+	copied := s3.clone()
+	copied.client = &client
+
+	return copied
+}
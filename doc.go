@@ -1,3 +1,24 @@
 // s3 provides a very simple interface for reading/writing blobs from S3 using
 // the multipart REST API (which allows for >5GB uploads).
+//
+// Every request made by the package passes through the same pipeline: the operation builds
+// the request, any RequestOptions are applied (first those registered with
+// SetDefaultRequestOptions, then those passed to the operation), the request is signed, and
+// finally it is sent. RequestOptions are the supported way to use features of S3, or of
+// S3-compatible providers such as MinIO or R2, that the package doesn't expose directly; see
+// WithRawAmzHeader, WithProviderHeader and WithQuery.
+//
+// Every operation that makes requests takes a context.Context as its first argument, which
+// bounds the lifetime of those requests. Errors returned by the package can be inspected with
+// errors.Is and errors.As: failed requests produce an *S3Error, and conditions that callers are
+// expected to test for are exported as sentinel errors such as ErrAborted and ErrVerification.
+//
+// Keys are used exactly as given: any valid S3 key, including those with spaces, plus signs,
+// question marks, percent signs or non-ASCII characters, is escaped as S3 requires wherever it is
+// sent, and comes back unchanged from listings. Paths passed to operations are keys, not URLs, and
+// mustn't be escaped by the caller.
+//
+// This is version 2 of the package, imported as github.com/lye/s3/v2. It differs from version 1
+// mainly in taking contexts; code written for version 1 can be ported by passing a context to
+// each operation.
 package s3
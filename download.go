@@ -0,0 +1,111 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Download fetches the object at path into w, splitting it into ranges of chunkSize bytes (7MB if
+// chunkSize is zero) that are fetched by concurrency workers at once and written at their offsets,
+// such as into a file opened for writing. Over links with high latency, this is much quicker than
+// reading the object in a single stream. Every range is fetched with If-Match set to the ETag the
+// object had at the start, so the download fails rather than mix two versions of it. It returns
+// the size of the object; if an error is returned, w holds an arbitrary subset of the object.
+func (s3 *S3) Download(ctx context.Context, path string, w io.WriterAt, chunkSize int64, concurrency int, opts ...RequestOption) (int64, error) {
+	header, er := s3.Head(ctx, path, opts...)
+	if er != nil {
+		return 0, er
+	}
+
+	size, er := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if er != nil {
+		return 0, fmt.Errorf("s3: missing size of %s", path)
+	}
+
+	/* The progress of the ranges is reported together, rather than range by range */
+	p := newProgress(opts, size)
+
+	rangeOpts := append(append([]RequestOption{}, opts...), withoutProgress())
+	if etag := header.Get("ETag"); etag != "" {
+		rangeOpts = append(rangeOpts, WithHeader("If-Match", etag))
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+
+	tasks := make(chan TransferTask)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for task := range tasks {
+				if er := s3.downloadRange(ctx, path, w, task, rangeOpts, p); er != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = er
+					}
+					errLock.Unlock()
+
+					/* Stop the other workers early; their errors are only consequences */
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for _, task := range planTasks(size, chunkSize) {
+		if task.Length == 0 || ctx.Err() != nil {
+			continue
+		}
+
+		tasks <- task
+	}
+
+	close(tasks)
+	wg.Wait()
+
+	if firstErr != nil {
+		return size, firstErr
+	}
+
+	return size, nil
+}
+
+// downloadRange fetches the range of the object at path described by task into w, counting it
+// towards p (which may be nil).
+func (s3 *S3) downloadRange(ctx context.Context, path string, w io.WriterAt, task TransferTask, opts []RequestOption, p *progress) error {
+	body, _, er := s3.GetRange(ctx, path, task.Offset, task.Length, opts...)
+	if er != nil {
+		return er
+	}
+
+	body = trackResponse(body, p)
+	defer body.Close()
+
+	n, er := io.Copy(io.NewOffsetWriter(w, task.Offset), body)
+	if er != nil {
+		return er
+	}
+
+	if n != task.Length {
+		return fmt.Errorf("s3: downloaded %d bytes at offset %d rather than %d", n, task.Offset, task.Length)
+	}
+
+	return nil
+}
@@ -0,0 +1,410 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The algorithms of version 2 of the AWS SDKs' S3 encryption client, which EncryptionClient
+// follows. Objects are encrypted with AES-GCM, under a random data key that is wrapped either
+// with AES-GCM under a local master key, or by KMS with the content algorithm bound into the
+// encryption context.
+const (
+	cekAlgorithm     = "AES/GCM/NoPadding"
+	wrapAESGCM       = "AES/GCM"
+	wrapKMSContext   = "kms+context"
+	cekAlgContextKey = "aws:x-amz-cek-alg"
+	gcmTagBits       = 128
+	dataKeySize      = 32
+)
+
+// KeyWrapper wraps and unwraps the data keys that an EncryptionClient encrypts objects with. The
+// package provides wrappers for local master keys (NewLocalKeyWrapper) and KMS keys
+// (NewKMSKeyWrapper).
+type KeyWrapper interface {
+	// WrapKey encrypts key, returning it along with the name of the wrapping algorithm and the
+	// material description to store with the object.
+	WrapKey(ctx context.Context, key []byte) (wrapped []byte, wrapAlg string, matdesc map[string]string, er error)
+
+	// UnwrapKey decrypts a key wrapped by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte, wrapAlg string, matdesc map[string]string) ([]byte, error)
+}
+
+// randomBytes returns n bytes from the system's secure random number generator.
+func randomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, er := io.ReadFull(rand.Reader, buf); er != nil {
+		return nil, fmt.Errorf("s3: generating random bytes: %w", er)
+	}
+
+	return buf, nil
+}
+
+// newGCM returns AES-GCM with key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, er := aes.NewCipher(key)
+	if er != nil {
+		return nil, fmt.Errorf("s3: invalid AES key: %w", er)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+type localKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKeyWrapper returns a KeyWrapper that wraps data keys with AES-GCM under masterKey, which
+// must be 16, 24 or 32 bytes long. Objects can only be decrypted with the same master key, so it
+// has to be kept as safe, and for as long, as the objects themselves.
+func NewLocalKeyWrapper(masterKey []byte) (KeyWrapper, error) {
+	aead, er := newGCM(masterKey)
+	if er != nil {
+		return nil, er
+	}
+
+	return &localKeyWrapper{aead: aead}, nil
+}
+
+func (wrapper *localKeyWrapper) WrapKey(ctx context.Context, key []byte) ([]byte, string, map[string]string, error) {
+	nonce, er := randomBytes(wrapper.aead.NonceSize())
+	if er != nil {
+		return nil, "", nil, er
+	}
+
+	/* The wrapped key is the nonce followed by the sealed key, authenticated along with the
+	 * content algorithm so that it can't be swapped for a weaker one */
+	return wrapper.aead.Seal(nonce, nonce, key, []byte(cekAlgorithm)), wrapAESGCM, map[string]string{}, nil
+}
+
+func (wrapper *localKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte, wrapAlg string, matdesc map[string]string) ([]byte, error) {
+	if wrapAlg != wrapAESGCM {
+		return nil, fmt.Errorf("s3: a local master key can't unwrap a key wrapped with %s", wrapAlg)
+	}
+
+	if len(wrapped) < wrapper.aead.NonceSize() {
+		return nil, fmt.Errorf("s3: invalid wrapped key")
+	}
+
+	nonce, sealed := wrapped[:wrapper.aead.NonceSize()], wrapped[wrapper.aead.NonceSize():]
+
+	key, er := wrapper.aead.Open(nil, nonce, sealed, []byte(cekAlgorithm))
+	if er != nil {
+		return nil, fmt.Errorf("s3: unwrapping the data key (wrong master key?): %w", er)
+	}
+
+	return key, nil
+}
+
+// KMSKeyWrapper is a KeyWrapper that has AWS KMS wrap data keys under a KMS key, as returned by
+// NewKMSKeyWrapper. Requests to KMS are signed with the credentials of the S3 it was made with.
+type KMSKeyWrapper struct {
+	s3      *S3
+	keyId   string
+	context map[string]string
+
+	// Endpoint is the URL KMS is reached at. It defaults to https://kms.<region>.amazonaws.com,
+	// for the region in the key's ARN, or else the region of the S3.
+	Endpoint string
+}
+
+// NewKMSKeyWrapper returns a KeyWrapper that wraps data keys with the KMS key keyId (an ID, ARN or
+// alias), binding context into the encryption context of each, along with the content algorithm.
+// Unwrapping requires kms:Decrypt on the key, with the same context.
+func NewKMSKeyWrapper(s3 *S3, keyId string, context map[string]string) *KMSKeyWrapper {
+	return &KMSKeyWrapper{s3: s3, keyId: keyId, context: context}
+}
+
+func (wrapper *KMSKeyWrapper) WrapKey(ctx context.Context, key []byte) ([]byte, string, map[string]string, error) {
+	matdesc := map[string]string{cekAlgContextKey: cekAlgorithm}
+	for name, value := range wrapper.context {
+		matdesc[name] = value
+	}
+
+	var resp struct {
+		CiphertextBlob []byte
+	}
+
+	er := wrapper.call(ctx, "Encrypt", map[string]interface{}{
+		"KeyId":             wrapper.keyId,
+		"Plaintext":         key,
+		"EncryptionContext": matdesc,
+	}, &resp)
+	if er != nil {
+		return nil, "", nil, er
+	}
+
+	return resp.CiphertextBlob, wrapKMSContext, matdesc, nil
+}
+
+func (wrapper *KMSKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte, wrapAlg string, matdesc map[string]string) ([]byte, error) {
+	if wrapAlg != wrapKMSContext {
+		return nil, fmt.Errorf("s3: a KMS key can't unwrap a key wrapped with %s", wrapAlg)
+	}
+
+	if matdesc[cekAlgContextKey] != cekAlgorithm {
+		return nil, fmt.Errorf("s3: the encryption context doesn't match the content algorithm")
+	}
+
+	var resp struct {
+		Plaintext []byte
+	}
+
+	er := wrapper.call(ctx, "Decrypt", map[string]interface{}{
+		"KeyId":             wrapper.keyId,
+		"CiphertextBlob":    wrapped,
+		"EncryptionContext": matdesc,
+	}, &resp)
+	if er != nil {
+		return nil, er
+	}
+
+	return resp.Plaintext, nil
+}
+
+// region returns the region of the KMS key.
+func (wrapper *KMSKeyWrapper) region() string {
+	/* arn:aws:kms:<region>:<account>:key/<id> */
+	if parts := strings.Split(wrapper.keyId, ":"); len(parts) >= 6 && parts[0] == "arn" {
+		return parts[3]
+	}
+
+	return wrapper.s3.currentRegion()
+}
+
+// call makes a request for action to the KMS JSON API, decoding the response into result.
+func (wrapper *KMSKeyWrapper) call(ctx context.Context, action string, body, result interface{}) error {
+	if wrapper.s3.anonymous {
+		return fmt.Errorf("s3: KMS can't be used without credentials")
+	}
+
+	region := wrapper.region()
+	if region == "" {
+		return fmt.Errorf("s3: the region of KMS key %s is unknown; give its ARN", wrapper.keyId)
+	}
+
+	endpoint := wrapper.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", region)
+	}
+
+	reqBody, er := json.Marshal(body)
+	if er != nil {
+		return er
+	}
+
+	req, er := http.NewRequestWithContext(ctx, "POST", endpoint+"/", bytes.NewReader(reqBody))
+	if er != nil {
+		return er
+	}
+
+	creds, er := wrapper.s3.creds.get()
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	req.Header.Set("X-Amz-Content-Sha256", sha256Hex(string(reqBody)))
+
+	if creds.Token != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.Token)
+	}
+
+	signV4(req, creds.AccessId, creds.Secret, region, "kms", wrapper.s3.now())
+
+	resp, er := wrapper.s3.httpClient().Do(req)
+	if er != nil {
+		return er
+	}
+	defer resp.Body.Close()
+
+	respBody, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return er
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var kmsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &kmsErr)
+
+		return fmt.Errorf("s3: KMS %s failed with %s: %s %s", action, resp.Status, kmsErr.Type, kmsErr.Message)
+	}
+
+	if er := json.Unmarshal(respBody, result); er != nil {
+		return fmt.Errorf("s3: invalid response from KMS %s: %w", action, er)
+	}
+
+	return nil
+}
+
+// EncryptionClient encrypts objects on the client before they are uploaded, and decrypts them
+// after they are downloaded, as returned by NewEncryptionClient. S3 only ever sees ciphertext.
+//
+// The format is that of version 2 of the AWS SDKs' S3 encryption client: each object is
+// encrypted with AES-GCM under its own random data key, which is wrapped by a KeyWrapper and
+// stored, with the IV and the algorithms used, in the object's user metadata (x-amz-key-v2,
+// x-amz-iv, x-amz-cek-alg, x-amz-wrap-alg, x-amz-matdesc). Objects written by either can be read
+// by the other, given the same master key. Objects in the older, version 1 format can't be read.
+//
+// AES-GCM authenticates the object as a whole, so objects are encrypted and decrypted in memory,
+// and ranged Gets aren't possible.
+type EncryptionClient struct {
+	s3      *S3
+	wrapper KeyWrapper
+}
+
+// NewEncryptionClient returns an EncryptionClient that stores objects with s3, wrapping their data
+// keys with wrapper.
+func NewEncryptionClient(s3 *S3, wrapper KeyWrapper) *EncryptionClient {
+	return &EncryptionClient{s3: s3, wrapper: wrapper}
+}
+
+// Put encrypts everything that can be read from r, and uploads it to path. opts are passed on to
+// S3.Put.
+func (client *EncryptionClient) Put(ctx context.Context, r io.Reader, path, contentType string, opts ...RequestOption) error {
+	plaintext, er := io.ReadAll(r)
+	if er != nil {
+		return er
+	}
+
+	key, er := randomBytes(dataKeySize)
+	if er != nil {
+		return er
+	}
+
+	aead, er := newGCM(key)
+	if er != nil {
+		return er
+	}
+
+	iv, er := randomBytes(aead.NonceSize())
+	if er != nil {
+		return er
+	}
+
+	ciphertext := aead.Seal(nil, iv, plaintext, nil)
+
+	wrapped, wrapAlg, matdesc, er := client.wrapper.WrapKey(ctx, key)
+	if er != nil {
+		return er
+	}
+
+	matdescJSON, er := json.Marshal(matdesc)
+	if er != nil {
+		return er
+	}
+
+	meta := map[string]string{
+		"x-amz-key-v2":                     base64.StdEncoding.EncodeToString(wrapped),
+		"x-amz-iv":                         base64.StdEncoding.EncodeToString(iv),
+		"x-amz-cek-alg":                    cekAlgorithm,
+		"x-amz-wrap-alg":                   wrapAlg,
+		"x-amz-matdesc":                    string(matdescJSON),
+		"x-amz-tag-len":                    strconv.Itoa(gcmTagBits),
+		"x-amz-unencrypted-content-length": strconv.Itoa(len(plaintext)),
+	}
+
+	opts = append([]RequestOption{WithMetadata(meta)}, opts...)
+	return client.s3.Put(ctx, bytes.NewReader(ciphertext), int64(len(ciphertext)), path, nil, contentType, opts...)
+}
+
+// Get downloads the object at path and decrypts it, returning the plaintext and the object's
+// headers (with Content-Length giving the size of the plaintext). An object that has been
+// tampered with, or wasn't encrypted by an encryption client, is an error. opts are passed on to
+// S3.Get, but mustn't ask for a range.
+func (client *EncryptionClient) Get(ctx context.Context, path string, opts ...RequestOption) (io.ReadCloser, http.Header, error) {
+	if newRequestConfig(opts).header.Get("Range") != "" {
+		return nil, nil, fmt.Errorf("s3: encrypted objects can't be read by range")
+	}
+
+	/* The ciphertext is needed exactly as stored, whatever its Content-Encoding */
+	opts = append([]RequestOption{WithAcceptEncoding("identity")}, opts...)
+
+	body, header, er := client.s3.Get(ctx, path, opts...)
+	if er != nil {
+		return nil, nil, er
+	}
+	defer body.Close()
+
+	ciphertext, er := io.ReadAll(body)
+	if er != nil {
+		return nil, nil, er
+	}
+
+	plaintext, er := client.decrypt(ctx, path, ciphertext, Metadata(header))
+	if er != nil {
+		return nil, nil, er
+	}
+
+	header = header.Clone()
+	header.Set("Content-Length", strconv.Itoa(len(plaintext)))
+
+	return io.NopCloser(bytes.NewReader(plaintext)), header, nil
+}
+
+// decrypt decrypts the ciphertext of the object at path, using the envelope in its metadata.
+func (client *EncryptionClient) decrypt(ctx context.Context, path string, ciphertext []byte, meta map[string]string) ([]byte, error) {
+	if meta["x-amz-key-v2"] == "" {
+		if meta["x-amz-key"] != "" {
+			return nil, fmt.Errorf("s3: %s is encrypted in the unsupported version 1 format", path)
+		}
+
+		return nil, fmt.Errorf("s3: %s isn't client-side encrypted", path)
+	}
+
+	if alg := meta["x-amz-cek-alg"]; alg != cekAlgorithm {
+		return nil, fmt.Errorf("s3: %s is encrypted with unsupported algorithm %#v", path, alg)
+	}
+
+	wrapped, er := base64.StdEncoding.DecodeString(meta["x-amz-key-v2"])
+	if er != nil {
+		return nil, fmt.Errorf("s3: invalid x-amz-key-v2 for %s: %w", path, er)
+	}
+
+	iv, er := base64.StdEncoding.DecodeString(meta["x-amz-iv"])
+	if er != nil {
+		return nil, fmt.Errorf("s3: invalid x-amz-iv for %s: %w", path, er)
+	}
+
+	matdesc := map[string]string{}
+	if desc := meta["x-amz-matdesc"]; desc != "" {
+		if er := json.Unmarshal([]byte(desc), &matdesc); er != nil {
+			return nil, fmt.Errorf("s3: invalid x-amz-matdesc for %s: %w", path, er)
+		}
+	}
+
+	key, er := client.wrapper.UnwrapKey(ctx, wrapped, meta["x-amz-wrap-alg"], matdesc)
+	if er != nil {
+		return nil, er
+	}
+
+	aead, er := newGCM(key)
+	if er != nil {
+		return nil, er
+	}
+
+	if len(iv) != aead.NonceSize() {
+		return nil, fmt.Errorf("s3: invalid x-amz-iv for %s", path)
+	}
+
+	plaintext, er := aead.Open(nil, iv, ciphertext, nil)
+	if er != nil {
+		return nil, fmt.Errorf("s3: %s failed authentication: %w", path, er)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultHost is the service endpoint used unless SetEndpoint is called.
+const defaultHost = "s3.amazonaws.com"
+
+// SetEndpoint directs requests to an S3-compatible service other than AWS, such as MinIO, Ceph
+// RGW or DigitalOcean Spaces. endpoint is the base URL of the service, like
+// "https://nyc3.digitaloceanspaces.com" or "http://localhost:9000"; if it has no scheme, HTTPS
+// is assumed. Unless path-style addressing is enabled with SetPathStyle, the bucket name is
+// prepended to the host, as it is for AWS.
+func (s3 *S3) SetEndpoint(endpoint string) error {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	u, er := url.Parse(endpoint)
+	if er != nil {
+		return fmt.Errorf("s3: invalid endpoint: %w", er)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("s3: invalid endpoint %#v: scheme must be http or https", endpoint)
+	}
+
+	if u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+		return fmt.Errorf("s3: invalid endpoint %#v: expected a scheme and host only", endpoint)
+	}
+
+	s3.scheme = u.Scheme
+	s3.baseHost = u.Host
+	s3.endpoint = s3.bucketHost()
+	s3.retarget = &retarget{}
+
+	return nil
+}
+
+// SetPathStyle switches between virtual-hosted addressing (the default), where the bucket is
+// part of the host name ("bucket.s3.amazonaws.com/key"), and path-style addressing, where it is
+// the first element of the path ("s3.amazonaws.com/bucket/key"). Many S3-compatible services
+// only support path-style addressing, as do bucket names that aren't valid host names. Access
+// points only support virtual-hosted addressing, so this has no effect on them.
+func (s3 *S3) SetPathStyle(pathStyle bool) {
+	s3.pathStyle = pathStyle && s3.accessPoint == nil
+	s3.endpoint = s3.bucketHost()
+	s3.retarget = &retarget{}
+}
+
+// SetDualStack switches requests to AWS's dual-stack endpoints
+// ("bucket.s3.dualstack.us-east-1.amazonaws.com"), which are reachable over IPv6 as well as IPv4,
+// or back to the IPv4-only ones. The endpoint is for the region of the S3 (see WithRegion), or
+// us-east-1 if it has none. Dual-stack endpoints are only available on AWS, and not for directory
+// buckets.
+func (s3 *S3) SetDualStack(dualStack bool) error {
+	if s3.express != nil || !isAWSHost(s3.baseHost) {
+		return fmt.Errorf("s3: dual-stack endpoints are only available for AWS general purpose buckets")
+	}
+
+	s3.dualStack = dualStack
+	s3.baseHost = s3.awsHost()
+	s3.endpoint = s3.bucketHost()
+	s3.retarget = &retarget{}
+
+	return nil
+}
+
+// isAWSHost reports whether host is one of AWS's S3 endpoints for general purpose buckets: the
+// global endpoint, a regional one, or a Transfer Acceleration one.
+func isAWSHost(host string) bool {
+	return host == defaultHost || host == accelerateHost || host == accelerateDualStackHost ||
+		(strings.HasPrefix(host, "s3.") && strings.HasSuffix(host, ".amazonaws.com"))
+}
+
+// awsHost returns the AWS endpoint for the region and options of the S3.
+func (s3 *S3) awsHost() string {
+	switch {
+	case s3.accelerate && s3.dualStack:
+		return accelerateDualStackHost
+	case s3.accelerate:
+		return accelerateHost
+	case s3.dualStack && s3.region == "":
+		return "s3.dualstack.us-east-1.amazonaws.com"
+	case s3.dualStack:
+		return "s3.dualstack." + s3.region + ".amazonaws.com"
+	case s3.region == "":
+		return defaultHost
+	}
+
+	return "s3." + s3.region + ".amazonaws.com"
+}
+
+// bucketHost returns the host that requests for the bucket are sent to.
+func (s3 *S3) bucketHost() string {
+	if s3.accessPoint != nil {
+		return s3.accessPoint.host(s3.baseHost, s3.dualStack)
+	}
+
+	if s3.pathStyle || s3.bucket == "" {
+		return s3.baseHost
+	}
+
+	return s3.bucket + "." + s3.baseHost
+}
+
+// baseURL returns the URL that keys are appended to in order to address objects in the bucket.
+func (s3 *S3) baseURL() string {
+	scheme := s3.scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	if s3.pathStyle && s3.bucket != "" {
+		return fmt.Sprintf("%s://%s/%s/", scheme, s3.currentEndpoint(), s3.bucket)
+	}
+
+	return fmt.Sprintf("%s://%s/", scheme, s3.currentEndpoint())
+}
+
+// SetSigningHost makes requests carry (and be signed for) host as their Host header, while
+// still being sent to the endpoint. This is for reaching S3 through a tunnel or port-forward,
+// such as "kubectl port-forward" to an in-cluster gateway: point the endpoint at the local end
+// of the tunnel with SetEndpoint, and set host to the name the service expects, such as
+// "bucket.s3.example.com". Passing an empty host restores the default.
+func (s3 *S3) SetSigningHost(host string) {
+	s3.signingHost = host
+}
+
+// applySigningHost overrides the Host of req if SetSigningHost was used. It must be called
+// before req is signed.
+func (s3 *S3) applySigningHost(req *http.Request) {
+	if s3.signingHost != "" {
+		req.Host = s3.signingHost
+		req.Header.Set("Host", s3.signingHost)
+	}
+}
@@ -1,16 +1,53 @@
 package s3
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 )
 
+// Errors that an *S3Error can be matched against with errors.Is, by the error code S3 responded
+// with. Responses to HEAD requests have no body to carry a code, so a 404 for one matches
+// ErrNoSuchKey and a 403 matches ErrAccessDenied.
+var (
+	ErrNoSuchKey    = errors.New("s3: no such key")
+	ErrNoSuchBucket = errors.New("s3: no such bucket")
+	ErrAccessDenied = errors.New("s3: access denied")
+	ErrSlowDown     = errors.New("s3: slow down")
+)
+
+// errorCodes maps the sentinel errors to the codes that match them.
+var errorCodes = map[error]string{
+	ErrNoSuchKey:    "NoSuchKey",
+	ErrNoSuchBucket: "NoSuchBucket",
+	ErrAccessDenied: "AccessDenied",
+	ErrSlowDown:     "SlowDown",
+}
+
+// S3Error is returned when S3 responds to a request with anything other than a 2xx status. Code
+// is the HTTP status; ErrorCode, Message, RequestId and HostId are parsed from the body, when it
+// has them.
 type S3Error struct {
 	Code        int
 	ShouldRetry bool
 	Body        []byte
+	Header      http.Header
+
+	ErrorCode string // The error code, such as "NoSuchKey".
+	Message   string
+	RequestId string // Identifies the request to AWS support, with HostId.
+	HostId    string
+}
+
+// s3ErrorBody is the XML document S3 returns describing most errors.
+type s3ErrorBody struct {
+	Code      string
+	Message   string
+	RequestId string
+	HostId    string
 }
 
 type S3NewEndpointError struct {
@@ -21,27 +58,135 @@ type S3NewEndpointError struct {
 }
 
 func wrapError(resp *http.Response) *S3Error {
-	bodyBytes, _ := ioutil.ReadAll(resp.Body)
+	bodyBytes, _ := io.ReadAll(resp.Body)
+
+	err := &S3Error{
+		Code:      resp.StatusCode,
+		Body:      bodyBytes,
+		Header:    resp.Header,
+		RequestId: resp.Header.Get("x-amz-request-id"),
+		HostId:    resp.Header.Get("x-amz-id-2"),
+	}
 
-	return &S3Error{
-		Code:        resp.StatusCode,
-		ShouldRetry: resp.StatusCode == http.StatusInternalServerError,
-		Body:        bodyBytes,
+	msg := s3ErrorBody{}
+	if er := xml.Unmarshal(bodyBytes, &msg); er == nil {
+		err.ErrorCode = msg.Code
+		err.Message = msg.Message
+
+		if msg.RequestId != "" {
+			err.RequestId = msg.RequestId
+			err.HostId = msg.HostId
+		}
 	}
+
+	err.ShouldRetry = err.Temporary()
+	return err
 }
 
-func (err *S3Error) Error() string {
-	return fmt.Sprintf("S3 Error: %d %s", err.Code, string(err.Body))
+// withEmbeddedErrors makes a request fail if S3 responds with a 200 status whose body is an error
+// document, as CompleteMultipartUpload, CopyObject and UploadPartCopy can: S3 sends the status as
+// soon as it starts working on them, and only knows whether they succeeded once it is done. The
+// failure is an *S3Error with a Code of 200, which is retried like any other when it is temporary,
+// such as an InternalError.
+func withEmbeddedErrors() RequestOption {
+	return func(config *requestConfig) {
+		config.embeddedErrors = true
+	}
 }
 
-func (err *S3Error) newEndpoint() string {
-	msg := S3NewEndpointError{}
+// embeddedError reads the body of resp, a successful response, and returns it as an *S3Error if
+// it is an error document. Otherwise, resp is left with a body that reads what was read.
+func embeddedError(resp *http.Response) error {
+	body, er := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if er != nil {
+		return er
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	/* S3 may send whitespace to keep the connection alive before the document itself */
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, er := decoder.Token()
+		if er != nil {
+			return nil
+		}
 
-	if er := xml.Unmarshal(err.Body, &msg); er == nil {
-		if msg.Code == "TemporaryRedirect" {
-			return msg.Endpoint
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Error" {
+			return wrapError(resp)
+		} else if ok {
+			return nil
 		}
 	}
+}
+
+func (err *S3Error) Error() string {
+	if err.ErrorCode == "" {
+		return fmt.Sprintf("S3 Error: %d %s", err.Code, string(err.Body))
+	}
+
+	return fmt.Sprintf("S3 Error: %d %s: %s (request %s)", err.Code, err.ErrorCode, err.Message, err.RequestId)
+}
+
+// temporaryCodes are the error codes of failures that may well not happen again, such as
+// throttling and timeouts.
+var temporaryCodes = map[string]bool{
+	"InternalError":            true,
+	"ServiceUnavailable":       true,
+	"SlowDown":                 true,
+	"RequestTimeout":           true,
+	"OperationAborted":         true,
+	"Throttling":               true,
+	"ThrottlingException":      true,
+	"RequestThrottled":         true,
+	"RequestLimitExceeded":     true,
+	"TooManyRequestsException": true,
+}
+
+// Temporary reports whether the request failed for a transient reason, so that sending it again
+// may succeed: a 5xx or 429 status, or an error code such as SlowDown or RequestTimeout. Other
+// errors, such as AccessDenied, NoSuchKey or SignatureDoesNotMatch, are permanent. ShouldRetry
+// holds the same answer.
+func (err *S3Error) Temporary() bool {
+	if err.Code >= 500 || err.Code == http.StatusTooManyRequests {
+		return true
+	}
+
+	return temporaryCodes[err.ErrorCode]
+}
+
+// Is reports whether target is the sentinel error (such as ErrNoSuchKey) for the error's code.
+func (err *S3Error) Is(target error) bool {
+	code, ok := errorCodes[target]
+	return ok && err.awsCode() == code
+}
+
+// awsCode returns the error code of the error (such as "NoSuchKey"). Without a body, the code of a
+// 404 or 403 is assumed to be the usual one for an object.
+func (err *S3Error) awsCode() string {
+	if err.ErrorCode != "" || len(err.Body) > 0 {
+		return err.ErrorCode
+	}
+
+	switch err.Code {
+	case http.StatusNotFound:
+		return "NoSuchKey"
+	case http.StatusForbidden:
+		return "AccessDenied"
+	}
 
 	return ""
 }
+
+// credentialsRejected reports whether the error means the credentials used to sign the request
+// are no longer (or were never) valid.
+func (err *S3Error) credentialsRejected() bool {
+	switch err.awsCode() {
+	case "InvalidAccessKeyId", "ExpiredToken":
+		return true
+	}
+
+	return false
+}
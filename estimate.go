@@ -0,0 +1,122 @@
+package s3
+
+import (
+	"context"
+	"math"
+	"math/rand"
+)
+
+// estimateZ is the normal quantile used for the confidence bounds of SizeEstimate (95%).
+const estimateZ = 1.96
+
+// SizeEstimate is an approximate count of the objects under a prefix and their total size.
+// The Low and High fields bound a 95% confidence interval around each estimate; when Exact is
+// set, no sampling was needed and the bounds equal the estimates.
+type SizeEstimate struct {
+	Objects     int64
+	ObjectsLow  int64
+	ObjectsHigh int64
+
+	Bytes     int64
+	BytesLow  int64
+	BytesHigh int64
+
+	Exact           bool
+	SampledPrefixes int // How many of the common prefixes were listed in full.
+	TotalPrefixes   int // How many common prefixes there are under the prefix.
+}
+
+// EstimateSize estimates how many objects there are under prefix, and how many bytes they
+// occupy, without listing every key. This gives quick capacity numbers for buckets that
+// aren't monitored with CloudWatch.
+//
+// The prefix is listed one level deep using delimiter. Objects found at that level are counted
+// exactly; of the common prefixes below it, samples are chosen at random and walked in full,
+// and the totals for the rest are extrapolated from them. The estimate is therefore only as
+// good as the key layout is regular: it works well when objects are spread over many similar
+// "directories", and is exact (but slow) when there are no more than samples of them.
+func (s3 *S3) EstimateSize(ctx context.Context, prefix, delimiter string, samples int) (*SizeEstimate, error) {
+	objects, prefixes, er := s3.List(ctx, prefix, delimiter)
+	if er != nil {
+		return nil, er
+	}
+
+	est := &SizeEstimate{TotalPrefixes: len(prefixes)}
+
+	var directCount, directBytes int64
+	for _, obj := range objects {
+		directCount++
+		directBytes += obj.Size
+	}
+
+	if samples > len(prefixes) {
+		samples = len(prefixes)
+	}
+
+	counts := make([]float64, 0, samples)
+	sizes := make([]float64, 0, samples)
+
+	for _, idx := range rand.Perm(len(prefixes))[:samples] {
+		var count, size float64
+
+		er := s3.Walk(ctx, prefixes[idx], func(obj ObjectSummary) error {
+			count++
+			size += float64(obj.Size)
+			return nil
+		})
+		if er != nil {
+			return nil, er
+		}
+
+		counts = append(counts, count)
+		sizes = append(sizes, size)
+	}
+
+	est.SampledPrefixes = samples
+	est.Exact = samples == len(prefixes)
+
+	est.Objects, est.ObjectsLow, est.ObjectsHigh = extrapolate(directCount, counts, len(prefixes))
+	est.Bytes, est.BytesLow, est.BytesHigh = extrapolate(directBytes, sizes, len(prefixes))
+
+	return est, nil
+}
+
+// extrapolate estimates the total of a quantity over population units given its value in a
+// random sample of them (plus a known exact amount), returning the estimate and the bounds of
+// its confidence interval.
+func extrapolate(exact int64, sample []float64, population int) (estimate, low, high int64) {
+	n := float64(len(sample))
+	N := float64(population)
+
+	if n == 0 {
+		return exact, exact, exact
+	}
+
+	var mean float64
+	for _, v := range sample {
+		mean += v
+	}
+	mean /= n
+
+	var variance float64
+	if n > 1 {
+		for _, v := range sample {
+			variance += (v - mean) * (v - mean)
+		}
+		variance /= n - 1
+	}
+
+	/* Standard error of the estimated total, with the finite population correction (so
+	 * that sampling every unit leaves no uncertainty) */
+	stdErr := N * math.Sqrt((1-n/N)*variance/n)
+
+	total := float64(exact) + N*mean
+	margin := estimateZ * stdErr
+
+	low = int64(math.Floor(total - margin))
+	if low < exact {
+		low = exact
+	}
+
+	return int64(math.Round(total)), low, int64(math.Ceil(total + margin))
+}
@@ -0,0 +1,215 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// expressSuffix terminates the name of every S3 Express One Zone directory bucket, which
+	// looks like "name--usw2-az1--x-s3".
+	expressSuffix = "--x-s3"
+
+	// expressRefresh is how long before expiry a directory bucket session is renewed. Sessions
+	// last five minutes, so this keeps requests from racing the expiration.
+	expressRefresh = time.Minute
+)
+
+// expressSession caches the temporary credentials returned by CreateSession. It is shared by
+// every copy of an S3, so that copies don't each create their own sessions.
+type expressSession struct {
+	lock       sync.Mutex
+	accessId   string
+	secret     string
+	token      string
+	expiration time.Time
+}
+
+type s3createSessionResp struct {
+	XMLName     xml.Name `xml:"CreateSessionResult"`
+	Credentials struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      time.Time
+	}
+}
+
+// NewS3Express allocates a new S3 for an S3 Express One Zone directory bucket in region. The
+// availability zone is taken from the bucket name, and requests are sent to the zonal endpoint
+// for it.
+//
+// Directory buckets only accept Signature Version 4, and authenticate data requests with
+// short-lived session credentials. These are obtained with CreateSession (using the passed
+// credentials) the first time they are needed, and renewed automatically before they expire.
+//
+// ListObjectsV2 behaves differently on directory buckets: results are not returned in
+// lexicographical order, "/" is the only supported delimiter, and prefixes must end in the
+// delimiter when one is used.
+//
+// New and NewS3 recognize directory buckets by their names too, taking the region from the
+// availability zone ID where it can be (see WithRegion).
+func NewS3Express(bucket, region, accessId, secret string) (*S3, error) {
+	if _, er := expressZone(bucket); er != nil {
+		return nil, er
+	}
+
+	return New(bucket, WithCredentials(accessId, secret, ""), WithRegion(region))
+}
+
+// zoneDirections maps the direction in availability zone IDs to the one in region names.
+var zoneDirections = map[string]string{
+	"e":  "east",
+	"w":  "west",
+	"n":  "north",
+	"s":  "south",
+	"c":  "central",
+	"ne": "northeast",
+	"nw": "northwest",
+	"se": "southeast",
+	"sw": "southwest",
+}
+
+// expressRegion derives the region of an availability zone from its ID, such as us-west-2 from
+// "usw2-az1", returning "" if it can't.
+func expressRegion(zone string) string {
+	code, _, _ := strings.Cut(zone, "-")
+
+	digits := strings.IndexAny(code, "0123456789")
+	if digits < 3 {
+		return ""
+	}
+
+	direction, ok := zoneDirections[code[2:digits]]
+	if !ok {
+		return ""
+	}
+
+	return code[:2] + "-" + direction + "-" + code[digits:]
+}
+
+// setExpressBucket makes the S3 address the directory bucket named bucket, through the zonal
+// endpoint of its availability zone, with a session of its own.
+func (s3 *S3) setExpressBucket(bucket string) {
+	zone, er := expressZone(bucket)
+	if region := expressRegion(zone); region != "" {
+		s3.region = region
+	}
+
+	switch {
+	case er != nil:
+		s3.bucketErr = er
+	case s3.region == "":
+		s3.bucketErr = fmt.Errorf("s3: cannot tell the region of directory bucket %#v; set it with WithRegion", bucket)
+	}
+
+	s3.express = &expressSession{}
+	s3.pathStyle = false
+	s3.baseHost = fmt.Sprintf("s3express-%s.%s.amazonaws.com", zone, s3.region)
+}
+
+// expressZone extracts the availability zone ID from the name of a directory bucket.
+func expressZone(bucket string) (string, error) {
+	if !strings.HasSuffix(bucket, expressSuffix) {
+		return "", fmt.Errorf("s3: %#v is not a directory bucket name (missing %#v suffix)", bucket, expressSuffix)
+	}
+
+	base := strings.TrimSuffix(bucket, expressSuffix)
+
+	idx := strings.LastIndex(base, "--")
+	if idx < 0 || idx+2 == len(base) {
+		return "", fmt.Errorf("s3: %#v is not a directory bucket name (missing zone ID)", bucket)
+	}
+
+	return base[idx+2:], nil
+}
+
+// expressCredentials returns the current session credentials for a directory bucket, creating
+// a new session first (with ctx) if there is none or it is about to expire.
+func (s3 *S3) expressCredentials(ctx context.Context) (Credentials, error) {
+	sess := s3.express
+
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if s3.now().Add(expressRefresh).After(sess.expiration) {
+		if er := s3.createSession(ctx, sess); er != nil {
+			return Credentials{}, er
+		}
+	}
+
+	return Credentials{
+		AccessId:   sess.accessId,
+		Secret:     sess.secret,
+		Token:      sess.token,
+		Expiration: sess.expiration,
+	}, nil
+}
+
+// signExpress signs req for a directory bucket with the current session credentials.
+func (s3 *S3) signExpress(req *http.Request) error {
+	creds, er := s3.expressCredentials(req.Context())
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("x-amz-s3session-token", creds.Token)
+	signV4(req, creds.AccessId, creds.Secret, s3.currentRegion(), "s3express", s3.now())
+
+	return nil
+}
+
+// createSession calls CreateSession with the long-term credentials and stores the resulting
+// session credentials in sess. The caller must hold sess.lock.
+func (s3 *S3) createSession(ctx context.Context, sess *expressSession) error {
+	values := url.Values{}
+	values.Set("session", "")
+
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource("", values), nil)
+	if er != nil {
+		return er
+	}
+
+	creds, er := s3.creds.get()
+	if er != nil {
+		return er
+	}
+
+	if creds.Token != "" {
+		req.Header.Set("x-amz-security-token", creds.Token)
+	}
+
+	req.Header.Set("x-amz-create-session-mode", "ReadWrite")
+	s3.applySigningHost(req)
+	signV4(req, creds.AccessId, creds.Secret, s3.currentRegion(), "s3express", s3.now())
+
+	resp, er := s3.send(req)
+	if er != nil {
+		return er
+	}
+	defer resp.Body.Close()
+
+	xmlBytes, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return er
+	}
+
+	var xmlResp s3createSessionResp
+	if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+		return er
+	}
+
+	sess.accessId = xmlResp.Credentials.AccessKeyId
+	sess.secret = xmlResp.Credentials.SecretAccessKey
+	sess.token = xmlResp.Credentials.SessionToken
+	sess.expiration = xmlResp.Credentials.Expiration
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SkipUnchanged makes PutFile check the destination with a HEAD request first, and skip the
+// upload if the remote object already has the same size and content as the local file. This
+// turns repeated uploads of mostly-unchanged files into near no-ops.
+//
+// The content is compared using the ETag, which S3 sets to the MD5 of the object for single
+// request uploads. Objects uploaded with the multipart API have an ETag computed from the MD5s
+// of the individual parts; these are only recognized if they were uploaded by this package
+// with the same part size (see SetPartSize).
+func SkipUnchanged() RequestOption {
+	return func(config *requestConfig) {
+		config.skipUnchanged = true
+	}
+}
+
+// fileETag computes the ETag S3 would assign to the content of f if it were uploaded by Put,
+// along with the plain MD5 of the content.
+func (s3 *S3) fileETag(f io.ReadSeeker, size int64) (etag string, md5sum []byte, er error) {
+	if _, er := f.Seek(0, io.SeekStart); er != nil {
+		return "", nil, er
+	}
+	defer f.Seek(0, io.SeekStart)
+
+	whole := md5.New()
+
+	if size <= multipartThreshold {
+		if _, er := io.Copy(whole, f); er != nil {
+			return "", nil, er
+		}
+
+		md5sum = whole.Sum(nil)
+		return hex.EncodeToString(md5sum), md5sum, nil
+	}
+
+	partSums := md5.New()
+	parts := 0
+
+	partSize := s3.uploadPartSize()
+
+	for remaining := size; remaining > 0; remaining -= partSize {
+		part := md5.New()
+
+		if _, er := io.CopyN(io.MultiWriter(part, whole), f, partSize); er != nil && er != io.EOF {
+			return "", nil, er
+		}
+
+		partSums.Write(part.Sum(nil))
+		parts++
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(partSums.Sum(nil)), parts), whole.Sum(nil), nil
+}
+
+// PutFile uploads the local file at localPath to remotePath, using its size and guessing its
+// Content-Type from the file extension, or failing that from its first 512 bytes. Large files are
+// uploaded as PutReaderAt does, so a part that fails is read from the file again and retried on
+// its own. Any opts are passed on to Put; see also SkipUnchanged.
+func (s3 *S3) PutFile(ctx context.Context, localPath, remotePath string, opts ...RequestOption) error {
+	f, er := os.Open(localPath)
+	if er != nil {
+		return er
+	}
+	defer f.Close()
+
+	info, er := f.Stat()
+	if er != nil {
+		return er
+	}
+
+	size := info.Size()
+	config := newRequestConfig(opts)
+
+	var md5sum []byte
+
+	if config.skipUnchanged {
+		etag, sum, er := s3.fileETag(f, size)
+		if er != nil {
+			return er
+		}
+
+		var s3er *S3Error
+
+		if header, er := s3.Head(ctx, remotePath); er == nil {
+			remoteSize, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+			remoteETag := strings.Trim(header.Get("ETag"), `"`)
+
+			if remoteSize == size && remoteETag == etag {
+				return nil
+			}
+
+		} else if !errors.As(er, &s3er) || s3er.Code != 404 {
+			return er
+		}
+
+		md5sum = sum
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(localPath))
+	if contentType == "" {
+		if contentType, er = sniffContentType(f); er != nil {
+			return er
+		}
+	}
+
+	/* Compressed content can't be read from offsets of the file */
+	if config.gzip {
+		return s3.Put(ctx, f, size, remotePath, md5sum, contentType, opts...)
+	}
+
+	return s3.putReaderAt(ctx, f, size, remotePath, md5sum, contentType, opts)
+}
+
+// sniffContentType guesses the Content-Type of the content of f from its beginning, leaving f
+// positioned at the start.
+func sniffContentType(f io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+
+	n, er := io.ReadFull(f, head)
+	if er != nil && er != io.EOF && er != io.ErrUnexpectedEOF {
+		return "", er
+	}
+
+	if _, er := f.Seek(0, io.SeekStart); er != nil {
+		return "", er
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
+
+// GetToFile downloads the object at remotePath to the local file at localPath. The object is
+// written to a temporary file alongside it, which is renamed into place once the download is
+// complete, so localPath never holds a partial download. Any opts are passed on to Get.
+func (s3 *S3) GetToFile(ctx context.Context, remotePath, localPath string, opts ...RequestOption) error {
+	body, _, er := s3.Get(ctx, remotePath, opts...)
+	if er != nil {
+		return er
+	}
+	defer body.Close()
+
+	return writeFileAtomic(localPath, func(w io.Writer) error {
+		_, er := io.Copy(w, body)
+		return er
+	})
+}
+
+// writeFileAtomic replaces the file at path with what write writes. It is written to a temporary
+// file alongside first, and renamed into place only once it is complete, so a failure or crash
+// leaves any previous version intact.
+func writeFileAtomic(path string, write func(w io.Writer) error) (er error) {
+	f, er := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if er != nil {
+		return er
+	}
+	defer func() {
+		if er != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if er := write(f); er != nil {
+		return er
+	}
+
+	if er := f.Close(); er != nil {
+		return er
+	}
+
+	return os.Rename(f.Name(), path)
+}
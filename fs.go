@@ -0,0 +1,263 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BucketFS is a read-only io/fs.FS over the objects under a prefix of a bucket, as returned by FS.
+// Keys are split into directories at slashes: a directory exists wherever there are objects
+// below it. Files support Seek and ReadAt, reading the object with ranged GETs (see
+// ObjectReader), so a BucketFS can be served with http.FS. Requests are made with
+// context.Background(), since io/fs has no way to pass a context.
+type BucketFS struct {
+	s3     *S3
+	prefix string
+}
+
+var (
+	_ fs.ReadDirFS = (*BucketFS)(nil)
+	_ fs.StatFS    = (*BucketFS)(nil)
+)
+
+// FS returns a BucketFS over the objects whose keys begin with prefix, which should normally end
+// in a slash (or be empty, for the whole bucket). Names in the file system are the rest of the
+// keys.
+func (s3 *S3) FS(prefix string) *BucketFS {
+	return &BucketFS{s3: s3, prefix: prefix}
+}
+
+// key returns the key that name refers to, or the prefix of the directory it names if dir is true.
+func (bfs *BucketFS) key(name string, dir bool) string {
+	if name == "." {
+		return bfs.prefix
+	}
+
+	if dir {
+		return bfs.prefix + name + "/"
+	}
+
+	return bfs.prefix + name
+}
+
+// Open opens the object called name, or the directory if there is no such object but there are
+// objects below it.
+func (bfs *BucketFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	ctx := context.Background()
+
+	if name != "." {
+		header, er := bfs.s3.Head(ctx, bfs.key(name, false))
+		if er == nil {
+			rd, er := bfs.s3.newObjectReader(ctx, bfs.key(name, false), header, nil)
+			if er != nil {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: er}
+			}
+
+			info := &bucketFileInfo{name: pathpkg.Base(name), size: rd.Size()}
+			info.modTime, _ = http.ParseTime(header.Get("Last-Modified"))
+
+			return &bucketFile{ObjectReader: rd, info: info}, nil
+
+		} else if !isNotFound(er) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: er}
+		}
+	}
+
+	info, er := bfs.statDir(name)
+	if er != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: er}
+	}
+
+	return &bucketDir{bfs: bfs, name: name, info: info}, nil
+}
+
+// Stat describes the object called name, or the directory if there is no such object.
+func (bfs *BucketFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name != "." {
+		info, er := bfs.s3.Stat(context.Background(), bfs.key(name, false))
+		if er == nil {
+			return &bucketFileInfo{name: pathpkg.Base(name), size: info.Size, modTime: info.LastModified}, nil
+
+		} else if !isNotFound(er) {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: er}
+		}
+	}
+
+	info, er := bfs.statDir(name)
+	if er != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: er}
+	}
+
+	return info, nil
+}
+
+// statDir describes the directory called name, checking with a listing that it has something in
+// it. The root always exists.
+func (bfs *BucketFS) statDir(name string) (*bucketFileInfo, error) {
+	info := &bucketFileInfo{name: pathpkg.Base(name), dir: true}
+
+	if name == "." {
+		return info, nil
+	}
+
+	page, er := bfs.s3.listPage(context.Background(), bfs.key(name, true), "/", "", []ListOption{ListPageSize(1)})
+	if er != nil {
+		return nil, er
+	}
+
+	if len(page.objects) == 0 && len(page.prefixes) == 0 {
+		return nil, fs.ErrNotExist
+	}
+
+	return info, nil
+}
+
+// ReadDir lists the directory called name, sorted by file name.
+func (bfs *BucketFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	prefix := bfs.key(name, true)
+
+	objects, prefixes, er := bfs.s3.List(context.Background(), prefix, "/")
+	if er != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: er}
+	}
+
+	entries := []fs.DirEntry{}
+
+	for _, obj := range objects {
+		/* Skip the empty objects some tools create to mark directories */
+		if obj.Key == prefix {
+			continue
+		}
+
+		entries = append(entries, fs.FileInfoToDirEntry(&bucketFileInfo{
+			name:    strings.TrimPrefix(obj.Key, prefix),
+			size:    obj.Size,
+			modTime: obj.LastModified,
+		}))
+	}
+
+	for _, sub := range prefixes {
+		entries = append(entries, fs.FileInfoToDirEntry(&bucketFileInfo{
+			name: strings.TrimSuffix(strings.TrimPrefix(sub, prefix), "/"),
+			dir:  true,
+		}))
+	}
+
+	if len(entries) == 0 && len(objects) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// isNotFound reports whether er is S3 saying there is no such object.
+func isNotFound(er error) bool {
+	var s3er *S3Error
+	return errors.As(er, &s3er) && s3er.Code == http.StatusNotFound
+}
+
+// bucketFileInfo describes a file or directory of a BucketFS.
+type bucketFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (info *bucketFileInfo) Name() string       { return info.name }
+func (info *bucketFileInfo) Size() int64        { return info.size }
+func (info *bucketFileInfo) ModTime() time.Time { return info.modTime }
+func (info *bucketFileInfo) IsDir() bool        { return info.dir }
+func (info *bucketFileInfo) Sys() any           { return nil }
+
+func (info *bucketFileInfo) Mode() fs.FileMode {
+	if info.dir {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+// bucketFile is an object opened from a BucketFS.
+type bucketFile struct {
+	*ObjectReader
+	info *bucketFileInfo
+}
+
+func (f *bucketFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// bucketDir is a directory opened from a BucketFS. It is listed on the first call to ReadDir.
+type bucketDir struct {
+	bfs     *BucketFS
+	name    string
+	info    *bucketFileInfo
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (dir *bucketDir) Stat() (fs.FileInfo, error) {
+	return dir.info, nil
+}
+
+func (dir *bucketDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: dir.name, Err: errors.New("is a directory")}
+}
+
+func (dir *bucketDir) Close() error {
+	return nil
+}
+
+func (dir *bucketDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !dir.listed {
+		entries, er := dir.bfs.ReadDir(dir.name)
+		if er != nil {
+			return nil, er
+		}
+
+		dir.entries = entries
+		dir.listed = true
+	}
+
+	if n <= 0 {
+		entries := dir.entries
+		dir.entries = nil
+		return entries, nil
+	}
+
+	if len(dir.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(dir.entries) {
+		n = len(dir.entries)
+	}
+
+	entries := dir.entries[:n]
+	dir.entries = dir.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GCOptions configures CollectGarbage.
+type GCOptions struct {
+	// Prefixes lists the temporary or staging prefixes to clean up. The empty prefix (the whole
+	// bucket) is refused, as a guard against misconfiguration.
+	Prefixes []string
+
+	// TTL is how old an object must be, by its LastModified time, to be deleted.
+	TTL time.Duration
+
+	// DryRun reports what would be deleted without deleting anything.
+	DryRun bool
+
+	// RateLimit caps the number of objects deleted per second; zero means no limit.
+	RateLimit int
+}
+
+// GCResult reports what CollectGarbage did.
+type GCResult struct {
+	Scanned int            // Objects listed under the prefixes.
+	Expired []string       // Keys that were deleted, or would have been with DryRun.
+	Failed  []DeleteResult // Keys that S3 refused to delete.
+}
+
+// CollectGarbage deletes the objects under each of opts.Prefixes that were last modified more
+// than opts.TTL ago. It is intended to be run periodically to clean up after processes that
+// stage uploads under a temporary prefix and occasionally fail to remove them.
+//
+// Expired objects are deleted in batches as the listing proceeds, at no more than
+// opts.RateLimit objects per second if it is set. Keys that couldn't be deleted are reported in
+// the result rather than stopping the collection; an error is returned if a request fails
+// outright, along with the result so far.
+func (s3 *S3) CollectGarbage(ctx context.Context, opts GCOptions) (*GCResult, error) {
+	for _, prefix := range opts.Prefixes {
+		if prefix == "" {
+			return nil, fmt.Errorf("s3: refusing to garbage collect the whole bucket")
+		}
+	}
+
+	result := &GCResult{Expired: []string{}, Failed: []DeleteResult{}}
+	cutoff := s3.now().Add(-opts.TTL)
+
+	batchSize := maxDeleteKeys
+	if opts.RateLimit > 0 && opts.RateLimit < batchSize {
+		batchSize = opts.RateLimit
+	}
+
+	batch := []string{}
+	var lastBatch time.Time
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		/* Space the batches out so that, on average, no more than RateLimit objects are
+		 * deleted per second */
+		if opts.RateLimit > 0 && !lastBatch.IsZero() {
+			wait := time.Duration(len(batch))*time.Second/time.Duration(opts.RateLimit) - time.Since(lastBatch)
+
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		lastBatch = time.Now()
+
+		results, er := s3.DeleteMulti(ctx, batch)
+
+		for _, res := range results {
+			if res.Deleted {
+				result.Expired = append(result.Expired, res.Key)
+			} else {
+				result.Failed = append(result.Failed, res)
+			}
+		}
+
+		batch = batch[:0]
+		return er
+	}
+
+	for _, prefix := range opts.Prefixes {
+		er := s3.Walk(ctx, prefix, func(obj ObjectSummary) error {
+			result.Scanned++
+
+			if !obj.LastModified.Before(cutoff) {
+				return nil
+			}
+
+			if opts.DryRun {
+				result.Expired = append(result.Expired, obj.Key)
+				return nil
+			}
+
+			batch = append(batch, obj.Key)
+
+			if len(batch) == batchSize {
+				return flush()
+			}
+
+			return nil
+		})
+		if er != nil {
+			return result, er
+		}
+	}
+
+	if er := flush(); er != nil {
+		return result, er
+	}
+
+	return result, nil
+}
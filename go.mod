@@ -0,0 +1,3 @@
+module github.com/lye/s3/v2
+
+go 1.23.0
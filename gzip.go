@@ -0,0 +1,130 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// uncompressedSizeMeta names the metadata WithGzip records the size of the content in.
+const uncompressedSizeMeta = "uncompressed-size"
+
+// gzipReadSize is how much of the content gzipReader compresses at a time.
+const gzipReadSize = 32 * 1024
+
+// WithGzip makes Put and PutStream compress the content with gzip as it is uploaded, storing it
+// with "Content-Encoding: gzip" and the size of the uncompressed content in its metadata (see
+// UncompressedSize). Get decompresses such objects again transparently. The md5sum passed to
+// Put, which is of the uncompressed content, is ignored.
+//
+// The size is only recorded for content uploaded with a single request, since a multipart
+// upload has to be started before all of its content has been read.
+func WithGzip() RequestOption {
+	return func(config *requestConfig) {
+		config.gzip = true
+	}
+}
+
+// UncompressedSize returns the size of the content of an object uploaded with WithGzip, from the
+// headers of a response to Head or Get, reporting false if it wasn't recorded.
+func UncompressedSize(header http.Header) (int64, bool) {
+	size, er := strconv.ParseInt(header.Get(metaPrefix+uncompressedSizeMeta), 10, 64)
+	if er != nil {
+		return 0, false
+	}
+
+	return size, true
+}
+
+// gzipReader compresses the content read from r as it is read itself, counting the bytes of
+// content until they have all been read.
+type gzipReader struct {
+	r    io.Reader
+	zw   *gzip.Writer
+	buf  bytes.Buffer
+	read []byte
+	size int64
+	done bool
+}
+
+func newGzipReader(r io.Reader) *gzipReader {
+	gr := &gzipReader{r: r, read: make([]byte, gzipReadSize)}
+	gr.zw = gzip.NewWriter(&gr.buf)
+
+	return gr
+}
+
+func (gr *gzipReader) Read(p []byte) (int, error) {
+	for gr.buf.Len() == 0 && !gr.done {
+		n, er := gr.r.Read(gr.read)
+		gr.size += int64(n)
+
+		if n > 0 {
+			gr.zw.Write(gr.read[:n])
+		}
+
+		if er == io.EOF {
+			gr.zw.Close()
+			gr.done = true
+
+		} else if er != nil {
+			return 0, er
+		}
+	}
+
+	if gr.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return gr.buf.Read(p)
+}
+
+// sizeOption returns an option recording the size of the content in metadata, once it has all
+// been read. Options are applied as requests are made, so it takes effect for requests made once
+// the content has been compressed, and is a no-op for any made before.
+func (gr *gzipReader) sizeOption() RequestOption {
+	return func(config *requestConfig) {
+		if gr.done {
+			config.header.Set(metaPrefix+uncompressedSizeMeta, strconv.FormatInt(gr.size, 10))
+		}
+	}
+}
+
+// gzipUpload wraps r to be compressed as it is uploaded, returning it along with the options to
+// upload it with.
+func gzipUpload(r io.Reader, opts []RequestOption) (io.Reader, []RequestOption) {
+	gr := newGzipReader(r)
+	opts = append(append([]RequestOption{}, opts...), WithContentEncoding("gzip"), gr.sizeOption())
+
+	return gr, opts
+}
+
+// gunzipBody returns body, the body of resp, wrapped to decompress it if S3 returned it as stored
+// with "Content-Encoding: gzip", which happens when the transport doesn't decompress it itself
+// because the request asked for the stored bytes. Like the transport, it removes the headers
+// describing the compressed content, and leaves partial content alone.
+func gunzipBody(resp *http.Response, body io.ReadCloser, path string) (io.ReadCloser, error) {
+	if resp.Uncompressed || resp.StatusCode != http.StatusOK || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return body, nil
+	}
+
+	zr, er := gzip.NewReader(body)
+	if er != nil {
+		body.Close()
+		return nil, fmt.Errorf("s3: invalid gzip content of %s: %w", path, er)
+	}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, body}, nil
+}
@@ -0,0 +1,128 @@
+package s3
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	pathpkg "path"
+	"strings"
+)
+
+// handlerRequestHeaders are passed on from the client to S3 by Handler, so that S3 handles range
+// and conditional requests itself.
+var handlerRequestHeaders = []string{
+	"Range",
+	"If-Range",
+	"If-Match",
+	"If-None-Match",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+}
+
+// handlerResponseHeaders are passed on from S3 to the client by Handler.
+var handlerResponseHeaders = []string{
+	"Accept-Ranges",
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+}
+
+// Handler returns an http.Handler that serves the objects under prefix, mapping the path of each
+// request (without its leading slash) to the rest of the key. It answers GET and HEAD requests
+// only, passing range and conditional headers on to S3, and the object's Content-Type, ETag,
+// Last-Modified and other representation headers back, so that clients can resume downloads and
+// revalidate their caches. The requests to S3 are signed with the S3's credentials (unless it is
+// anonymous), so the handler can serve a private bucket as a thin proxy; put whatever
+// authentication the content needs in front of it.
+//
+// Missing objects, and those the credentials may not read, are reported as 404s; other failures
+// of S3 as 502s. For a file server over the bucket that also lists directories, see FS, which
+// can be served with http.FileServer(http.FS(...)).
+func (s3 *S3) Handler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(pathpkg.Clean("/"+r.URL.Path), "/")
+		if name == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		req, er := http.NewRequestWithContext(r.Context(), r.Method, s3.resource(prefix+name, nil), nil)
+		if er != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		for _, name := range handlerRequestHeaders {
+			if val := r.Header.Get(name); val != "" {
+				req.Header.Set(name, val)
+			}
+		}
+
+		/* Objects are relayed as stored; the transport mustn't decompress them */
+		req.Header.Set("Accept-Encoding", "identity")
+
+		resp, er := s3.do(req)
+
+		var s3er *S3Error
+		if errors.As(er, &s3er) {
+			relayError(w, r, s3er)
+			return
+
+		} else if er != nil {
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		copyHeaders(w.Header(), resp.Header, handlerResponseHeaders)
+		w.WriteHeader(resp.StatusCode)
+
+		if r.Method == "GET" {
+			io.Copy(w, resp.Body)
+		}
+	})
+}
+
+// relayError relays the error S3 responded to a request from Handler with.
+func relayError(w http.ResponseWriter, r *http.Request, s3er *S3Error) {
+	switch s3er.Code {
+	case http.StatusNotModified:
+		copyHeaders(w.Header(), s3er.Header, []string{"Cache-Control", "ETag", "Expires", "Last-Modified"})
+		w.WriteHeader(http.StatusNotModified)
+
+	case http.StatusPreconditionFailed:
+		http.Error(w, http.StatusText(s3er.Code), s3er.Code)
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		copyHeaders(w.Header(), s3er.Header, []string{"Content-Range"})
+		http.Error(w, http.StatusText(s3er.Code), s3er.Code)
+
+	case http.StatusNotFound, http.StatusForbidden:
+		http.NotFound(w, r)
+
+	default:
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+	}
+}
+
+// copyHeaders copies the headers called names from src to dst.
+func copyHeaders(dst, src http.Header, names []string) {
+	for _, name := range names {
+		if vals := src.Values(name); len(vals) > 0 {
+			dst[http.CanonicalHeaderKey(name)] = vals
+		}
+	}
+}
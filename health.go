@@ -0,0 +1,102 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// ProbePrefix is the prefix under which Probe writes its probe objects. A lifecycle rule expiring
+// objects under it is a good backstop for probes that never get to clean up after themselves.
+const ProbePrefix = ".s3-probe/"
+
+// defaultProbeInstance names the current process for Test's probes.
+func defaultProbeInstance() string {
+	host, er := os.Hostname()
+	if er != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Probe checks that the bucket can be written to and read from by writing a short object, reading
+// it back and deleting it, as a health check. Each probe uses its own key, under ProbePrefix and
+// then instance, so that any number of replicas of a service can probe the same bucket at once
+// without reading each other's objects.
+//
+// Probe objects left behind by replicas that died mid-probe are deleted by later probes once they
+// are older than ttl, on a best-effort basis (listing the prefix may not be permitted); pass 0 to
+// leave them alone.
+func (s3 *S3) Probe(ctx context.Context, instance string, ttl time.Duration) error {
+	if instance == "" || strings.Contains(instance, "/") {
+		return fmt.Errorf("s3: invalid probe instance name %#v", instance)
+	}
+
+	key := fmt.Sprintf("%s%s/%d", ProbePrefix, instance, rand.Int63())
+	testString := fmt.Sprintf("roundtrip-test-%d", rand.Int())
+	testReader := strings.NewReader(testString)
+
+	var s3er *S3Error
+
+	if er := s3.Put(ctx, testReader, int64(testReader.Len()), key, nil, "text/x-empty"); er != nil {
+		if errors.As(er, &s3er) && s3er.ShouldRetry {
+			return s3.Probe(ctx, instance, ttl)
+		}
+
+		return er
+	}
+	defer s3.Delete(ctx, key)
+
+	actualReader, header, er := s3.Get(ctx, key)
+	if er != nil {
+		if errors.As(er, &s3er) && s3er.ShouldRetry {
+			return s3.Probe(ctx, instance, ttl)
+		}
+
+		return er
+	}
+	defer actualReader.Close()
+
+	actualBytes, er := io.ReadAll(actualReader)
+	if er != nil {
+		return er
+	}
+
+	if string(actualBytes) != testString {
+		return fmt.Errorf("String read back from S3 was different than what we put there.")
+	}
+
+	if header.Get("Content-Type") != "text/x-empty" {
+		return fmt.Errorf("Content served back from S3 had a different Content-Type than what we put there")
+	}
+
+	if ttl > 0 {
+		s3.sweepProbes(ctx, ttl)
+	}
+
+	return nil
+}
+
+// sweepProbes deletes the probe objects older than ttl, ignoring any errors.
+func (s3 *S3) sweepProbes(ctx context.Context, ttl time.Duration) {
+	cutoff := s3.now().Add(-ttl)
+	stale := []string{}
+
+	s3.Walk(ctx, ProbePrefix, func(obj ObjectSummary) error {
+		if obj.LastModified.Before(cutoff) {
+			stale = append(stale, obj.Key)
+		}
+
+		return nil
+	})
+
+	if len(stale) > 0 {
+		s3.DeleteMulti(ctx, stale)
+	}
+}
@@ -0,0 +1,237 @@
+package s3
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// indexBatchSize is how many changed entries Index.Refresh writes to its store at once.
+const indexBatchSize = 1000
+
+// IndexStore is where an Index keeps the listing metadata it mirrors. MemoryIndexStore and
+// FileIndexStore are provided; for buckets too large to hold in memory, implement it on top of an
+// embedded database such as bbolt or SQLite, with keys as the primary key.
+type IndexStore interface {
+	// Lookup returns the entry for key, and whether there is one.
+	Lookup(key string) (ObjectSummary, bool, error)
+
+	// Store adds or replaces the entries for objs.
+	Store(objs []ObjectSummary) error
+
+	// Remove deletes the entries for keys.
+	Remove(keys []string) error
+
+	// Keys calls fn with each key beginning with prefix, in any order, stopping if fn returns an
+	// error (which is then returned).
+	Keys(prefix string, fn func(key string) error) error
+}
+
+// Index mirrors the listing of a bucket into a local IndexStore, so that whether an object
+// exists, and its size and ETag, can be looked up instantly and offline, as sync and
+// deduplication tools need to do for buckets with millions of keys. The index is only as fresh as
+// its last Refresh.
+type Index struct {
+	s3    *S3
+	store IndexStore
+}
+
+// IndexRefresh reports what Index.Refresh changed.
+type IndexRefresh struct {
+	Listed  int // Objects listed under the prefix.
+	Added   int // Objects that weren't in the index.
+	Updated int // Objects whose size, ETag or modification time had changed.
+	Removed int // Objects in the index that no longer exist.
+}
+
+// NewIndex returns an Index of the bucket kept in store.
+func (s3 *S3) NewIndex(store IndexStore) *Index {
+	return &Index{s3: s3, store: store}
+}
+
+// Refresh brings the entries under prefix up to date with the bucket, listing it and writing only
+// the entries that have changed. Refreshing the prefixes that are known to change, rather than
+// the whole bucket, keeps refreshes quick.
+func (idx *Index) Refresh(ctx context.Context, prefix string) (*IndexRefresh, error) {
+	result := &IndexRefresh{}
+	seen := map[string]bool{}
+	batch := []ObjectSummary{}
+
+	er := idx.s3.Walk(ctx, prefix, func(obj ObjectSummary) error {
+		result.Listed++
+		seen[obj.Key] = true
+
+		old, ok, er := idx.store.Lookup(obj.Key)
+		if er != nil {
+			return er
+		}
+
+		switch {
+		case !ok:
+			result.Added++
+		case old.ETag != obj.ETag || old.Size != obj.Size || !old.LastModified.Equal(obj.LastModified) || old.StorageClass != obj.StorageClass:
+			result.Updated++
+		default:
+			return nil
+		}
+
+		batch = append(batch, obj)
+
+		if len(batch) == indexBatchSize {
+			er := idx.store.Store(batch)
+			batch = batch[:0]
+			return er
+		}
+
+		return nil
+	})
+	if er != nil {
+		return result, er
+	}
+
+	if len(batch) > 0 {
+		if er := idx.store.Store(batch); er != nil {
+			return result, er
+		}
+	}
+
+	removed := []string{}
+
+	er = idx.store.Keys(prefix, func(key string) error {
+		if !seen[key] {
+			removed = append(removed, key)
+		}
+
+		return nil
+	})
+	if er != nil {
+		return result, er
+	}
+
+	result.Removed = len(removed)
+	return result, idx.store.Remove(removed)
+}
+
+// Lookup returns the indexed listing entry for key, and whether there is one.
+func (idx *Index) Lookup(key string) (ObjectSummary, bool, error) {
+	return idx.store.Lookup(key)
+}
+
+// Exists reports whether key was in the bucket when the index was last refreshed.
+func (idx *Index) Exists(key string) (bool, error) {
+	_, ok, er := idx.store.Lookup(key)
+	return ok, er
+}
+
+// MemoryIndexStore is an IndexStore that keeps its entries in memory. It is safe for concurrent
+// use.
+type MemoryIndexStore struct {
+	lock    sync.RWMutex
+	entries map[string]ObjectSummary
+}
+
+var _ IndexStore = (*MemoryIndexStore)(nil)
+
+// NewMemoryIndexStore returns an empty MemoryIndexStore.
+func NewMemoryIndexStore() *MemoryIndexStore {
+	return &MemoryIndexStore{entries: map[string]ObjectSummary{}}
+}
+
+// Lookup implements IndexStore.
+func (store *MemoryIndexStore) Lookup(key string) (ObjectSummary, bool, error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	obj, ok := store.entries[key]
+	return obj, ok, nil
+}
+
+// Store implements IndexStore.
+func (store *MemoryIndexStore) Store(objs []ObjectSummary) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for _, obj := range objs {
+		store.entries[obj.Key] = obj
+	}
+
+	return nil
+}
+
+// Remove implements IndexStore.
+func (store *MemoryIndexStore) Remove(keys []string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for _, key := range keys {
+		delete(store.entries, key)
+	}
+
+	return nil
+}
+
+// Keys implements IndexStore, calling fn in sorted order.
+func (store *MemoryIndexStore) Keys(prefix string, fn func(key string) error) error {
+	store.lock.RLock()
+	keys := []string{}
+	for key := range store.entries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	store.lock.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if er := fn(key); er != nil {
+			return er
+		}
+	}
+
+	return nil
+}
+
+// FileIndexStore is a MemoryIndexStore that can be saved to a file and loaded again, so that an
+// index survives restarts without being rebuilt from a full listing.
+type FileIndexStore struct {
+	*MemoryIndexStore
+	path string
+}
+
+// OpenFileIndexStore loads the index saved at path, or returns an empty one if there is no file
+// there yet.
+func OpenFileIndexStore(path string) (*FileIndexStore, error) {
+	store := &FileIndexStore{MemoryIndexStore: NewMemoryIndexStore(), path: path}
+
+	f, er := os.Open(path)
+	if errors.Is(er, os.ErrNotExist) {
+		return store, nil
+
+	} else if er != nil {
+		return nil, er
+	}
+	defer f.Close()
+
+	if er := gob.NewDecoder(f).Decode(&store.entries); er != nil {
+		return nil, er
+	}
+
+	return store, nil
+}
+
+// Save writes the index to its file. The file is replaced atomically, so a crash while saving
+// leaves the previous version intact.
+func (store *FileIndexStore) Save() error {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	return writeFileAtomic(store.path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(store.entries)
+	})
+}
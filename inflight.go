@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SetMaxInFlight bounds how many requests the S3 has in flight at once, across every goroutine
+// using it and every copy derived from it afterwards (with WithBucket, WithRegion and so on), so
+// that batch jobs can't exhaust file descriptors or trip S3's request rate limits. Requests over
+// the limit wait for one of the others to finish, or for their context to be done. A request is in
+// flight until its response has been read: the body returned by Get, for example, counts against
+// the limit until it is closed, so a goroutine holding as many bodies open as the limit can't
+// make any further requests. Zero, the default, removes the limit.
+func (s3 *S3) SetMaxInFlight(n int) {
+	if n <= 0 {
+		s3.inFlight = nil
+		return
+	}
+
+	s3.inFlight = make(chan struct{}, n)
+}
+
+// acquireSlot waits for a request to be allowed in flight, returning the function that ends it.
+func (s3 *S3) acquireSlot(ctx context.Context) (func(), error) {
+	slots := s3.inFlight
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("s3: waiting for a request slot: %w", ctx.Err())
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-slots })
+	}, nil
+}
+
+// slotBody is the body of a response, which ends the request's slot when it is closed.
+type slotBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (sb *slotBody) Close() error {
+	er := sb.ReadCloser.Close()
+	sb.release()
+
+	return er
+}
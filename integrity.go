@@ -0,0 +1,99 @@
+package s3
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// checksumHash is the hash registered with SetChecksumHash.
+type checksumHash struct {
+	name    string
+	newHash func() hash.Hash
+}
+
+// header returns the name of the metadata header the checksum is stored in.
+func (ch *checksumHash) header() string {
+	return "x-amz-meta-checksum-" + ch.name
+}
+
+// SetChecksumHash registers a hash function that Put uses to record a checksum of each object
+// it uploads, and that Get uses to verify downloads. This lets teams that have standardized on a
+// digest other than MD5 (such as BLAKE3 or SHA-256) use it end to end, independently of the
+// checksums S3 computes itself.
+//
+// The hex-encoded digest is stored in the object's user metadata as x-amz-meta-checksum-<name>.
+// Since the metadata must be sent before the content, Put can only compute it when it can read
+// the content twice: when r is an io.ReadSeeker, or when Put buffers r itself (size -1 and the
+// content fits within the buffer limit). Other uploads are stored without the checksum.
+//
+// Get verifies any object carrying a checksum under name, as it is read: the returned reader
+// reports an error wrapping ErrVerification instead of io.EOF if the content doesn't match.
+// Partial (ranged) downloads are not verified. Passing a nil newHash disables both.
+func (s3 *S3) SetChecksumHash(name string, newHash func() hash.Hash) {
+	if newHash == nil {
+		s3.checksum = nil
+		return
+	}
+
+	s3.checksum = &checksumHash{name: strings.ToLower(name), newHash: newHash}
+}
+
+// checksumHeader hashes the next size bytes of r, returning an option carrying the checksum
+// metadata, and rewinds r back to where it was. Nothing is returned if r can't be rewound.
+func (s3 *S3) checksumHeader(r io.Reader, size int64) ([]RequestOption, error) {
+	h := s3.checksum.newHash()
+
+	if ok, er := hashRewind(r, size, h); !ok {
+		return nil, er
+	}
+
+	return []RequestOption{WithHeader(s3.checksum.header(), hex.EncodeToString(h.Sum(nil)))}, nil
+}
+
+// hashRewind writes the next size bytes of r to h, and rewinds r back to where it was. It reports
+// false, without an error, if r can't be rewound, in which case nothing is read.
+func hashRewind(r io.Reader, size int64, h hash.Hash) (bool, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return false, nil
+	}
+
+	start, er := rs.Seek(0, io.SeekCurrent)
+	if er != nil {
+		return false, nil
+	}
+
+	if _, er := io.CopyN(h, rs, size); er != nil {
+		return false, er
+	}
+
+	if _, er := rs.Seek(start, io.SeekStart); er != nil {
+		return false, er
+	}
+
+	return true, nil
+}
+
+// verifyChecksum returns the body of resp, wrapped to verify it against its checksum metadata
+// if it has any for the registered hash.
+func (s3 *S3) verifyChecksum(resp *http.Response, path string) io.ReadCloser {
+	if s3.checksum == nil || resp.StatusCode != http.StatusOK || resp.Uncompressed {
+		return resp.Body
+	}
+
+	expected, er := hex.DecodeString(resp.Header.Get(s3.checksum.header()))
+	if er != nil || len(expected) == 0 {
+		return resp.Body
+	}
+
+	return &verifyingReader{
+		ReadCloser: resp.Body,
+		hash:       s3.checksum.newHash(),
+		expected:   expected,
+		path:       path,
+		source:     "its " + s3.checksum.header() + " metadata",
+	}
+}
@@ -0,0 +1,94 @@
+package s3
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the manifests written by ExportInventory.
+const (
+	InventoryCSV       = "csv"   // A header line, then a line of comma-separated fields per object.
+	InventoryJSONLines = "jsonl" // A JSON object per line.
+)
+
+// inventoryFields name the fields of each object in a manifest, in order: the columns of CSV, and
+// the names of JSON.
+var inventoryFields = []string{"key", "size", "etag", "last_modified", "storage_class"}
+
+// inventoryEntry is an object as a line of a JSON manifest.
+type inventoryEntry struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+	StorageClass string `json:"storage_class"`
+}
+
+// ExportInventory writes a manifest of every object whose key begins with prefix to w, in format
+// (InventoryCSV or InventoryJSONLines), for audits and reconciliation against a database. Each
+// object is described by its key, size, ETag (without quotes), last-modified time (RFC 3339, in
+// UTC) and storage class, in order of key. The listing is fetched and written one page at a time,
+// so only a page of it is held in memory whatever the number of objects; opts customize it as they
+// do Walk's.
+//
+// It returns how many objects were written. If it fails part way, w holds the manifest of the
+// objects before the failure, with every line complete.
+func (s3 *S3) ExportInventory(ctx context.Context, w io.Writer, prefix, format string, opts ...ListOption) (int64, error) {
+	if format != InventoryCSV && format != InventoryJSONLines {
+		return 0, fmt.Errorf("s3: unsupported inventory format %#v", format)
+	}
+
+	buf := bufio.NewWriter(w)
+	csvWriter := csv.NewWriter(buf)
+	jsonEncoder := json.NewEncoder(buf)
+
+	/* Each line is written in full before the next, so a failure leaves no line half-written */
+	writeLine := func(entry inventoryEntry) error {
+		if format == InventoryJSONLines {
+			return jsonEncoder.Encode(entry)
+		}
+
+		csvWriter.Write([]string{entry.Key, strconv.FormatInt(entry.Size, 10), entry.ETag, entry.LastModified, entry.StorageClass})
+		csvWriter.Flush()
+
+		return csvWriter.Error()
+	}
+
+	if format == InventoryCSV {
+		csvWriter.Write(inventoryFields)
+	}
+
+	count := int64(0)
+
+	er := s3.Walk(ctx, prefix, func(obj ObjectSummary) error {
+		entry := inventoryEntry{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         strings.Trim(obj.ETag, `"`),
+			LastModified: obj.LastModified.UTC().Format(time.RFC3339),
+			StorageClass: obj.StorageClass,
+		}
+
+		if er := writeLine(entry); er != nil {
+			return er
+		}
+
+		count++
+		return nil
+	}, opts...)
+
+	csvWriter.Flush()
+
+	if flushErr := buf.Flush(); er == nil {
+		er = flushErr
+	}
+
+	return count, er
+}
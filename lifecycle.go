@@ -0,0 +1,157 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+)
+
+// LifecycleRule is a rule of a bucket's lifecycle configuration, which has S3 expire objects, move
+// them to cheaper storage classes, or clean up after them, a number of days after they were
+// created. Zero fields are left out of the rule.
+type LifecycleRule struct {
+	ID      string
+	Prefix  string // The rule applies to the objects whose keys begin with Prefix (all, if empty).
+	Enabled bool
+
+	// ExpirationDays is the age at which objects are deleted (or, in a versioned bucket, hidden
+	// behind a delete marker).
+	ExpirationDays int
+
+	// Transitions move objects to another storage class at a given age.
+	Transitions []LifecycleTransition
+
+	// NoncurrentExpirationDays is how long versions are kept once they are no longer the
+	// latest, in versioned buckets.
+	NoncurrentExpirationDays int
+
+	// ExpiredObjectDeleteMarker removes delete markers that no longer hide any versions.
+	ExpiredObjectDeleteMarker bool
+
+	// AbortIncompleteMultipartDays is the age at which multipart uploads that were never
+	// completed or aborted are aborted, and their parts deleted.
+	AbortIncompleteMultipartDays int
+}
+
+// LifecycleTransition moves objects to StorageClass once they are Days old.
+type LifecycleTransition struct {
+	Days         int
+	StorageClass string
+}
+
+type s3expiration struct {
+	Days                      int  `xml:",omitempty"`
+	ExpiredObjectDeleteMarker bool `xml:",omitempty"`
+}
+
+type s3noncurrentExpiration struct {
+	NoncurrentDays int
+}
+
+type s3abortIncomplete struct {
+	DaysAfterInitiation int
+}
+
+type s3lifecycleRule struct {
+	ID     string `xml:",omitempty"`
+	Filter struct {
+		Prefix string
+		And    *struct {
+			Prefix string
+		} `xml:",omitempty"`
+	}
+	Prefix                         *string `xml:",omitempty"` // The filter of rules from before filters existed.
+	Status                         string
+	Expiration                     *s3expiration           `xml:",omitempty"`
+	Transitions                    []LifecycleTransition   `xml:"Transition"`
+	NoncurrentVersionExpiration    *s3noncurrentExpiration `xml:",omitempty"`
+	AbortIncompleteMultipartUpload *s3abortIncomplete      `xml:",omitempty"`
+}
+
+type s3lifecycle struct {
+	XMLName xml.Name          `xml:"LifecycleConfiguration"`
+	Rules   []s3lifecycleRule `xml:"Rule"`
+}
+
+// GetBucketLifecycle returns the rules of the bucket's lifecycle configuration, which are empty
+// if it has none. Parts of rules that LifecycleRule can't express, such as filters on tags, are
+// left out.
+func (s3 *S3) GetBucketLifecycle(ctx context.Context) ([]LifecycleRule, error) {
+	var xmlResp s3lifecycle
+	if er := s3.getConfig(ctx, "lifecycle", &xmlResp); isNoConfig(er) {
+		return []LifecycleRule{}, nil
+
+	} else if er != nil {
+		return nil, er
+	}
+
+	rules := make([]LifecycleRule, 0, len(xmlResp.Rules))
+
+	for _, xmlRule := range xmlResp.Rules {
+		rule := LifecycleRule{
+			ID:      xmlRule.ID,
+			Prefix:  xmlRule.Filter.Prefix,
+			Enabled: xmlRule.Status == "Enabled",
+		}
+
+		if xmlRule.Filter.And != nil {
+			rule.Prefix = xmlRule.Filter.And.Prefix
+		} else if xmlRule.Prefix != nil {
+			rule.Prefix = *xmlRule.Prefix
+		}
+
+		if exp := xmlRule.Expiration; exp != nil {
+			rule.ExpirationDays = exp.Days
+			rule.ExpiredObjectDeleteMarker = exp.ExpiredObjectDeleteMarker
+		}
+
+		rule.Transitions = xmlRule.Transitions
+
+		if exp := xmlRule.NoncurrentVersionExpiration; exp != nil {
+			rule.NoncurrentExpirationDays = exp.NoncurrentDays
+		}
+
+		if abort := xmlRule.AbortIncompleteMultipartUpload; abort != nil {
+			rule.AbortIncompleteMultipartDays = abort.DaysAfterInitiation
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// PutBucketLifecycle replaces the bucket's lifecycle configuration with rules. S3 applies rules
+// once a day, so changes take a while to have an effect.
+func (s3 *S3) PutBucketLifecycle(ctx context.Context, rules []LifecycleRule) error {
+	body := s3lifecycle{}
+
+	for _, rule := range rules {
+		xmlRule := s3lifecycleRule{ID: rule.ID, Status: "Disabled", Transitions: rule.Transitions}
+		xmlRule.Filter.Prefix = rule.Prefix
+
+		if rule.Enabled {
+			xmlRule.Status = "Enabled"
+		}
+
+		if rule.ExpirationDays > 0 || rule.ExpiredObjectDeleteMarker {
+			xmlRule.Expiration = &s3expiration{Days: rule.ExpirationDays, ExpiredObjectDeleteMarker: rule.ExpiredObjectDeleteMarker}
+		}
+
+		if rule.NoncurrentExpirationDays > 0 {
+			xmlRule.NoncurrentVersionExpiration = &s3noncurrentExpiration{NoncurrentDays: rule.NoncurrentExpirationDays}
+		}
+
+		if rule.AbortIncompleteMultipartDays > 0 {
+			xmlRule.AbortIncompleteMultipartUpload = &s3abortIncomplete{DaysAfterInitiation: rule.AbortIncompleteMultipartDays}
+		}
+
+		body.Rules = append(body.Rules, xmlRule)
+	}
+
+	return s3.putConfig(ctx, "lifecycle", body, nil)
+}
+
+// DeleteBucketLifecycle removes the bucket's lifecycle configuration, and with it every rule.
+func (s3 *S3) DeleteBucketLifecycle(ctx context.Context) error {
+	return s3.deleteConfig(ctx, "lifecycle")
+}
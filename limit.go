@@ -0,0 +1,71 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrTooLarge is returned when an object is larger than the limit set with WithMaxSize.
+var ErrTooLarge = errors.New("s3: object exceeds the size limit")
+
+// WithMaxSize makes Get fail with ErrTooLarge rather than return an object larger than max
+// bytes. When S3 reports the size up front, the body is never downloaded; otherwise (for
+// example when the object is decompressed on the way) reading the body fails as soon as more
+// than max bytes have arrived.
+func WithMaxSize(max int64) RequestOption {
+	return func(config *requestConfig) {
+		config.maxSize = max
+	}
+}
+
+// GetBytesLimited reads the object at path into memory, provided it is no larger than max bytes;
+// otherwise it fails with ErrTooLarge. This is the safe way to read something expected to be
+// small, such as a configuration file, without risking a multi-gigabyte allocation when it
+// isn't.
+func (s3 *S3) GetBytesLimited(ctx context.Context, path string, max int64, opts ...RequestOption) ([]byte, error) {
+	r, _, er := s3.Get(ctx, path, append(opts, WithMaxSize(max))...)
+	if er != nil {
+		return nil, er
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// limitBody enforces a size limit of max bytes on the body of resp, a response to a request for
+// path.
+func limitBody(resp *http.Response, path string, max int64) (io.ReadCloser, error) {
+	if resp.ContentLength > max {
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3: %s is %d bytes: %w", path, resp.ContentLength, ErrTooLarge)
+	}
+
+	return &limitedReader{ReadCloser: resp.Body, path: path, remaining: max}, nil
+}
+
+// limitedReader fails with ErrTooLarge once more than remaining bytes have been read through it.
+type limitedReader struct {
+	io.ReadCloser
+	path      string
+	remaining int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	/* One byte more than the limit is asked for, so that a body of exactly the limit isn't
+	 * mistaken for one that exceeds it */
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+
+	n, er := lr.ReadCloser.Read(p)
+
+	if int64(n) > lr.remaining {
+		return int(lr.remaining), fmt.Errorf("s3: %s is larger than expected: %w", lr.path, ErrTooLarge)
+	}
+
+	lr.remaining -= int64(n)
+	return n, er
+}
@@ -0,0 +1,250 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ObjectSummary describes a single object returned by a listing.
+type ObjectSummary struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	StorageClass string
+}
+
+type listOptions struct {
+	pageSize   int
+	startAfter string
+}
+
+// ListOption customizes the behavior of List.
+type ListOption func(*listOptions)
+
+// ListPageSize sets the number of keys requested from S3 per page (S3 returns at most 1000,
+// which is also the default).
+func ListPageSize(n int) ListOption {
+	return func(opts *listOptions) {
+		opts.pageSize = n
+	}
+}
+
+// ListStartAfter causes the listing to begin with the first key that sorts after key. It is not
+// supported by directory buckets.
+func ListStartAfter(key string) ListOption {
+	return func(opts *listOptions) {
+		opts.startAfter = key
+	}
+}
+
+type s3listResp struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	IsTruncated           bool
+	NextContinuationToken string
+	EncodingType          string
+	Contents              []ObjectSummary
+	CommonPrefixes        []struct {
+		Prefix string
+	}
+}
+
+// listPage is a single page of results from ListObjectsV2.
+type listPage struct {
+	objects  []ObjectSummary
+	prefixes []string
+	next     string // The continuation token for the next page, or "" if this was the last.
+}
+
+// List enumerates the objects whose keys begin with prefix. If delimiter is non-empty, keys
+// that contain delimiter after the prefix are rolled up: instead of being returned individually,
+// the portion of each key up to and including the delimiter is returned once in the list of
+// common prefixes. This is how directory-style listings are done, using "/" as the delimiter.
+//
+// List follows continuation tokens until the whole listing has been read. For very large
+// listings see Walk, which doesn't hold every result in memory at once.
+//
+// Directory buckets only support "/" as a delimiter, require the prefix to end in the delimiter
+// when one is given, and do not return keys in sorted order.
+func (s3 *S3) List(ctx context.Context, prefix, delimiter string, opts ...ListOption) ([]ObjectSummary, []string, error) {
+	objects := []ObjectSummary{}
+	prefixes := []string{}
+	token := ""
+
+	for {
+		page, er := s3.listPage(ctx, prefix, delimiter, token, opts)
+		if er != nil {
+			return objects, prefixes, er
+		}
+
+		objects = append(objects, page.objects...)
+		prefixes = append(prefixes, page.prefixes...)
+
+		if page.next == "" {
+			return objects, prefixes, nil
+		}
+
+		token = page.next
+	}
+}
+
+// StopWalk may be returned by the function passed to Walk to stop walking without Walk
+// returning an error.
+var StopWalk = errors.New("s3: stop walk")
+
+// Walk calls fn for every object whose key begins with prefix, fetching the listing one page at
+// a time so that only a single page is held in memory. If fn returns an error, Walk stops and
+// returns that error (or nil, if the error was StopWalk).
+func (s3 *S3) Walk(ctx context.Context, prefix string, fn func(ObjectSummary) error, opts ...ListOption) error {
+	token := ""
+
+	for {
+		page, er := s3.listPage(ctx, prefix, "", token, opts)
+		if er != nil {
+			return er
+		}
+
+		for _, obj := range page.objects {
+			if er := fn(obj); errors.Is(er, StopWalk) {
+				return nil
+
+			} else if er != nil {
+				return er
+			}
+		}
+
+		if page.next == "" {
+			return nil
+		}
+
+		token = page.next
+	}
+}
+
+// validateExpressList checks a listing request against the restrictions directory buckets
+// place on ListObjectsV2.
+func validateExpressList(prefix, delimiter string, options listOptions) error {
+	if delimiter != "" && delimiter != "/" {
+		return fmt.Errorf("s3: directory buckets only support \"/\" as a delimiter")
+	}
+
+	if delimiter != "" && prefix != "" && !strings.HasSuffix(prefix, delimiter) {
+		return fmt.Errorf("s3: directory bucket listings require the prefix to end in the delimiter")
+	}
+
+	if options.startAfter != "" {
+		return fmt.Errorf("s3: directory buckets do not support listing with a start-after key")
+	}
+
+	return nil
+}
+
+func (s3 *S3) listPage(ctx context.Context, prefix, delimiter, token string, opts []ListOption) (*listPage, error) {
+	options := listOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if s3.express != nil {
+		if er := validateExpressList(prefix, delimiter, options); er != nil {
+			return nil, er
+		}
+	}
+
+	values := url.Values{}
+	values.Set("list-type", "2")
+	values.Set("encoding-type", "url")
+
+	if prefix != "" {
+		values.Set("prefix", prefix)
+	}
+
+	if delimiter != "" {
+		values.Set("delimiter", delimiter)
+	}
+
+	if token != "" {
+		values.Set("continuation-token", token)
+	}
+
+	if options.pageSize > 0 {
+		values.Set("max-keys", fmt.Sprintf("%d", options.pageSize))
+	}
+
+	if options.startAfter != "" && token == "" {
+		values.Set("start-after", options.startAfter)
+	}
+
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource("", values), nil)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	xmlBytes, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return nil, er
+	}
+
+	var xmlResp s3listResp
+	if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+		return nil, er
+	}
+
+	page := &listPage{objects: xmlResp.Contents}
+
+	for _, cp := range xmlResp.CommonPrefixes {
+		page.prefixes = append(page.prefixes, cp.Prefix)
+	}
+
+	if xmlResp.EncodingType == "url" {
+		if er := page.decode(); er != nil {
+			return nil, er
+		}
+	}
+
+	if xmlResp.IsTruncated {
+		page.next = xmlResp.NextContinuationToken
+	}
+
+	return page, nil
+}
+
+// decode decodes the keys and prefixes of a page listed with encoding-type=url. Listings are
+// requested that way so that keys with characters XML can't carry, such as control characters,
+// are listed too; services that don't support it say so by not echoing the encoding type.
+func (page *listPage) decode() error {
+	for i := range page.objects {
+		key, er := url.QueryUnescape(page.objects[i].Key)
+		if er != nil {
+			return fmt.Errorf("s3: invalid key in listing: %w", er)
+		}
+
+		page.objects[i].Key = key
+	}
+
+	for i := range page.prefixes {
+		prefix, er := url.QueryUnescape(page.prefixes[i])
+		if er != nil {
+			return fmt.Errorf("s3: invalid prefix in listing: %w", er)
+		}
+
+		page.prefixes[i] = prefix
+	}
+
+	return nil
+}
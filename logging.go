@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redactedHeaders are the headers whose values are never logged, since they carry credentials,
+// signatures or encryption keys.
+var redactedHeaders = map[string]bool{
+	"Authorization":                                         true,
+	"X-Amz-Security-Token":                                  true,
+	"X-Amz-Server-Side-Encryption-Customer-Key":             true,
+	"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key": true,
+}
+
+// redactedParams are the query parameters whose values are never logged, as for redactedHeaders.
+var redactedParams = map[string]bool{
+	"Signature":            true,
+	"X-Amz-Signature":      true,
+	"X-Amz-Security-Token": true,
+}
+
+const redacted = "REDACTED"
+
+// SetLogger has every request the S3 sends logged to logger at debug level, once per attempt: its
+// method and resource, the status S3 responded with, how long it took, how many times it had been
+// retried, the request ID S3 assigned it, and its headers. When S3 rejects a signature, the string
+// S3 expected to be signed (and, for Signature Version 4, its canonical request) is logged with
+// it, to compare with what was signed. Passing nil disables logging.
+//
+// The Authorization header, session tokens, signatures in query strings, and customer-provided
+// encryption keys are always replaced by "REDACTED", so logs can be shared without leaking
+// credentials.
+func (s3 *S3) SetLogger(logger *slog.Logger) {
+	s3.logger = logger
+}
+
+// logAttempt logs an attempt at sending req that started at start, and its outcome.
+func (s3 *S3) logAttempt(req *http.Request, resp *http.Response, er error, attempt int, start time.Time) {
+	if s3.logger == nil || !s3.logger.Enabled(req.Context(), slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("resource", redactURL(req.URL)),
+		slog.Int("retries", attempt-1),
+		slog.Duration("latency", time.Since(start)),
+	}
+
+	var s3er *S3Error
+
+	switch {
+	case er == nil:
+		attrs = append(attrs,
+			slog.Int("status", resp.StatusCode),
+			slog.String("request_id", resp.Header.Get("x-amz-request-id")))
+
+	case errors.As(er, &s3er):
+		attrs = append(attrs,
+			slog.Int("status", s3er.Code),
+			slog.String("request_id", s3er.RequestId),
+			slog.String("error_code", s3er.awsCode()))
+
+		if s3er.awsCode() == "SignatureDoesNotMatch" {
+			var details struct {
+				StringToSign     string
+				CanonicalRequest string
+			}
+
+			if xml.Unmarshal(s3er.Body, &details) == nil {
+				attrs = append(attrs,
+					slog.String("string_to_sign", details.StringToSign),
+					slog.String("canonical_request", details.CanonicalRequest))
+			}
+		}
+
+	default:
+		attrs = append(attrs, slog.String("error", er.Error()))
+	}
+
+	attrs = append(attrs, slog.Any("headers", loggedHeaders(req.Header)))
+	s3.logger.LogAttrs(req.Context(), slog.LevelDebug, "s3: request", attrs...)
+}
+
+// redactURL returns u as a string, with the values of redactedParams replaced.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	changed := false
+
+	for name := range query {
+		if redactedParams[name] {
+			query.Set(name, redacted)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return u.String()
+	}
+
+	copied := *u
+	copied.RawQuery = query.Encode()
+
+	return copied.String()
+}
+
+// loggedHeaders logs headers as a group, in order of name, with the values of redactedHeaders
+// replaced.
+type loggedHeaders http.Header
+
+func (lh loggedHeaders) LogValue() slog.Value {
+	names := make([]string, 0, len(lh))
+	for name := range lh {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	attrs := make([]slog.Attr, 0, len(names))
+
+	for _, name := range names {
+		value := strings.Join(lh[name], ",")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = redacted
+		}
+
+		attrs = append(attrs, slog.String(name, value))
+	}
+
+	return slog.GroupValue(attrs...)
+}
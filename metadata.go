@@ -0,0 +1,125 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// metaPrefix begins the names of the headers that carry user metadata.
+const metaPrefix = "x-amz-meta-"
+
+// WithMetadata attaches user metadata to the object being uploaded, as x-amz-meta-* headers that
+// are covered by the signature. S3 stores the names in lower case, and limits the metadata of an
+// object to 2KB in total (see SetStrict). Values should be ASCII; S3 returns anything else
+// RFC 2047-encoded.
+func WithMetadata(meta map[string]string) RequestOption {
+	return func(config *requestConfig) {
+		for name, value := range meta {
+			config.header.Set(metaPrefix+strings.ToLower(name), value)
+		}
+	}
+}
+
+// Metadata extracts the user metadata from the headers of a response to Head or Get, by name
+// (in lower case, without the x-amz-meta- prefix). It returns an empty map if there is none.
+func Metadata(header http.Header) map[string]string {
+	meta := map[string]string{}
+
+	for name, vals := range header {
+		if lname := strings.ToLower(name); strings.HasPrefix(lname, metaPrefix) && len(vals) > 0 {
+			meta[lname[len(metaPrefix):]] = strings.Join(vals, ",")
+		}
+	}
+
+	return meta
+}
+
+// WithCacheControl sets the Cache-Control header S3 stores with the object being uploaded, and
+// returns with it on every GET, so that browsers and CDNs cache it accordingly.
+func WithCacheControl(value string) RequestOption {
+	return WithHeader("Cache-Control", value)
+}
+
+// WithContentDisposition sets the Content-Disposition header stored with the object being
+// uploaded, such as `attachment; filename="report.pdf"` to have browsers download it.
+func WithContentDisposition(value string) RequestOption {
+	return WithHeader("Content-Disposition", value)
+}
+
+// WithContentEncoding sets the Content-Encoding header stored with the object being uploaded,
+// such as "gzip" for content that was compressed before being uploaded. S3 doesn't compress or
+// decompress anything itself. See WithAcceptEncoding for reading such objects back unchanged.
+func WithContentEncoding(value string) RequestOption {
+	return WithHeader("Content-Encoding", value)
+}
+
+// WithExpires sets the Expires header stored with the object being uploaded, the time after
+// which caches should consider it stale.
+func WithExpires(t time.Time) RequestOption {
+	return WithHeader("Expires", t.UTC().Format(http.TimeFormat))
+}
+
+// WithReplaceMetadata makes Copy (and Move) store the user metadata and response headers given
+// by the other options with the new object, instead of copying those of the source, which is the
+// only way to change them on an existing object. The source's Content-Type is kept unless one is
+// given with WithHeader.
+func WithReplaceMetadata() RequestOption {
+	return WithHeader("x-amz-metadata-directive", "REPLACE")
+}
+
+// retainedHeaders are the headers S3 stores with an object besides its user metadata and
+// Content-Type, which UpdateMetadata carries over. A copy made without the storage class, or the
+// encryption, of its source gets the defaults of the bucket instead.
+var retainedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Expires",
+	"x-amz-storage-class",
+	"x-amz-server-side-encryption",
+	"x-amz-server-side-encryption-aws-kms-key-id",
+	"x-amz-website-redirect-location",
+}
+
+// UpdateMetadata changes the user metadata and Content-Type of the object at path, by copying it
+// onto itself with WithReplaceMetadata, as S3 doesn't allow changing an object's metadata
+// otherwise; the content isn't downloaded or uploaded again. meta replaces the user metadata, or
+// is kept if meta is nil; an empty contentType keeps the Content-Type. Headers given in opts, such
+// as WithCacheControl, replace those of the object, whose other headers and storage class are
+// kept. If the object changes after it has been examined, the copy fails rather than overwrite
+// the change.
+//
+// In a versioned bucket, the updated object is a new version. Objects over 5GB, which are copied
+// in parts, lose any tags, and aren't protected from changes made while they are copied.
+func (s3 *S3) UpdateMetadata(ctx context.Context, path string, meta map[string]string, contentType string, opts ...RequestOption) error {
+	header, er := s3.Head(ctx, path)
+	if er != nil {
+		return er
+	}
+
+	copyOpts := []RequestOption{WithReplaceMetadata()}
+
+	for _, name := range retainedHeaders {
+		if value := header.Get(name); value != "" {
+			copyOpts = append(copyOpts, WithHeader(name, value))
+		}
+	}
+
+	if meta == nil {
+		meta = Metadata(header)
+	}
+	copyOpts = append(copyOpts, WithMetadata(meta))
+
+	if contentType != "" {
+		copyOpts = append(copyOpts, WithHeader("Content-Type", contentType))
+	}
+
+	if etag := header.Get("ETag"); etag != "" {
+		copyOpts = append(copyOpts, WithHeader("x-amz-copy-source-if-match", etag))
+	}
+
+	return s3.Copy(ctx, path, path, append(copyOpts, opts...)...)
+}
@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"hash"
+	"io"
+)
+
+// MeteredReader passes reads through to another reader while counting the bytes and, if a hash
+// was given, computing their digest. It is meant to be wrapped around the content passed to Put
+// (or returned by Get) to report progress and record a checksum in a single pass.
+type MeteredReader struct {
+	r    io.Reader
+	hash hash.Hash
+	n    int64
+	done bool
+
+	onProgress func(total int64)
+	onDone     func(total int64, digest []byte)
+}
+
+// NewMeteredReader wraps r. After every read that returns data, onProgress is called with the
+// number of bytes read so far. When r reports io.EOF, onDone is called once with the total and
+// the digest computed by h (nil if h is nil). Either callback may be nil. The callbacks run on
+// the goroutine calling Read, so they should return quickly.
+func NewMeteredReader(r io.Reader, h hash.Hash, onProgress func(total int64), onDone func(total int64, digest []byte)) *MeteredReader {
+	return &MeteredReader{
+		r:          r,
+		hash:       h,
+		onProgress: onProgress,
+		onDone:     onDone,
+	}
+}
+
+// Read reads from the underlying reader, updating the count and digest.
+func (mr *MeteredReader) Read(p []byte) (int, error) {
+	n, er := mr.r.Read(p)
+
+	if n > 0 {
+		mr.n += int64(n)
+
+		if mr.hash != nil {
+			mr.hash.Write(p[:n])
+		}
+
+		if mr.onProgress != nil {
+			mr.onProgress(mr.n)
+		}
+	}
+
+	if er == io.EOF && !mr.done {
+		mr.done = true
+
+		if mr.onDone != nil {
+			mr.onDone(mr.n, mr.Sum())
+		}
+	}
+
+	return n, er
+}
+
+// Close closes the underlying reader if it is an io.Closer.
+func (mr *MeteredReader) Close() error {
+	if closer, ok := mr.r.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// BytesRead returns the number of bytes read so far.
+func (mr *MeteredReader) BytesRead() int64 {
+	return mr.n
+}
+
+// Sum returns the digest of the bytes read so far, or nil if there is no hash.
+func (mr *MeteredReader) Sum() []byte {
+	if mr.hash == nil {
+		return nil
+	}
+
+	return mr.hash.Sum(nil)
+}
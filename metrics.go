@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestMetrics describes a request the S3 sent, for a MetricsCollector.
+type RequestMetrics struct {
+	Operation string // The S3 API operation, such as "GetObject" or "UploadPart".
+	Status    int    // The status S3 responded with, or zero if no response arrived.
+
+	// ErrorCode is S3's error code, such as "NoSuchKey", if the request failed; "NetworkError"
+	// if no response arrived; and empty if it succeeded.
+	ErrorCode string
+
+	Latency       time.Duration // Until the response headers arrived.
+	BytesUploaded int64         // The content uploaded with the request.
+}
+
+// MetricsCollector receives metrics of every request the S3 sends, including each retry, so that
+// usage can be monitored. Its methods may be called from several goroutines at once, and should
+// return quickly. See the s3prom package for a collector exporting them to Prometheus.
+type MetricsCollector interface {
+	// ObserveRequest is called once the response to a request has arrived, or it has failed.
+	ObserveRequest(metrics RequestMetrics)
+
+	// ObserveDownload is called as bytes of the body of a response to operation are read.
+	ObserveDownload(operation string, bytes int64)
+}
+
+// SetMetricsCollector registers collector to receive the metrics of every request. Passing nil
+// disables metrics.
+func (s3 *S3) SetMetricsCollector(collector MetricsCollector) {
+	s3.metrics = collector
+}
+
+// configSubResources are the sub-resources of buckets and objects that configure them, which name
+// the operations getting and setting them, in the order they are looked for.
+var configSubResources = []string{
+	"accelerate", "acl", "attributes", "cors", "encryption", "legal-hold", "lifecycle",
+	"location", "logging", "notification", "ownershipControls", "policy", "replication",
+	"requestPayment", "retention", "tagging", "versioning", "website",
+}
+
+// requestOperation returns the name of the S3 API operation req performs on key.
+func requestOperation(req *http.Request, key string) string {
+	query := req.URL.Query()
+	copied := req.Header.Get("x-amz-copy-source") != ""
+
+	switch {
+	case query.Has("partNumber") && copied:
+		return "UploadPartCopy"
+	case query.Has("partNumber"):
+		return "UploadPart"
+	case query.Has("uploadId") && req.Method == "POST":
+		return "CompleteMultipartUpload"
+	case query.Has("uploadId") && req.Method == "DELETE":
+		return "AbortMultipartUpload"
+	case query.Has("uploadId"):
+		return "ListParts"
+	case query.Has("uploads") && req.Method == "POST":
+		return "CreateMultipartUpload"
+	case query.Has("uploads"):
+		return "ListMultipartUploads"
+	case query.Has("versions"):
+		return "ListObjectVersions"
+	case query.Has("delete"):
+		return "DeleteObjects"
+	case query.Has("select"):
+		return "SelectObjectContent"
+	case query.Has("restore"):
+		return "RestoreObject"
+	}
+
+	verb := strings.ToUpper(req.Method[:1]) + strings.ToLower(req.Method[1:])
+
+	target := "Object"
+	if key == "" {
+		target = "Bucket"
+	}
+
+	for _, sub := range configSubResources {
+		if query.Has(sub) {
+			name := ""
+			for _, word := range strings.Split(sub, "-") {
+				name += strings.ToUpper(word[:1]) + word[1:]
+			}
+
+			return verb + target + name
+		}
+	}
+
+	switch {
+	case key == "" && req.Method == "GET":
+		return "ListObjects"
+	case key == "" && req.Method == "PUT":
+		return "CreateBucket"
+	case req.Method == "PUT" && copied:
+		return "CopyObject"
+	}
+
+	return verb + target
+}
+
+// observe reports req, which was sent at start, to the metrics collector, if there is one. er is
+// the error it failed with, if it did; resp is the response, if one arrived. The bytes of the
+// body of resp are reported as it is read.
+func (s3 *S3) observe(req *http.Request, resp *http.Response, er error, start time.Time) {
+	collector := s3.metrics
+	if collector == nil {
+		return
+	}
+
+	metrics := RequestMetrics{
+		Operation: requestOperation(req, s3.requestKey(req)),
+		Latency:   time.Since(start),
+	}
+
+	if req.Method == "PUT" || req.Method == "POST" {
+		metrics.BytesUploaded = payloadLength(req)
+	}
+
+	var s3er *S3Error
+
+	switch {
+	case errors.As(er, &s3er):
+		metrics.Status = s3er.Code
+		metrics.ErrorCode = s3er.awsCode()
+	case er != nil:
+		metrics.ErrorCode = "NetworkError"
+	default:
+		metrics.Status = resp.StatusCode
+		resp.Body = &countingBody{ReadCloser: resp.Body, count: func(n int64) {
+			collector.ObserveDownload(metrics.Operation, n)
+		}}
+	}
+
+	collector.ObserveRequest(metrics)
+}
@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the transport requests are sent with, to observe or modify every request the
+// S3 sends and every response it receives, such as to add headers, log, or inject failures for
+// testing. It is given the next transport in the chain and returns the one to use in its place.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc lets an ordinary function be used as an http.RoundTripper, as is convenient
+// in a Middleware.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// SetMiddleware sets the middleware every request is sent through, replacing any set before.
+// The first wraps the rest, so it sees each request first and each response last; the last wraps
+// the transport of the HTTP client (see SetHTTPClient).
+//
+// Middleware sees requests once they are signed, and sees every attempt at sending them: each
+// retry, and each request to the endpoint a request was redirected to. Any headers it adds are not
+// covered by the signature, which S3 rejects for x-amz-* headers; use SetDefaultRequestOptions to
+// add headers to every request before it is signed. A response returned without an error is
+// handled as if S3 had sent it, and an error as if the connection had failed, so middleware can
+// also stand in for S3 entirely.
+func (s3 *S3) SetMiddleware(mw ...Middleware) {
+	s3.middleware = mw
+}
+
+// sendClient returns the HTTP client requests are sent with: the S3's own, with its transport
+// wrapped in the middleware if there is any.
+func (s3 *S3) sendClient() *http.Client {
+	client := s3.httpClient()
+	if len(s3.middleware) == 0 {
+		return client
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	for i := len(s3.middleware) - 1; i >= 0; i-- {
+		transport = s3.middleware[i](transport)
+	}
+
+	wrapped := *client
+	wrapped.Transport = transport
+
+	return &wrapped
+}
@@ -2,94 +2,536 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"runtime"
 	"sync"
+	"time"
 )
 
 // S3Multipart tracks the state of a multipart upload, and provides an interface for streaming
 // data to S3 in chunks. All methods on S3Multipart are mutually locked to ensure state doesn't
-// become corrupt.
+// become corrupt. Requests for the upload are made with the context passed to StartMultipart,
+// except for Abort, which must work even after that context has been cancelled.
 type S3Multipart struct {
 	etags     []string
+	partSizes map[int]int64 // The sizes of the parts uploaded, where known.
+	uploaded  int64
 	uploadId  string
 	key       string
 	completed bool
+	etag      string            // The ETag of the completed object.
+	version   string            // The version ID of the completed object, if the bucket is versioned.
+	versionId *string           // Where to store the version ID of the completed object (see ReceiveVersionId).
+	metadata  *ResponseMetadata // Where to store the metadata of the last response (see ReceiveResponseMetadata).
 	s3        *S3
-	lock      sync.Mutex
+
+	checksumAlgorithm string         // The algorithm each part has a checksum of, if any.
+	checksums         map[int]string // The checksums of the parts, by part number.
+
+	progress *progress // Where the parts are counted, if the upload was started WithProgress.
+	nested   bool      // Whether the upload was started by another operation (see nestedAudit).
+
+	lock sync.Mutex
+
+	ctx      context.Context
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// audit records an audit event for a request of the upload.
+func (mp *S3Multipart) audit(operation string, bytes int64, start time.Time, er error) {
+	mp.s3.auditNested(operation, mp.key, bytes, start, er, mp.nested)
+}
+
+// multipartAbortTimeout bounds the abort request sent when the context governing a multipart
+// upload is cancelled, since that context can no longer be used for it.
+const multipartAbortTimeout = 30 * time.Second
+
+// ErrAborted is returned when a multipart upload is used after it has been aborted.
+var ErrAborted = errors.New("s3: multipart upload was aborted")
+
+// errAbortFailed is wrapped in the error of an operation that gave up on its multipart upload but
+// couldn't abort it, leaving the parts uploaded so far in storage.
+var errAbortFailed = errors.New("s3: aborting the multipart upload failed")
+
+// KeepOnCancel stops a multipart upload from being aborted when the context passed to
+// StartMultipart is cancelled, or when the S3Multipart is garbage collected, leaving the parts
+// uploaded so far on S3 so that the upload can be resumed with ResumeMultipart. The caller
+// becomes responsible for eventually completing or aborting it.
+func KeepOnCancel() RequestOption {
+	return func(config *requestConfig) {
+		config.keepOnCancel = true
+	}
 }
 
 type s3multipartResp struct {
-	XMLName  string `xml:InitiateMultipartUploadResult`
+	XMLName  string `xml:"InitiateMultipartUploadResult"`
 	Bucket   string
 	Key      string
 	UploadId string
 }
 
+type s3completeResp struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	ETag    string
+}
+
+type s3copyPartResp struct {
+	XMLName string `xml:"CopyPartResult"`
+	ETag    string
+}
+
+// Part describes a part of a multipart upload that has been uploaded.
+type Part struct {
+	Number       int
+	ETag         string
+	Size         int64
+	LastModified time.Time
+	Checksums    Checksums // Empty unless the upload was started with a checksum algorithm.
+}
+
+type s3listPartsResp struct {
+	XMLName              xml.Name `xml:"ListPartsResult"`
+	IsTruncated          bool
+	NextPartNumberMarker int
+	Part                 []struct {
+		s3checksums
+		PartNumber   int
+		ETag         string
+		Size         int64
+		LastModified time.Time
+	}
+}
+
+// newMultipart returns an S3Multipart for the upload uploadId of key, which requests are made
+// for with ctx, arranging for it to be aborted if ctx is cancelled unless opts include
+// KeepOnCancel.
+func newMultipart(ctx context.Context, s3 *S3, key, uploadId string, opts []RequestOption) *S3Multipart {
+	mp := &S3Multipart{
+		uploadId:  uploadId,
+		key:       key,
+		partSizes: map[int]int64{},
+		checksums: map[int]string{},
+		s3:        s3,
+		ctx:       ctx,
+		done:      make(chan struct{}),
+	}
+
+	config := newRequestConfig(opts)
+	mp.versionId = config.versionId
+	mp.metadata = config.metadata
+	mp.progress = newProgress(opts, -1)
+	mp.nested = config.nestedAudit
+
+	if config.keepOnCancel {
+		return mp
+	}
+
+	if ctx.Done() != nil {
+		go mp.abortOnCancel()
+	}
+
+	runtime.SetFinalizer(mp, func(mp *S3Multipart) {
+		mp.Abort()
+	})
+
+	return mp
+}
+
+// ResumeMultipart picks up the multipart upload uploadId of the object at path where it left
+// off, such as after the process that started it died. The parts already on S3 are listed, and
+// uploading continues with the part after them; Uploaded says how many bytes of the content they
+// hold, and so where to continue reading it from. If the listing has a gap (because parts were
+// being uploaded concurrently), only the parts before the gap are kept, and those after it are
+// overwritten as the upload continues.
+//
+// ctx and opts are treated as by StartMultipart; pass KeepOnCancel again for the upload to
+// remain resumable.
+func (s3 *S3) ResumeMultipart(ctx context.Context, path, uploadId string, opts ...RequestOption) (*S3Multipart, error) {
+	parts, er := s3.ListParts(ctx, path, uploadId)
+	if er != nil {
+		return nil, er
+	}
+
+	mp := newMultipart(ctx, s3, path, uploadId, opts)
+	mp.checksumAlgorithm = s3.checksumAlgorithm
+
+	for i, part := range parts {
+		if part.Number != i+1 {
+			break
+		}
+
+		mp.etags = append(mp.etags, part.ETag)
+		mp.partSizes[part.Number] = part.Size
+		mp.checksums[part.Number] = part.Checksums.get(mp.checksumAlgorithm)
+		mp.uploaded += part.Size
+	}
+
+	/* Progress counts on from the parts already uploaded */
+	if mp.progress != nil {
+		mp.progress.transferred = mp.uploaded
+	}
+
+	return mp, nil
+}
+
+// ListParts lists the parts that have been uploaded so far in the multipart upload uploadId of
+// the object at path, in order of part number.
+func (s3 *S3) ListParts(ctx context.Context, path, uploadId string) ([]Part, error) {
+	parts := []Part{}
+	marker := 0
+
+	for {
+		values := url.Values{}
+		values.Set("uploadId", uploadId)
+		if marker > 0 {
+			values.Set("part-number-marker", fmt.Sprintf("%d", marker))
+		}
+
+		req, er := http.NewRequestWithContext(ctx, "GET", s3.resource(path, values), nil)
+		if er != nil {
+			return nil, er
+		}
+
+		resp, er := s3.do(req)
+		if er != nil {
+			return nil, er
+		}
+
+		xmlBytes, er := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if er != nil {
+			return nil, er
+		}
+
+		var xmlResp s3listPartsResp
+		if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+			return nil, er
+		}
+
+		for _, part := range xmlResp.Part {
+			parts = append(parts, Part{
+				Number:       part.PartNumber,
+				ETag:         part.ETag,
+				Size:         part.Size,
+				LastModified: part.LastModified,
+				Checksums:    Checksums(part.s3checksums),
+			})
+		}
+
+		if !xmlResp.IsTruncated || xmlResp.NextPartNumberMarker <= marker {
+			return parts, nil
+		}
+
+		marker = xmlResp.NextPartNumberMarker
+	}
+}
+
+// UploadId returns the ID S3 assigned to the upload, which is needed to resume it with
+// ResumeMultipart.
+func (mp *S3Multipart) UploadId() string {
+	return mp.uploadId
+}
+
+// Uploaded returns how many bytes have been uploaded in parts so far.
+func (mp *S3Multipart) Uploaded() int64 {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	return mp.uploaded
+}
+
+// finish records that the upload has been completed or aborted, stopping abortOnCancel.
+func (mp *S3Multipart) finish() {
+	mp.doneOnce.Do(func() {
+		if mp.done != nil {
+			close(mp.done)
+		}
+	})
+}
+
+// abortOnCancel waits for the upload's context to be cancelled and aborts the upload when it
+// is, unless the upload finishes first. Failures are ignored: this is a best-effort cleanup, and
+// the caller will already have seen the cancellation in whatever request it interrupted.
+func (mp *S3Multipart) abortOnCancel() {
+	select {
+	case <-mp.done:
+	case <-mp.ctx.Done():
+		ctx, cancel := context.WithTimeout(context.Background(), multipartAbortTimeout)
+		defer cancel()
+
+		mp.abort(ctx)
+	}
+}
+
 // AddPart uploads the contents of r to S3. The number of bytes that r will read must be passed
 // as size (otherwise the request cannot be signed). Optionally, you can pass the md5sum of the
 // bytes which will be verified on S3's end; if md5sum is nil no end-to-end integrity checking
 // is performed. As per S3's API, size must always exceed 5MB (1024 * 1024 * 5) bytes, except
-// for the last part. This is not enforced locally.
-func (mp *S3Multipart) AddPart(r io.Reader, size int64, md5sum []byte) error {
+// for the last part. This is only enforced locally in strict mode (see SetStrict).
+func (mp *S3Multipart) AddPart(r io.Reader, size int64, md5sum []byte) (er error) {
 	mp.lock.Lock()
 	defer mp.lock.Unlock()
 
+	defer func(start time.Time) {
+		mp.audit("AddPart", size, start, er)
+	}(time.Now())
+
 	if mp.completed {
-		return fmt.Errorf("s3: cannot call AddPart on an aborted multipart request")
+		return fmt.Errorf("s3: cannot call AddPart: %w", ErrAborted)
+	}
+
+	if er := mp.checkPartSizes(len(mp.etags) + 1); er != nil {
+		return er
+	}
+
+	etag, checksum, er := mp.sendPart(len(mp.etags)+1, r, size, md5sum)
+	if er != nil {
+		return er
+	}
+
+	mp.etags = append(mp.etags, etag)
+	mp.partSizes[len(mp.etags)] = size
+	mp.checksums[len(mp.etags)] = checksum
+	mp.uploaded += size
+	return nil
+}
+
+// addPartAt is like AddPart, but uploads the given part number rather than the next one. Like
+// copyPart, it doesn't hold the lock while the request is in flight, so several parts may be
+// uploaded concurrently, and the caller must have sized mp.etags to hold partNumber beforehand.
+func (mp *S3Multipart) addPartAt(partNumber int, r io.Reader, size int64, md5sum []byte) (er error) {
+	defer func(start time.Time) {
+		mp.audit("AddPart", size, start, er)
+	}(time.Now())
+
+	mp.lock.Lock()
+	completed := mp.completed
+	mp.lock.Unlock()
+
+	if completed {
+		return fmt.Errorf("s3: cannot add a part: %w", ErrAborted)
+	}
+
+	etag, checksum, er := mp.sendPart(partNumber, r, size, md5sum)
+	if er != nil {
+		return er
+	}
+
+	mp.lock.Lock()
+	mp.etags[partNumber-1] = etag
+	mp.partSizes[partNumber] = size
+	mp.checksums[partNumber] = checksum
+	mp.uploaded += size
+	mp.lock.Unlock()
+
+	return nil
+}
+
+// sendPart uploads the contents of r as part partNumber, returning its ETag, and its checksum if
+// the upload was started with a checksum algorithm.
+func (mp *S3Multipart) sendPart(partNumber int, r io.Reader, size int64, md5sum []byte) (etag, checksum string, er error) {
+	ctx, endSpan := mp.s3.startSpan(mp.ctx, "AddPart", mp.key)
+	defer func() {
+		endSpan(er)
+	}()
+
+	/* Streamed parts carry their checksum in a trailer, computed as they are sent */
+	if mp.checksumAlgorithm != "" && !mp.s3.streamsUploads() {
+		if checksum, er = computeChecksum(mp.checksumAlgorithm, r, size); er != nil {
+			return "", "", er
+
+		} else if checksum == "" {
+			return "", "", fmt.Errorf("s3: a %s checksum can't be computed for part %d of %s, which can't be read twice", mp.checksumAlgorithm, partNumber, mp.key)
+		}
 	}
 
 	values := url.Values{}
 	values.Set("uploadId", mp.uploadId)
-	values.Set("partNumber", fmt.Sprintf("%d", len(mp.etags)+1))
+	values.Set("partNumber", fmt.Sprintf("%d", partNumber))
 
-	req, er := http.NewRequest("PUT", mp.s3.resource(mp.key, values), r)
+	req, er := http.NewRequestWithContext(ctx, "PUT", mp.s3.resource(mp.key, values), r)
 	if er != nil {
-		return er
+		return "", "", er
 	}
+	makeRewindable(req, r)
 
 	if md5sum != nil {
 		md5value := base64.StdEncoding.EncodeToString(md5sum)
 		req.Header.Set("Content-MD5", md5value)
 	}
 
+	if checksum != "" {
+		req.Header.Set(checksumHeaderName(mp.checksumAlgorithm), checksum)
+	}
+
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", size))
 	req.Header.Set("Host", req.URL.Host)
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req.ContentLength = size
+	trackBody(req, mp.progress)
 
-	mp.s3.signRequest(req)
+	cb := mp.s3.streamBody(req, size, mp.checksumAlgorithm)
+	mp.s3.expectContinueFor(req, size)
 
-	resp, er := http.DefaultClient.Do(req)
+	resp, er := mp.s3.do(req)
 	if er != nil {
+		return "", "", er
+	}
+	resp.Body.Close()
+
+	if cb != nil && mp.checksumAlgorithm != "" {
+		checksum = cb.value
+	}
+
+	return resp.Header.Get("ETag"), checksum, nil
+}
+
+// AddPartCopy adds the next part of the upload by having S3 copy it from the object at srcPath in
+// the same bucket, without the data passing through the client. If a byteRange is given, only that
+// range of the source is copied; otherwise the whole object is. This is how objects are assembled
+// from (or copied in pieces of) existing objects larger than the 5GB a single copy can handle;
+// as with AddPart, every part but the last must be at least 5MB.
+//
+// The bytes copied count towards Uploaded only when a byteRange is given.
+func (mp *S3Multipart) AddPartCopy(srcPath string, byteRange ...ByteRange) (er error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	var size int64
+	if len(byteRange) == 1 {
+		size = byteRange[0].Length
+	}
+
+	defer func(start time.Time) {
+		mp.audit("AddPartCopy", size, start, er)
+	}(time.Now())
+
+	if mp.completed {
+		return fmt.Errorf("s3: cannot call AddPartCopy: %w", ErrAborted)
+	}
+
+	if len(byteRange) > 1 {
+		return fmt.Errorf("s3: a part can only be copied from a single byte range")
+	}
+
+	var br *ByteRange
+	if len(byteRange) == 1 {
+		if byteRange[0].Offset < 0 || byteRange[0].Length <= 0 {
+			return fmt.Errorf("s3: invalid byte range %+v", byteRange[0])
+		}
+
+		br = &byteRange[0]
+	}
+
+	if er := mp.checkPartSizes(len(mp.etags) + 1); er != nil {
 		return er
 	}
-	defer resp.Body.Close()
 
-	body, er := ioutil.ReadAll(resp.Body)
+	etag, er := mp.sendPartCopy(len(mp.etags)+1, mp.s3.bucket, srcPath, br)
 	if er != nil {
 		return er
 	}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("s3: AddPart returned an error (HTTP %d)\n%s", resp.StatusCode, string(body))
+	mp.etags = append(mp.etags, etag)
+	if br != nil {
+		mp.partSizes[len(mp.etags)] = size
 	}
+	mp.uploaded += size
+	return nil
+}
+
+// copyPart fills part partNumber of the upload by having S3 copy the inclusive byte range
+// [start, end] of the object at srcPath in srcBucket. Unlike AddPartCopy, copyPart does not hold
+// the lock while the request is in flight, so several parts may be copied concurrently; the caller
+// must have sized mp.etags to hold partNumber beforehand.
+func (mp *S3Multipart) copyPart(partNumber int, srcBucket, srcPath string, start, end int64) error {
+	mp.lock.Lock()
+	completed := mp.completed
+	mp.lock.Unlock()
+
+	if completed {
+		return fmt.Errorf("s3: cannot copy a part: %w", ErrAborted)
+	}
+
+	etag, er := mp.sendPartCopy(partNumber, srcBucket, srcPath, &ByteRange{Offset: start, Length: end - start + 1})
+	if er != nil {
+		return er
+	}
+
+	mp.lock.Lock()
+	mp.etags[partNumber-1] = etag
+	mp.uploaded += end - start + 1
+	mp.lock.Unlock()
 
-	mp.etags = append(mp.etags, resp.Header.Get("ETag"))
 	return nil
 }
 
+// sendPartCopy has S3 copy part partNumber from byteRange of the object at srcPath in srcBucket
+// (or all of it, if byteRange is nil), returning the part's ETag.
+func (mp *S3Multipart) sendPartCopy(partNumber int, srcBucket, srcPath string, byteRange *ByteRange) (string, error) {
+	values := url.Values{}
+	values.Set("uploadId", mp.uploadId)
+	values.Set("partNumber", fmt.Sprintf("%d", partNumber))
+
+	req, er := http.NewRequestWithContext(mp.ctx, "PUT", mp.s3.resource(mp.key, values), nil)
+	if er != nil {
+		return "", er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("x-amz-copy-source", copySource(srcBucket, srcPath))
+
+	if byteRange != nil {
+		req.Header.Set("x-amz-copy-source-range", "bytes="+byteRange.spec())
+	}
+
+	resp, er := mp.s3.do(req, withEmbeddedErrors())
+	if er != nil {
+		return "", er
+	}
+	defer resp.Body.Close()
+
+	xmlBytes, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return "", er
+	}
+
+	var xmlResp s3copyPartResp
+	if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+		return "", er
+	}
+
+	return xmlResp.ETag, nil
+}
+
 // Complete finalizes the upload, and should be called after all parts have been added.
-func (mp *S3Multipart) Complete(contentType string) error {
+func (mp *S3Multipart) Complete(contentType string) (er error) {
 	mp.lock.Lock()
 	defer mp.lock.Unlock()
 
+	defer func(start time.Time) {
+		mp.audit("CompleteMultipart", 0, start, er)
+	}(time.Now())
+
+	ctx, endSpan := mp.s3.startSpan(mp.ctx, "Complete", mp.key)
+	defer func() {
+		endSpan(er)
+	}()
+
 	if mp.completed {
-		return fmt.Errorf("s3: cannot call Complete on an aborted multipart request")
+		return fmt.Errorf("s3: cannot call Complete: %w", ErrAborted)
+	}
+
+	if er := mp.checkPartSizes(len(mp.etags)); er != nil {
+		return er
 	}
 
 	if contentType == "" {
@@ -99,7 +541,12 @@ func (mp *S3Multipart) Complete(contentType string) error {
 	/* ghetto request body generation, bleh */
 	xmlBody := ""
 	for idx, etag := range mp.etags {
-		xmlBody += fmt.Sprintf("<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", idx+1, etag)
+		checksum := ""
+		if value := mp.checksums[idx+1]; value != "" {
+			checksum = fmt.Sprintf("<Checksum%s>%s</Checksum%s>", mp.checksumAlgorithm, value, mp.checksumAlgorithm)
+		}
+
+		xmlBody += fmt.Sprintf("<Part><PartNumber>%d</PartNumber><ETag>%s</ETag>%s</Part>", idx+1, etag, checksum)
 	}
 	xmlBody = "<CompleteMultipartUpload>" + xmlBody + "</CompleteMultipartUpload>"
 
@@ -108,7 +555,7 @@ func (mp *S3Multipart) Complete(contentType string) error {
 	values := url.Values{}
 	values.Set("uploadId", mp.uploadId)
 
-	req, er := http.NewRequest("POST", mp.s3.resource(mp.key, values), r)
+	req, er := http.NewRequestWithContext(ctx, "POST", mp.s3.resource(mp.key, values), r)
 	if er != nil {
 		return er
 	}
@@ -118,21 +565,43 @@ func (mp *S3Multipart) Complete(contentType string) error {
 	req.Header.Set("Content-Type", contentType)
 	req.ContentLength = int64(len(xmlBody))
 
-	mp.s3.signRequest(req)
-
-	resp, er := http.DefaultClient.Do(req)
+	resp, er := mp.s3.do(req, ReceiveVersionId(mp.versionId), ReceiveResponseMetadata(mp.metadata), withEmbeddedErrors())
 	if er != nil {
 		return er
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("s3: Complete returned an error (HTTP %d)", resp.StatusCode)
+	/* S3-compatible services don't all describe the object, which is only a loss for ETag */
+	var xmlResp s3completeResp
+	if er := xml.NewDecoder(resp.Body).Decode(&xmlResp); er == nil {
+		mp.etag = xmlResp.ETag
 	}
 
+	mp.version = resp.Header.Get("x-amz-version-id")
+
+	mp.finish()
 	return nil
 }
 
+// ETag returns the ETag of the object created by Complete, or an empty string before the upload is
+// complete. The ETag of an object uploaded in parts isn't the MD5 of its content, and for objects
+// encrypted with SSE-KMS or SSE-C, isn't derived from their content at all.
+func (mp *S3Multipart) ETag() string {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	return mp.etag
+}
+
+// VersionId returns the version ID of the object created by Complete, or an empty string before
+// the upload is complete or if the bucket doesn't have versioning enabled.
+func (mp *S3Multipart) VersionId() string {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	return mp.version
+}
+
 // Abort cancels the upload. If an upload is started but not completed, the storage space will
 // be counted against your AWS account (and getting rid of it is difficult), so you should make
 // sure either Abort or Complete is called.
@@ -140,31 +609,56 @@ func (mp *S3Multipart) Complete(contentType string) error {
 // For your convenience, Abort is set as the finalizer for S3Multipart objects as a failsafe, but
 // you shouldn't rely on that.
 func (mp *S3Multipart) Abort() error {
+	return mp.abort(context.Background())
+}
+
+// abandon aborts the upload because the operation making it failed with er, and returns er, along
+// with the reason the upload couldn't be aborted if it couldn't.
+func (mp *S3Multipart) abandon(er error) error {
+	abortEr := mp.Abort()
+	if abortEr == nil || errors.Is(abortEr, ErrAborted) {
+		return er
+	}
+
+	return fmt.Errorf("%w (%w: %w)", er, errAbortFailed, abortEr)
+}
+
+// abort implements Abort, sending the request with ctx. It deliberately doesn't use the upload's
+// own context, which may be the reason the upload is being aborted.
+func (mp *S3Multipart) abort(ctx context.Context) (er error) {
 	mp.lock.Lock()
 	defer mp.lock.Unlock()
 
+	defer func(start time.Time) {
+		mp.audit("AbortMultipart", 0, start, er)
+	}(time.Now())
+
+	ctx, endSpan := mp.s3.startSpan(ctx, "Abort", mp.key)
+	defer func() {
+		endSpan(er)
+	}()
+
 	if mp.completed {
-		return fmt.Errorf("s3: cannot call Abort on an aborted multipart request")
+		return fmt.Errorf("s3: cannot call Abort: %w", ErrAborted)
 	}
 
 	values := url.Values{}
 	values.Set("uploadId", mp.uploadId)
 
-	req, er := http.NewRequest("DELETE", mp.s3.resource(mp.key, values), nil)
+	req, er := http.NewRequestWithContext(ctx, "DELETE", mp.s3.resource(mp.key, values), nil)
 	if er != nil {
 		return er
 	}
 
-	resp, er := http.DefaultClient.Do(req)
+	req.Header.Set("Host", req.URL.Host)
+
+	resp, er := mp.s3.do(req)
 	if er != nil {
 		return er
 	}
 	resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("s3: Abort returned an error (HTTP %d)", resp.StatusCode)
-	}
-
 	mp.completed = true
+	mp.finish()
 	return nil
 }
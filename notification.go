@@ -0,0 +1,125 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Event types that notifications can be sent for, as in Notification.Events. S3 documents the
+// rest, such as "s3:ObjectCreated:Put" for the objects created by Put alone.
+const (
+	EventObjectCreated         = "s3:ObjectCreated:*"
+	EventObjectRemoved         = "s3:ObjectRemoved:*"
+	EventObjectRestoreComplete = "s3:ObjectRestore:Completed"
+	EventObjectTagging         = "s3:ObjectTagging:*"
+	EventLifecycleExpiration   = "s3:LifecycleExpiration:*"
+	EventReducedRedundancyLost = "s3:ReducedRedundancyLostObject"
+)
+
+// Notification sends a message to an SNS topic or SQS queue, or invokes a Lambda function, when
+// one of Events happens to an object whose key begins with Prefix and ends with Suffix. The kind
+// of destination is told apart by the service in its ARN, which must already allow S3 to use it.
+type Notification struct {
+	ID          string
+	Destination string // The ARN of the topic, queue or function.
+	Events      []string
+	Prefix      string
+	Suffix      string
+}
+
+type s3filterRule struct {
+	Name  string
+	Value string
+}
+
+type s3notification struct {
+	ID          string         `xml:"Id,omitempty"`
+	Topic       string         `xml:",omitempty"`
+	Queue       string         `xml:",omitempty"`
+	Function    string         `xml:"CloudFunction,omitempty"`
+	Events      []string       `xml:"Event"`
+	FilterRules []s3filterRule `xml:"Filter>S3Key>FilterRule,omitempty"`
+}
+
+type s3notifications struct {
+	XMLName   xml.Name         `xml:"NotificationConfiguration"`
+	Topics    []s3notification `xml:"TopicConfiguration"`
+	Queues    []s3notification `xml:"QueueConfiguration"`
+	Functions []s3notification `xml:"CloudFunctionConfiguration"`
+}
+
+// GetBucketNotifications returns the notifications configured for the bucket: those sent to
+// topics, then queues, then functions.
+func (s3 *S3) GetBucketNotifications(ctx context.Context) ([]Notification, error) {
+	var xmlResp s3notifications
+	if er := s3.getConfig(ctx, "notification", &xmlResp); er != nil {
+		return nil, er
+	}
+
+	notifications := []Notification{}
+	for _, configs := range [][]s3notification{xmlResp.Topics, xmlResp.Queues, xmlResp.Functions} {
+		for _, config := range configs {
+			notification := Notification{
+				ID:          config.ID,
+				Destination: config.Topic + config.Queue + config.Function,
+				Events:      config.Events,
+			}
+
+			for _, rule := range config.FilterRules {
+				switch strings.ToLower(rule.Name) {
+				case "prefix":
+					notification.Prefix = rule.Value
+				case "suffix":
+					notification.Suffix = rule.Value
+				}
+			}
+
+			notifications = append(notifications, notification)
+		}
+	}
+
+	return notifications, nil
+}
+
+// PutBucketNotifications replaces the notifications of the bucket with notifications; with none,
+// it stops every notification. S3 sends a test message to each destination, and fails if any of
+// them can't be used.
+func (s3 *S3) PutBucketNotifications(ctx context.Context, notifications []Notification) error {
+	body := s3notifications{}
+
+	for _, notification := range notifications {
+		config := s3notification{ID: notification.ID, Events: notification.Events}
+
+		if notification.Prefix != "" {
+			config.FilterRules = append(config.FilterRules, s3filterRule{Name: "prefix", Value: notification.Prefix})
+		}
+
+		if notification.Suffix != "" {
+			config.FilterRules = append(config.FilterRules, s3filterRule{Name: "suffix", Value: notification.Suffix})
+		}
+
+		/* ARNs are arn:partition:service:region:account:resource */
+		arn := strings.SplitN(notification.Destination, ":", 6)
+		if len(arn) != 6 || arn[0] != "arn" {
+			return fmt.Errorf("s3: invalid notification destination %q", notification.Destination)
+		}
+
+		switch arn[2] {
+		case "sns":
+			config.Topic = notification.Destination
+			body.Topics = append(body.Topics, config)
+		case "sqs":
+			config.Queue = notification.Destination
+			body.Queues = append(body.Queues, config)
+		case "lambda":
+			config.Function = notification.Destination
+			body.Functions = append(body.Functions, config)
+		default:
+			return fmt.Errorf("s3: notifications can't be sent to %s", arn[2])
+		}
+	}
+
+	return s3.putConfig(ctx, "notification", body, nil)
+}
@@ -0,0 +1,202 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Object Lock retention modes, as in Retention.Mode. Objects under governance retention can be
+// deleted or have their retention shortened by users with the s3:BypassGovernanceRetention
+// permission (see WithBypassGovernance); objects under compliance retention can't be, by anyone,
+// until it ends.
+const (
+	LockGovernance = "GOVERNANCE"
+	LockCompliance = "COMPLIANCE"
+)
+
+// Retention protects a version of an object from being overwritten or deleted until RetainUntil.
+type Retention struct {
+	Mode        string // LockGovernance or LockCompliance.
+	RetainUntil time.Time
+}
+
+type s3retention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	Mode            string
+	RetainUntilDate time.Time
+}
+
+type s3legalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Status  string
+}
+
+// WithRetention puts the object being created under retention until until, in mode. It applies to
+// Put, Copy and StartMultipart, in buckets with Object Lock enabled. S3 requires uploads with it
+// to be checked for integrity, so pass the MD5 of the content to Put or set a checksum hash with
+// SetChecksumHash.
+func WithRetention(mode string, until time.Time) RequestOption {
+	return func(config *requestConfig) {
+		config.header.Set("x-amz-object-lock-mode", mode)
+		config.header.Set("x-amz-object-lock-retain-until-date", until.UTC().Format(time.RFC3339))
+	}
+}
+
+// WithLegalHold puts the object being created under a legal hold, which protects it from being
+// overwritten or deleted until it is removed with PutObjectLegalHold. It applies to Put, Copy and
+// StartMultipart, in buckets with Object Lock enabled.
+func WithLegalHold() RequestOption {
+	return WithHeader("x-amz-object-lock-legal-hold", "ON")
+}
+
+// WithBypassGovernance lets a Delete, or a PutObjectRetention that shortens or removes retention,
+// override governance mode retention, for users with the s3:BypassGovernanceRetention permission.
+func WithBypassGovernance() RequestOption {
+	return WithHeader("x-amz-bypass-governance-retention", "true")
+}
+
+// objectLockRequest returns a request for the sub-resource named by subresource of the object at
+// path, with body marshalled as XML unless it is nil.
+func (s3 *S3) objectLockRequest(ctx context.Context, method, path, subresource string, body interface{}) (*http.Request, error) {
+	values := url.Values{}
+	values.Set(subresource, "")
+
+	if body == nil {
+		req, er := http.NewRequestWithContext(ctx, method, s3.resource(path, values), nil)
+		if er != nil {
+			return nil, er
+		}
+
+		req.Header.Set("Host", req.URL.Host)
+		return req, nil
+	}
+
+	xmlBody, er := xml.Marshal(body)
+	if er != nil {
+		return nil, er
+	}
+
+	md5sum := md5.Sum(xmlBody)
+
+	req, er := http.NewRequestWithContext(ctx, method, s3.resource(path, values), bytes.NewReader(xmlBody))
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md5sum[:]))
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(xmlBody)))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Host", req.URL.Host)
+	req.ContentLength = int64(len(xmlBody))
+
+	return req, nil
+}
+
+// getObjectLock fetches the sub-resource named by subresource of the object at path into v. It
+// reports false, without an error, if S3 says the object has no such setting.
+func (s3 *S3) getObjectLock(ctx context.Context, path, subresource string, v interface{}, opts []RequestOption) (bool, error) {
+	req, er := s3.objectLockRequest(ctx, "GET", path, subresource, nil)
+	if er != nil {
+		return false, er
+	}
+
+	resp, er := s3.do(req, opts...)
+
+	var s3er *S3Error
+	if errors.As(er, &s3er) && s3er.ErrorCode == "NoSuchObjectLockConfiguration" {
+		return false, nil
+
+	} else if er != nil {
+		return false, er
+	}
+	defer resp.Body.Close()
+
+	xmlBytes, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return false, er
+	}
+
+	if er := xml.Unmarshal(xmlBytes, v); er != nil {
+		return false, fmt.Errorf("s3: invalid %s for %s: %w", subresource, path, er)
+	}
+
+	return true, nil
+}
+
+// GetObjectRetention returns the retention of the object at path, or nil if it has none. Any opts
+// are applied to the request; WithVersionId selects a version other than the latest.
+func (s3 *S3) GetObjectRetention(ctx context.Context, path string, opts ...RequestOption) (*Retention, error) {
+	var xmlResp s3retention
+	if found, er := s3.getObjectLock(ctx, path, "retention", &xmlResp, opts); !found {
+		return nil, er
+	}
+
+	return &Retention{Mode: xmlResp.Mode, RetainUntil: xmlResp.RetainUntilDate}, nil
+}
+
+// PutObjectRetention puts the object at path under retention. Retention can always be extended,
+// but only shortened, or changed from governance to compliance mode, with WithBypassGovernance.
+func (s3 *S3) PutObjectRetention(ctx context.Context, path string, retention Retention, opts ...RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("PutObjectRetention", path, 0, start, er)
+	}(time.Now())
+
+	body := s3retention{Mode: retention.Mode, RetainUntilDate: retention.RetainUntil.UTC()}
+
+	req, er := s3.objectLockRequest(ctx, "PUT", path, "retention", body)
+	if er != nil {
+		return er
+	}
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// GetObjectLegalHold reports whether the object at path is under a legal hold. Any opts are applied
+// to the request; WithVersionId selects a version other than the latest.
+func (s3 *S3) GetObjectLegalHold(ctx context.Context, path string, opts ...RequestOption) (bool, error) {
+	var xmlResp s3legalHold
+	found, er := s3.getObjectLock(ctx, path, "legal-hold", &xmlResp, opts)
+
+	return found && xmlResp.Status == "ON", er
+}
+
+// PutObjectLegalHold places the object at path under a legal hold, or removes it. A legal hold
+// has no expiry, and is independent of any retention the object is under.
+func (s3 *S3) PutObjectLegalHold(ctx context.Context, path string, hold bool, opts ...RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("PutObjectLegalHold", path, 0, start, er)
+	}(time.Now())
+
+	body := s3legalHold{Status: "OFF"}
+	if hold {
+		body.Status = "ON"
+	}
+
+	req, er := s3.objectLockRequest(ctx, "PUT", path, "legal-hold", body)
+	if er != nil {
+		return er
+	}
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
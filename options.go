@@ -0,0 +1,115 @@
+package s3
+
+import (
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// requestConfig collects the effect of the RequestOptions passed to a single operation.
+type requestConfig struct {
+	header http.Header
+	query  url.Values
+
+	skipUnchanged bool
+	keepOnCancel  bool
+	maxSize       int64
+	versionId     *string
+	metadata      *ResponseMetadata
+	gzip          bool
+	progress      func(transferred, total int64)
+
+	embeddedErrors bool // Whether a 200 may carry an error document (see withEmbeddedErrors).
+	nestedAudit    bool // Whether audit events are part of another operation's (see nestedAudit).
+}
+
+// RequestOption customizes a single request made by an operation such as Put or Get. Options
+// that add headers or query parameters are applied after the operation has built its request,
+// and before the request is signed, so anything they add is covered by the signature. Other
+// options change the behavior of particular operations, and are ignored by the rest.
+type RequestOption func(*requestConfig)
+
+func newRequestConfig(opts []RequestOption) *requestConfig {
+	config := &requestConfig{
+		header: http.Header{},
+		query:  url.Values{},
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return config
+}
+
+// apply adds the headers and query parameters collected in config to req. Headers set by
+// options replace any the operation set itself.
+func (config *requestConfig) apply(req *http.Request) {
+	for k, vals := range config.header {
+		req.Header[k] = vals
+	}
+
+	if len(config.query) > 0 {
+		query := req.URL.Query()
+
+		for k, vals := range config.query {
+			query[k] = vals
+		}
+
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+// WithHeader sets an arbitrary HTTP header on the request.
+func WithHeader(name, value string) RequestOption {
+	return func(config *requestConfig) {
+		config.header.Set(name, value)
+	}
+}
+
+// WithAcceptEncoding sets the Accept-Encoding header of a Get. Unless it is set, Go's HTTP
+// transport asks for gzip itself and transparently decompresses objects stored with
+// "Content-Encoding: gzip", so that the bytes returned differ from the bytes stored. Setting the
+// header explicitly turns that off, and the body is returned exactly as the server sent it; pass
+// "identity" to get the raw stored bytes, as needed to check them against a checksum.
+func WithAcceptEncoding(encoding string) RequestOption {
+	return WithHeader("Accept-Encoding", encoding)
+}
+
+// WithRawAmzHeader sets an x-amz-* header on the request, adding the "x-amz-" prefix to name if
+// it is missing. This gives access to S3 features the package doesn't otherwise expose.
+func WithRawAmzHeader(name, value string) RequestOption {
+	if !strings.HasPrefix(strings.ToLower(name), "x-amz-") {
+		name = "x-amz-" + name
+	}
+
+	return WithHeader(name, value)
+}
+
+// WithProviderHeader sets a header in the namespace of an S3-compatible provider, for features
+// that only exist there. The header is named "X-<namespace>-<name>", so for example
+// WithProviderHeader("minio", "extract", "true") sends "X-Minio-Extract: true".
+//
+// Note that provider headers are sent unsigned: requests are signed with the Host, Content-Type
+// and Content-MD5 headers and the x-amz-* ones alone, whichever signature version is used, so
+// anything that can alter a request in transit can alter them.
+func WithProviderHeader(namespace, name, value string) RequestOption {
+	return WithHeader(textproto.CanonicalMIMEHeaderKey("x-"+namespace+"-"+name), value)
+}
+
+// WithQuery adds a query parameter to the request. Parameters that S3 does not recognize as
+// sub-resources are not covered by Signature Version 2.
+func WithQuery(name, value string) RequestOption {
+	return func(config *requestConfig) {
+		config.query.Add(name, value)
+	}
+}
+
+// SetDefaultRequestOptions registers options that are applied to every request the S3 makes,
+// before any options passed to the individual operation. This is the extension point for
+// provider-specific behavior that should apply everywhere, such as a vendor header required on
+// all requests.
+func (s3 *S3) SetDefaultRequestOptions(opts ...RequestOption) {
+	s3.defaultOpts = opts
+}
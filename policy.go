@@ -0,0 +1,33 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetBucketPolicy returns the bucket's policy, a JSON document, or nil if it has none.
+func (s3 *S3) GetBucketPolicy(ctx context.Context) ([]byte, error) {
+	policy, er := s3.getConfigBody(ctx, "policy")
+	if isNoConfig(er) {
+		return nil, nil
+	}
+
+	return policy, er
+}
+
+// PutBucketPolicy replaces the bucket's policy with policy, a JSON document. It is checked to be
+// valid JSON before it is sent, but S3 is the judge of whether it is a valid policy. Any opts are
+// applied to the request.
+func (s3 *S3) PutBucketPolicy(ctx context.Context, policy []byte, opts ...RequestOption) error {
+	if !json.Valid(policy) {
+		return fmt.Errorf("s3: the bucket policy isn't valid JSON")
+	}
+
+	return s3.putConfigBody(ctx, "policy", policy, "application/json", opts)
+}
+
+// DeleteBucketPolicy removes the bucket's policy.
+func (s3 *S3) DeleteBucketPolicy(ctx context.Context) error {
+	return s3.deleteConfig(ctx, "policy")
+}
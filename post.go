@@ -0,0 +1,159 @@
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PostPolicy describes the uploads permitted by a presigned POST form.
+type PostPolicy struct {
+	// Key is the exact key the upload must be stored at. Alternatively, set KeyPrefix to let the
+	// form choose any key beginning with it; the key field then defaults to the prefix followed
+	// by the name of the uploaded file.
+	Key       string
+	KeyPrefix string
+
+	// ContentType, if set, is the Content-Type the upload must be sent with. If
+	// ContentTypePrefix is set instead, any Content-Type beginning with it is allowed (for
+	// example "image/").
+	ContentType       string
+	ContentTypePrefix string
+
+	// MinSize and MaxSize bound the size of the upload in bytes, if MaxSize is non-zero.
+	MinSize int64
+	MaxSize int64
+
+	// Expires is how long the form remains usable.
+	Expires time.Duration
+}
+
+// PresignedPost holds what a browser needs to upload with an HTML form: the form should be
+// POSTed to URL, with multipart/form-data encoding, carrying each of Fields as a form field, and
+// the file itself as a final field named "file".
+type PresignedPost struct {
+	URL    string
+	Fields map[string]string
+}
+
+// PresignPost generates a signed policy document allowing uploads to the bucket through an HTML
+// form, subject to the conditions in policy. Unlike presigned PUT URLs, POST policies can limit
+// the size of the upload, which makes them the way to enforce upload limits on untrusted
+// clients.
+//
+// The policy is signed as presigned URLs are, with Signature Version 4 for access points and
+// directory buckets, or once SetSignatureV4 is called, and Version 2 otherwise. Multi-Region
+// Access Points don't accept forms.
+func (s3 *S3) PresignPost(policy PostPolicy) (*PresignedPost, error) {
+	if s3.anonymous {
+		return nil, fmt.Errorf("s3: cannot presign a POST without credentials")
+	}
+
+	if policy.Key == "" && policy.KeyPrefix == "" {
+		return nil, fmt.Errorf("s3: a POST policy requires either a Key or a KeyPrefix")
+	}
+
+	now := s3.now().UTC()
+	fields := map[string]string{}
+	conditions := []interface{}{
+		map[string]string{"bucket": s3.bucket},
+	}
+
+	if policy.Key != "" {
+		fields["key"] = policy.Key
+		conditions = append(conditions, map[string]string{"key": policy.Key})
+
+	} else {
+		fields["key"] = policy.KeyPrefix + "${filename}"
+		conditions = append(conditions, []string{"starts-with", "$key", policy.KeyPrefix})
+	}
+
+	if policy.ContentType != "" {
+		fields["Content-Type"] = policy.ContentType
+		conditions = append(conditions, map[string]string{"Content-Type": policy.ContentType})
+
+	} else if policy.ContentTypePrefix != "" {
+		conditions = append(conditions, []string{"starts-with", "$Content-Type", policy.ContentTypePrefix})
+	}
+
+	if policy.MaxSize > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", policy.MinSize, policy.MaxSize})
+	}
+
+	if ap := s3.accessPoint; ap != nil && ap.multiRegion {
+		return nil, fmt.Errorf("s3: cannot presign a POST for a Multi-Region Access Point")
+	}
+
+	var creds Credentials
+	var er error
+
+	if s3.express != nil {
+		creds, er = s3.expressCredentials(context.Background())
+	} else {
+		creds, er = s3.creds.get()
+	}
+
+	if er != nil {
+		return nil, er
+	}
+
+	/* Forms are signed as presigned URLs are: with Version 4 for directory buckets, access points
+	 * and when SetSignatureV4 says so, and Version 2 otherwise */
+	sigV4 := s3.express != nil || s3.sigV4 || s3.accessPoint != nil
+	region, service := s3.signingRegion(), "s3"
+	if s3.express != nil {
+		region, service = s3.currentRegion(), "s3express"
+	}
+
+	/* The credential fields have to be covered by the policy too, so they're added as
+	 * conditions before it is encoded */
+	if sigV4 {
+		amzDate := now.Format(v4TimeFormat)
+
+		fields["x-amz-algorithm"] = v4Algorithm
+		fields["x-amz-credential"] = creds.AccessId + "/" + v4Scope(amzDate[:8], region, service)
+		fields["x-amz-date"] = amzDate
+
+	} else {
+		fields["AWSAccessKeyId"] = creds.AccessId
+	}
+
+	if s3.express != nil {
+		fields["x-amz-s3session-token"] = creds.Token
+	} else if creds.Token != "" {
+		fields["x-amz-security-token"] = creds.Token
+	}
+
+	for _, name := range []string{"x-amz-algorithm", "x-amz-credential", "x-amz-date", "x-amz-s3session-token", "x-amz-security-token"} {
+		if value, ok := fields[name]; ok {
+			conditions = append(conditions, map[string]string{name: value})
+		}
+	}
+
+	policyJSON, er := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(policy.Expires).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if er != nil {
+		return nil, er
+	}
+
+	policy64 := base64.StdEncoding.EncodeToString(policyJSON)
+	fields["policy"] = policy64
+
+	if sigV4 {
+		key := v4SigningKey(creds.Secret, fields["x-amz-date"][:8], region, service)
+		fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(key, policy64))
+
+	} else {
+		fields["signature"] = v2Signature(creds.Secret, policy64)
+	}
+
+	return &PresignedPost{
+		URL:    s3.baseURL(),
+		Fields: fields,
+	}, nil
+}
@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Object is the result of downloading a single object with PrefetchGet.
+type Object struct {
+	Key    string
+	Data   []byte
+	Header http.Header
+	Err    error
+}
+
+// PrefetchGet downloads the objects named on keys, up to concurrency at a time, and delivers
+// them on the returned channel in the same order the keys were received. Downloads run ahead
+// of the consumer, but no more than concurrency objects are held in memory at once: a new
+// download is only started once the consumer has received an earlier result.
+//
+// Downloads that fail for a transient reason, such as S3 throttling them, are retried as the
+// retry policy allows (see SetRetryPolicy). Failures are reported through Object.Err rather than
+// stopping the pipeline. The returned channel is closed after keys is closed and every object has
+// been delivered.
+//
+// Cancelling ctx stops the pipeline: no more keys are read, results not yet delivered are
+// dropped, and the returned channel is closed, without keys having to be closed or the consumer
+// having to receive anything more.
+func (s3 *S3) PrefetchGet(ctx context.Context, keys <-chan string, concurrency int) <-chan Object {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	out := make(chan Object)
+	pending := make(chan chan Object, concurrency)
+	slots := make(chan struct{}, concurrency)
+
+	go func() {
+		defer close(pending)
+
+		for {
+			var key string
+
+			select {
+			case next, ok := <-keys:
+				if !ok {
+					return
+				}
+				key = next
+
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			/* Holding a slot leaves room in pending, which has as many */
+			result := make(chan Object, 1)
+			pending <- result
+
+			go func(key string) {
+				data, header, er := s3.getBytes(ctx, key)
+				result <- Object{Key: key, Data: data, Header: header, Err: er}
+			}(key)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+
+		for result := range pending {
+			var obj Object
+
+			select {
+			case obj = <-result:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case out <- obj:
+			case <-ctx.Done():
+				return
+			}
+
+			<-slots
+		}
+	}()
+
+	return out
+}
+
+// getBytes is like Get, but reads the entire object into memory.
+func (s3 *S3) getBytes(ctx context.Context, path string) ([]byte, http.Header, error) {
+	r, header, er := s3.Get(ctx, path)
+	if er != nil {
+		return nil, header, er
+	}
+	defer r.Close()
+
+	data, er := io.ReadAll(r)
+	if er != nil {
+		return nil, header, er
+	}
+
+	return data, header, nil
+}
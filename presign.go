@@ -0,0 +1,235 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SignedURL returns a presigned URL that lets anybody holding it GET the object at path, without
+// credentials of their own, until expires has elapsed.
+//
+// The expiry is measured from the local clock, corrected by SyncClock if it has been called. See
+// SignedURLAt to supply the reference time yourself.
+func (s3 *S3) SignedURL(path string, expires time.Duration) (string, error) {
+	return s3.SignedURLAt(path, expires, s3.now())
+}
+
+// SignedURLAt is like SignedURL, but the URL expires relative to now rather than the local
+// clock. This lets hosts whose clocks can't be trusted issue URLs that neither expire instantly
+// nor live longer than intended, given a reference time from somewhere reliable (such as the
+// Date header of a recent response from S3).
+//
+// URLs for directory buckets are signed with session credentials, and stop working when the
+// session expires regardless of expires.
+func (s3 *S3) SignedURLAt(path string, expires time.Duration, now time.Time) (string, error) {
+	return s3.presign("GET", path, nil, expires, now)
+}
+
+// SignedPutURL returns a presigned URL that lets anybody holding it upload an object to path with
+// a PUT request until expires has elapsed, such as a browser uploading directly to S3. If
+// contentType is non-empty it is part of the signature, so the upload must be sent with exactly
+// that Content-Type header.
+func (s3 *S3) SignedPutURL(path, contentType string, expires time.Duration) (string, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	return s3.presign("PUT", path, header, expires, s3.now())
+}
+
+// PresignBatch returns presigned URLs allowing method requests (such as "GET" or "PUT") to each
+// of keys until expires has elapsed, mapped by key. The credentials are fetched (and, for
+// directory buckets, the signing key derived) only once for the whole batch, which makes it the
+// efficient way to link to hundreds of objects from a single response.
+func (s3 *S3) PresignBatch(keys []string, method string, expires time.Duration) (map[string]string, error) {
+	sign, er := s3.presigner(expires, s3.now())
+	if er != nil {
+		return nil, er
+	}
+
+	urls := make(map[string]string, len(keys))
+
+	for _, key := range keys {
+		if urls[key], er = sign(method, key, nil, nil); er != nil {
+			return nil, er
+		}
+	}
+
+	return urls, nil
+}
+
+// SignedPartURLs returns presigned URLs that let anybody holding them upload parts 1 to parts of
+// the multipart upload uploadId of the object at path, with PUT requests, until expires has
+// elapsed; the URL for part n is at index n-1. This lets a browser upload a large object directly
+// to S3, in parts, while the server decides where it goes: the server starts the upload with
+// StartMultipart (passing KeepOnCancel, so that it outlives the request that started it), hands
+// out the URLs, and finishes the upload with ResumeMultipart and Complete, or lets the browser
+// finish it with a URL from SignedCompleteURL. S3 returns the ETag of each part in the ETag
+// header of the response, which must be exposed by the CORS configuration of the bucket (see
+// PutBucketCORS) for a browser to read it.
+func (s3 *S3) SignedPartURLs(path, uploadId string, parts int, expires time.Duration) ([]string, error) {
+	sign, er := s3.presigner(expires, s3.now())
+	if er != nil {
+		return nil, er
+	}
+
+	urls := make([]string, parts)
+
+	for i := range urls {
+		values := url.Values{}
+		values.Set("partNumber", strconv.Itoa(i+1))
+		values.Set("uploadId", uploadId)
+
+		if urls[i], er = sign("PUT", path, values, nil); er != nil {
+			return nil, er
+		}
+	}
+
+	return urls, nil
+}
+
+// SignedCompleteURL returns a presigned URL that lets anybody holding it complete the multipart
+// upload uploadId of the object at path until expires has elapsed (see SignedPartURLs). The
+// completion is a POST request with a Content-Type of application/xml, whose body lists every
+// part by number and ETag, in order:
+//
+//	<CompleteMultipartUpload>
+//	  <Part><PartNumber>1</PartNumber><ETag>"..."</ETag></Part>
+//	  ...
+//	</CompleteMultipartUpload>
+func (s3 *S3) SignedCompleteURL(path, uploadId string, expires time.Duration) (string, error) {
+	sign, er := s3.presigner(expires, s3.now())
+	if er != nil {
+		return "", er
+	}
+
+	values := url.Values{}
+	values.Set("uploadId", uploadId)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/xml")
+
+	return sign("POST", path, values, header)
+}
+
+// presign returns a URL for a method request to path that is valid until expires after now.
+// Any headers in header (such as Content-Type) are included in the signature, and so must be
+// sent by whoever uses the URL.
+func (s3 *S3) presign(method, path string, header http.Header, expires time.Duration, now time.Time) (string, error) {
+	sign, er := s3.presigner(expires, now)
+	if er != nil {
+		return "", er
+	}
+
+	return sign(method, path, nil, header)
+}
+
+// presignFunc presigns a method request to path, with the query parameters in values, as
+// described for presign.
+type presignFunc func(method, path string, values url.Values, header http.Header) (string, error)
+
+// presigner fetches the credentials to presign requests with, and returns a function that signs
+// requests with them so that they are valid until expires after now.
+func (s3 *S3) presigner(expires time.Duration, now time.Time) (presignFunc, error) {
+	newRequest := func(method, path string, values url.Values, header http.Header) (*http.Request, error) {
+		req, er := http.NewRequest(method, s3.resource(path, values), nil)
+		if er != nil {
+			return nil, er
+		}
+
+		for k, vals := range header {
+			req.Header[k] = vals
+		}
+
+		return req, nil
+	}
+
+	if s3.anonymous {
+		return func(method, path string, values url.Values, header http.Header) (string, error) {
+			req, er := newRequest(method, path, values, header)
+			if er != nil {
+				return "", er
+			}
+
+			return req.URL.String(), nil
+		}, nil
+	}
+
+	if s3.express != nil {
+		creds, er := s3.expressCredentials(context.Background())
+		if er != nil {
+			return nil, er
+		}
+
+		return func(method, path string, values url.Values, header http.Header) (string, error) {
+			req, er := newRequest(method, path, values, header)
+			if er != nil {
+				return "", er
+			}
+
+			presignV4(req, creds.AccessId, creds.Secret, creds.Token, "X-Amz-S3session-Token", s3.currentRegion(), "s3express", now, expires)
+			return req.URL.String(), nil
+		}, nil
+	}
+
+	creds, er := s3.creds.get()
+	if er != nil {
+		return nil, er
+	}
+
+	return func(method, path string, values url.Values, header http.Header) (string, error) {
+		req, er := newRequest(method, path, values, header)
+		if er != nil {
+			return "", er
+		}
+
+		if ap := s3.accessPoint; ap != nil && ap.multiRegion {
+			if er := presignV4A(req, creds.AccessId, creds.Secret, creds.Token, "s3", now, expires); er != nil {
+				return "", er
+			}
+
+			return req.URL.String(), nil
+		}
+
+		if s3.sigV4 || s3.accessPoint != nil {
+			presignV4(req, creds.AccessId, creds.Secret, creds.Token, "X-Amz-Security-Token", s3.signingRegion(), "s3", now, expires)
+			return req.URL.String(), nil
+		}
+
+		s3.presignV2(req, creds, now.Add(expires))
+		return req.URL.String(), nil
+	}, nil
+}
+
+// presignV2 signs req with Signature Version 2 query string authentication, so that it is valid
+// until expiresAt.
+func (s3 *S3) presignV2(req *http.Request, creds Credentials, expiresAt time.Time) {
+	expiresStr := fmt.Sprintf("%d", expiresAt.Unix())
+
+	amzHeader := http.Header{}
+	for k, vals := range req.Header {
+		amzHeader[k] = vals
+	}
+
+	if creds.Token != "" {
+		amzHeader.Set("x-amz-security-token", creds.Token)
+	}
+
+	authStr := s3.v2StringToSign(req, amzHeader, expiresStr)
+
+	query := req.URL.Query()
+	query.Set("AWSAccessKeyId", creds.AccessId)
+	query.Set("Expires", expiresStr)
+	query.Set("Signature", v2Signature(creds.Secret, authStr))
+
+	if creds.Token != "" {
+		query.Set("x-amz-security-token", creds.Token)
+	}
+
+	req.URL.RawQuery = query.Encode()
+}
@@ -0,0 +1,122 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// WithProgress has fn called as the content of an upload or download is transferred, with the
+// number of bytes transferred so far and the total, or -1 if the total isn't known in advance
+// (as for PutStream). It applies to Put, PutStream, the parts of multipart uploads started with
+// StartMultipart, Get, GetRange and Download, counting the bytes of all of the parts or ranges
+// of the object together.
+//
+// Uploads count bytes as they are handed to the connection, so when a request is retried, the
+// count goes back by the bytes of the attempt that failed. fn may be called from several
+// goroutines, but never concurrently, so it needn't lock anything itself; it should return
+// quickly. A transfer that has stalled stops calling it.
+func WithProgress(fn func(transferred, total int64)) RequestOption {
+	return func(config *requestConfig) {
+		config.progress = fn
+	}
+}
+
+// withoutProgress stops the requests of an operation from reporting progress themselves, for
+// operations that report the progress of all of them together.
+func withoutProgress() RequestOption {
+	return func(config *requestConfig) {
+		config.progress = nil
+	}
+}
+
+// progress counts the bytes transferred by one operation, which may be spread across several
+// requests, and reports them to the callback passed to WithProgress.
+type progress struct {
+	lock        sync.Mutex
+	fn          func(transferred, total int64)
+	transferred int64
+	total       int64
+}
+
+// newProgress returns a progress reporting to the callback set by opts, or nil if there isn't one.
+func newProgress(opts []RequestOption, total int64) *progress {
+	fn := newRequestConfig(opts).progress
+	if fn == nil {
+		return nil
+	}
+
+	return &progress{fn: fn, total: total}
+}
+
+// add counts n more bytes as transferred, or n fewer if it is negative. It may be called on a
+// nil progress.
+func (p *progress) add(n int64) {
+	if p == nil || n == 0 {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.transferred += n
+	p.fn(p.transferred, p.total)
+}
+
+// setTotal sets the total number of bytes to be transferred. It may be called on a nil progress.
+func (p *progress) setTotal(total int64) {
+	if p != nil {
+		p.lock.Lock()
+		p.total = total
+		p.lock.Unlock()
+	}
+}
+
+// progressReader counts the bytes read through it towards a progress.
+type progressReader struct {
+	io.ReadCloser
+	p *progress
+	n int64
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, er := pr.ReadCloser.Read(b)
+	pr.n += int64(n)
+	pr.p.add(int64(n))
+
+	return n, er
+}
+
+// trackBody counts the body of req towards p as it is sent. When the body is rewound to send the
+// request again, the bytes already sent are taken off the count. It does nothing if p is nil.
+func trackBody(req *http.Request, p *progress) {
+	if p == nil || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	pr := &progressReader{ReadCloser: req.Body, p: p}
+	req.Body = pr
+
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, er := getBody()
+			if er != nil {
+				return nil, er
+			}
+
+			p.add(-pr.n)
+			pr = &progressReader{ReadCloser: body, p: p}
+
+			return pr, nil
+		}
+	}
+}
+
+// trackResponse returns body, counted towards p as it is read, or body itself if p is nil.
+func trackResponse(body io.ReadCloser, p *progress) io.ReadCloser {
+	if p == nil {
+		return body
+	}
+
+	return &progressReader{ReadCloser: body, p: p}
+}
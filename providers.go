@@ -0,0 +1,531 @@
+package s3
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultIMDSEndpoint is the address of the EC2 instance metadata service.
+	defaultIMDSEndpoint = "http://169.254.169.254"
+
+	// defaultContainerEndpoint is the address of the ECS container credentials service, which
+	// AWS_CONTAINER_CREDENTIALS_RELATIVE_URI is relative to.
+	defaultContainerEndpoint = "http://169.254.170.2"
+
+	// imdsTimeout bounds each request to the instance metadata service when no client is
+	// given, so that the chain fails quickly when not running on EC2.
+	imdsTimeout = 2 * time.Second
+
+	// providerTimeout bounds a refresh of the credentials by a provider.
+	providerTimeout = 30 * time.Second
+)
+
+// ErrNoCredentials is returned (wrapped) by providers that found nothing to supply.
+var ErrNoCredentials = errors.New("s3: no credentials found")
+
+// CredentialsProvider supplies the credentials used to sign requests. Providers are consulted
+// when an S3 has no credentials yet, and again whenever the credentials they supplied are about
+// to expire; see SetCredentialsProvider.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// SetCredentialsProvider makes provider the source of the credentials used to sign requests,
+// replacing any set so far. The provider is first called when the next request is signed, and
+// again before any temporary credentials it returned expire (see SetCredentialsRefresh, which
+// this is built on).
+func (s3 *S3) SetCredentialsProvider(provider CredentialsProvider) {
+	s3.creds.lock.Lock()
+	s3.creds.current = Credentials{}
+	s3.creds.refresh = func() (Credentials, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+		defer cancel()
+
+		return provider.Retrieve(ctx)
+	}
+	s3.creds.lock.Unlock()
+
+	s3.anonymous = false
+	s3.expireExpressSession()
+}
+
+// NewS3FromProvider allocates a new S3 that gets its credentials from provider. Use
+// DefaultCredentialsChain to find credentials the way the AWS tools do.
+func NewS3FromProvider(bucket string, provider CredentialsProvider) *S3 {
+	s3 := NewS3(bucket, "", "")
+	s3.SetCredentialsProvider(provider)
+
+	return s3
+}
+
+// ChainProvider tries each provider in turn, returning the credentials from the first that
+// succeeds.
+type ChainProvider []CredentialsProvider
+
+// Retrieve returns the credentials from the first provider in the chain that has any, or an
+// error combining the failures of all of them.
+func (chain ChainProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	errs := []error{}
+
+	for _, provider := range chain {
+		creds, er := provider.Retrieve(ctx)
+		if er == nil {
+			return creds, nil
+		}
+
+		errs = append(errs, er)
+	}
+
+	return Credentials{}, fmt.Errorf("%w: %w", ErrNoCredentials, errors.Join(errs...))
+}
+
+// DefaultCredentialsChain returns a provider that looks for credentials in the standard order
+// used by the AWS tools: the environment, the shared credentials file, a web identity token
+// (IAM roles for EKS service accounts), the ECS or EKS Pod Identity container credentials
+// service, and finally the EC2 instance metadata service. Role credentials from the last three
+// are temporary, and are refreshed automatically before they expire.
+func DefaultCredentialsChain() CredentialsProvider {
+	return ChainProvider{
+		EnvProvider{},
+		SharedCredentialsProvider{},
+		&WebIdentityProvider{},
+		&ContainerProvider{},
+		&IMDSProvider{},
+	}
+}
+
+// EnvProvider reads credentials from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and (for
+// temporary credentials) AWS_SESSION_TOKEN environment variables.
+type EnvProvider struct{}
+
+// Retrieve returns the credentials set in the environment.
+func (EnvProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	creds := Credentials{
+		AccessId: os.Getenv("AWS_ACCESS_KEY_ID"),
+		Secret:   os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		Token:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+
+	if creds.AccessId == "" || creds.Secret == "" {
+		return Credentials{}, fmt.Errorf("%w in the environment", ErrNoCredentials)
+	}
+
+	return creds, nil
+}
+
+// SharedCredentialsProvider reads credentials from a profile in the shared credentials file
+// written by the AWS CLI.
+type SharedCredentialsProvider struct {
+	// Filename is the path of the file. If empty, AWS_SHARED_CREDENTIALS_FILE is used, or
+	// failing that ~/.aws/credentials.
+	Filename string
+
+	// Profile is the section of the file to use. If empty, AWS_PROFILE is used, or failing
+	// that "default".
+	Profile string
+}
+
+// Retrieve returns the credentials in the configured profile.
+func (provider SharedCredentialsProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	filename := provider.Filename
+	if filename == "" {
+		filename = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+
+	if filename == "" {
+		home, er := os.UserHomeDir()
+		if er != nil {
+			return Credentials{}, er
+		}
+
+		filename = filepath.Join(home, ".aws", "credentials")
+	}
+
+	profile := provider.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, er := os.Open(filename)
+	if er != nil {
+		return Credentials{}, fmt.Errorf("%w: %w", ErrNoCredentials, er)
+	}
+	defer f.Close()
+
+	values, er := readProfile(f, profile)
+	if er != nil {
+		return Credentials{}, er
+	}
+
+	creds := Credentials{
+		AccessId: values["aws_access_key_id"],
+		Secret:   values["aws_secret_access_key"],
+		Token:    values["aws_session_token"],
+	}
+
+	if creds.AccessId == "" || creds.Secret == "" {
+		return Credentials{}, fmt.Errorf("%w in profile %#v of %s", ErrNoCredentials, profile, filename)
+	}
+
+	return creds, nil
+}
+
+// readProfile returns the keys and values in the named section of an INI-style file.
+func readProfile(r io.Reader, profile string) (map[string]string, error) {
+	values := map[string]string{}
+	inProfile := false
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			continue
+		}
+
+		if !inProfile {
+			continue
+		}
+
+		if idx := strings.Index(line, "="); idx >= 0 {
+			values[strings.TrimSpace(line[:idx])] = strings.TrimSpace(line[idx+1:])
+		}
+	}
+
+	return values, scanner.Err()
+}
+
+// IMDSProvider fetches the credentials of the IAM role attached to the EC2 instance from the
+// instance metadata service, using IMDSv2 session tokens. The credentials are temporary, and
+// are fetched again before they expire.
+type IMDSProvider struct {
+	// Client makes the requests to the metadata service. If nil, a client with a short
+	// timeout is used.
+	Client *http.Client
+
+	// Endpoint is the base URL of the metadata service; if empty, the standard link-local
+	// address is used.
+	Endpoint string
+}
+
+type imdsCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+// Retrieve returns the instance's role credentials.
+func (provider *IMDSProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	endpoint := provider.Endpoint
+	if endpoint == "" {
+		endpoint = defaultIMDSEndpoint
+	}
+
+	token, er := provider.get(ctx, "PUT", endpoint+"/latest/api/token", nil)
+	if er != nil {
+		return Credentials{}, fmt.Errorf("%w from instance metadata: %w", ErrNoCredentials, er)
+	}
+
+	roles, er := provider.get(ctx, "GET", endpoint+"/latest/meta-data/iam/security-credentials/", token)
+	if er != nil {
+		return Credentials{}, fmt.Errorf("%w from instance metadata: %w", ErrNoCredentials, er)
+	}
+
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return Credentials{}, fmt.Errorf("%w from instance metadata: no role attached", ErrNoCredentials)
+	}
+
+	body, er := provider.get(ctx, "GET", endpoint+"/latest/meta-data/iam/security-credentials/"+role, token)
+	if er != nil {
+		return Credentials{}, er
+	}
+
+	var resp imdsCredentials
+	if er := json.Unmarshal(body, &resp); er != nil {
+		return Credentials{}, er
+	}
+
+	return Credentials{
+		AccessId:   resp.AccessKeyId,
+		Secret:     resp.SecretAccessKey,
+		Token:      resp.Token,
+		Expiration: resp.Expiration,
+	}, nil
+}
+
+// get makes a request to the metadata service, returning the body of the response. If token
+// is nil a new session token is being requested.
+func (provider *IMDSProvider) get(ctx context.Context, method, url string, token []byte) ([]byte, error) {
+	req, er := http.NewRequestWithContext(ctx, method, url, nil)
+	if er != nil {
+		return nil, er
+	}
+
+	if len(token) == 0 {
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	} else {
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	}
+
+	client := provider.Client
+	if client == nil {
+		client = &http.Client{Timeout: imdsTimeout}
+	}
+
+	resp, er := client.Do(req)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	body, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return nil, er
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s3: instance metadata service returned %d for %s", resp.StatusCode, url)
+	}
+
+	return body, nil
+}
+
+// ContainerProvider fetches role credentials from the container credentials service, as used
+// by ECS tasks and EKS Pod Identity. The service is located through the
+// AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or AWS_CONTAINER_CREDENTIALS_FULL_URI environment
+// variables, and authenticated with AWS_CONTAINER_AUTHORIZATION_TOKEN or the contents of
+// AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE when they are set.
+//
+// As in the AWS SDKs, a full URI is only accepted if it uses HTTPS, or if its host is the
+// loopback interface or one of the addresses of the ECS and EKS credentials services, so that
+// a misconfigured environment can't send the authorization token elsewhere in the clear.
+type ContainerProvider struct {
+	// Client makes the requests to the service. If nil, a client with a short timeout is used.
+	Client *http.Client
+}
+
+// Retrieve returns the container's role credentials.
+func (provider *ContainerProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	url := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		url = defaultContainerEndpoint + rel
+	}
+
+	if url == "" {
+		return Credentials{}, fmt.Errorf("%w: not running in a container with a credentials service", ErrNoCredentials)
+	}
+
+	req, er := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if er != nil {
+		return Credentials{}, er
+	}
+
+	if er := checkContainerEndpoint(ctx, req.URL); er != nil {
+		return Credentials{}, er
+	}
+
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		tokenBytes, er := os.ReadFile(tokenFile)
+		if er != nil {
+			return Credentials{}, er
+		}
+
+		token = strings.TrimSpace(string(tokenBytes))
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	client := provider.Client
+	if client == nil {
+		client = &http.Client{Timeout: imdsTimeout}
+	}
+
+	resp, er := client.Do(req)
+	if er != nil {
+		return Credentials{}, er
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("s3: container credentials service returned %d", resp.StatusCode)
+	}
+
+	var creds imdsCredentials
+	if er := json.NewDecoder(resp.Body).Decode(&creds); er != nil {
+		return Credentials{}, er
+	}
+
+	return Credentials{
+		AccessId:   creds.AccessKeyId,
+		Secret:     creds.SecretAccessKey,
+		Token:      creds.Token,
+		Expiration: creds.Expiration,
+	}, nil
+}
+
+// containerHosts are the addresses of the ECS and EKS container credentials services, which
+// may be reached over plain HTTP.
+var containerHosts = []string{"169.254.170.2", "169.254.170.23", "fd00:ec2::23"}
+
+// checkContainerEndpoint returns an error unless u is somewhere ContainerProvider may fetch
+// credentials from: any host over HTTPS, and otherwise only a container credentials service
+// or a host that resolves to nothing but loopback addresses.
+func checkContainerEndpoint(ctx context.Context, u *url.URL) error {
+	if u.Scheme == "https" {
+		return nil
+	}
+
+	if u.Scheme == "http" {
+		host := u.Hostname()
+
+		for _, allowed := range containerHosts {
+			if ip := net.ParseIP(host); ip != nil && ip.Equal(net.ParseIP(allowed)) {
+				return nil
+			}
+		}
+
+		addrs, er := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if er == nil && len(addrs) > 0 {
+			loopback := true
+			for _, addr := range addrs {
+				loopback = loopback && addr.IP.IsLoopback()
+			}
+
+			if loopback {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("s3: refusing to fetch container credentials from %s; it must use HTTPS or a loopback or container host", u.Redacted())
+}
+
+// WebIdentityProvider exchanges a web identity token for role credentials with STS
+// AssumeRoleWithWebIdentity, as used by IAM roles for EKS service accounts. The token is read
+// from the file named by AWS_WEB_IDENTITY_TOKEN_FILE on every refresh (it is rotated by the
+// cluster), and the role from AWS_ROLE_ARN.
+type WebIdentityProvider struct {
+	// Client makes the requests to STS; if nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Endpoint is the base URL of STS. If empty, the regional endpoint for AWS_REGION is used,
+	// or the global endpoint if that isn't set.
+	Endpoint string
+}
+
+type stsWebIdentityResp struct {
+	XMLName xml.Name `xml:"AssumeRoleWithWebIdentityResponse"`
+	Result  struct {
+		Credentials struct {
+			AccessKeyId     string
+			SecretAccessKey string
+			SessionToken    string
+			Expiration      time.Time
+		}
+	} `xml:"AssumeRoleWithWebIdentityResult"`
+}
+
+// Retrieve assumes the configured role.
+func (provider *WebIdentityProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	roleArn := os.Getenv("AWS_ROLE_ARN")
+
+	if tokenFile == "" || roleArn == "" {
+		return Credentials{}, fmt.Errorf("%w: no web identity token configured", ErrNoCredentials)
+	}
+
+	token, er := os.ReadFile(tokenFile)
+	if er != nil {
+		return Credentials{}, er
+	}
+
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("s3-%d", time.Now().UnixNano())
+	}
+
+	endpoint := provider.Endpoint
+	if endpoint == "" {
+		endpoint = "https://sts.amazonaws.com"
+
+		if region := os.Getenv("AWS_REGION"); region != "" {
+			endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", region)
+		}
+	}
+
+	values := url.Values{}
+	values.Set("Action", "AssumeRoleWithWebIdentity")
+	values.Set("Version", "2011-06-15")
+	values.Set("RoleArn", roleArn)
+	values.Set("RoleSessionName", sessionName)
+	values.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+	/* The token is the credential; the request itself is not signed */
+	req, er := http.NewRequestWithContext(ctx, "POST", endpoint+"/", strings.NewReader(values.Encode()))
+	if er != nil {
+		return Credentials{}, er
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := provider.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, er := client.Do(req)
+	if er != nil {
+		return Credentials{}, er
+	}
+	defer resp.Body.Close()
+
+	body, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return Credentials{}, er
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("s3: AssumeRoleWithWebIdentity failed: %d %s", resp.StatusCode, body)
+	}
+
+	var xmlResp stsWebIdentityResp
+	if er := xml.Unmarshal(body, &xmlResp); er != nil {
+		return Credentials{}, er
+	}
+
+	creds := xmlResp.Result.Credentials
+
+	return Credentials{
+		AccessId:   creds.AccessKeyId,
+		Secret:     creds.SecretAccessKey,
+		Token:      creds.SessionToken,
+		Expiration: creds.Expiration,
+	}, nil
+}
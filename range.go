@@ -0,0 +1,313 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// ByteRange identifies Length bytes of an object starting at Offset.
+type ByteRange struct {
+	Offset int64
+	Length int64
+}
+
+func (br ByteRange) spec() string {
+	return fmt.Sprintf("%d-%d", br.Offset, br.Offset+br.Length-1)
+}
+
+// ContentRange describes the part of an object returned by GetRange: the bytes from Start to End
+// inclusive, out of Total. Total is -1 if the server didn't say how large the object is, as is End
+// if the rest of such an object was asked for. ETag identifies the version of the object the
+// bytes came from, for passing to WithIfRange when resuming.
+type ContentRange struct {
+	Start int64
+	End   int64
+	Total int64
+	ETag  string
+}
+
+// WithIfRange makes a ranged GET conditional on the object still having the given ETag: if it
+// has changed, the whole of the new version is returned instead of the range. GetRange then
+// reports a ContentRange starting at 0, which tells a resuming download to start over rather than
+// append bytes from a different version of the object to those it already has.
+func WithIfRange(etag string) RequestOption {
+	return WithHeader("If-Range", etag)
+}
+
+// rangeChunk is a contiguous piece of an object returned by a ranged GET.
+type rangeChunk struct {
+	start int64
+	data  []byte
+}
+
+// parseContentRange parses a Content-Range header of the form "bytes start-end/total". If the
+// total size is not known ("*"), total is returned as -1.
+func parseContentRange(value string) (start, end, total int64, er error) {
+	var totalStr string
+
+	if _, er := fmt.Sscanf(value, "bytes %d-%d/%s", &start, &end, &totalStr); er != nil {
+		return 0, 0, 0, fmt.Errorf("s3: malformed Content-Range %#v", value)
+	}
+
+	total = -1
+	if totalStr != "*" {
+		if _, er := fmt.Sscanf(totalStr, "%d", &total); er != nil {
+			return 0, 0, 0, fmt.Errorf("s3: malformed Content-Range %#v", value)
+		}
+	}
+
+	return start, end, total, nil
+}
+
+// GetRange fetches length bytes of the object at path, starting at offset, or everything from
+// offset onwards if length is zero. Along with the body, it returns the range actually returned,
+// which is shorter than requested if the object ends first; its Total field gives the size of
+// the whole object, as needed to resume an interrupted download or seek within media.
+//
+// If the server ignores the Range header and sends the whole object, the unwanted bytes are
+// skipped, so the body starts at offset. The exception is a GET made conditional with WithIfRange
+// on an object that has since changed, where the whole object is returned.
+func (s3 *S3) GetRange(ctx context.Context, path string, offset, length int64, opts ...RequestOption) (io.ReadCloser, *ContentRange, error) {
+	if offset < 0 || length < 0 {
+		return nil, nil, fmt.Errorf("s3: invalid byte range of %d bytes at %d", length, offset)
+	}
+
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource(path, nil), nil)
+	if er != nil {
+		return nil, nil, er
+	}
+
+	if length == 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else {
+		req.Header.Set("Range", "bytes="+ByteRange{Offset: offset, Length: length}.spec())
+	}
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return nil, nil, er
+	}
+
+	resp.Body = trackResponse(resp.Body, newProgress(opts, resp.ContentLength))
+
+	if resp.StatusCode == http.StatusPartialContent {
+		start, end, total, er := parseContentRange(resp.Header.Get("Content-Range"))
+		if er != nil {
+			resp.Body.Close()
+			return nil, nil, er
+		}
+
+		return resp.Body, &ContentRange{Start: start, End: end, Total: total, ETag: resp.Header.Get("ETag")}, nil
+	}
+
+	/* The object has changed since the range was asked for, so all of it is returned */
+	if newRequestConfig(opts).header.Get("If-Range") != "" {
+		return resp.Body, &ContentRange{End: resp.ContentLength - 1, Total: resp.ContentLength, ETag: resp.Header.Get("ETag")}, nil
+	}
+
+	/* The server ignored the Range header and sent the whole object */
+	if _, er := io.CopyN(io.Discard, resp.Body, offset); er != nil && er != io.EOF {
+		resp.Body.Close()
+		return nil, nil, er
+	}
+
+	cr := &ContentRange{Start: offset, Total: resp.ContentLength, ETag: resp.Header.Get("ETag")}
+	body := resp.Body
+
+	switch {
+	case cr.Total < 0:
+		cr.End = -1
+		if length > 0 {
+			cr.End = offset + length - 1
+		}
+
+	case length == 0 || offset+length > cr.Total:
+		cr.End = cr.Total - 1
+
+	default:
+		cr.End = offset + length - 1
+	}
+
+	if length > 0 {
+		body = struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(resp.Body, length), resp.Body}
+	}
+
+	return body, cr, nil
+}
+
+// GetRanges fetches several byte ranges of the object at path, returning the content of each in
+// the same order as ranges. All of the ranges are requested at once; if the server answers with
+// a multipart/byteranges response it is split back into the individual ranges.
+//
+// S3 itself only honors a single range per request, and answers a request for several with the
+// whole object, which the ranges are then taken from; it is only read as far as the end of the
+// last of them. (Some S3-compatible stores return just the ranges.) Any range the server didn't
+// return is fetched with a request of its own, so GetRanges works everywhere. A range that
+// extends past the end of the object is truncated.
+func (s3 *S3) GetRanges(ctx context.Context, path string, ranges []ByteRange, opts ...RequestOption) ([][]byte, error) {
+	specs := []string{}
+
+	for _, br := range ranges {
+		if br.Offset < 0 || br.Length <= 0 {
+			return nil, fmt.Errorf("s3: invalid byte range %+v", br)
+		}
+
+		specs = append(specs, br.spec())
+	}
+
+	if len(specs) == 0 {
+		return [][]byte{}, nil
+	}
+
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource(path, nil), nil)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Range", "bytes="+strings.Join(specs, ","))
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	chunks, size, er := readRangeChunks(resp, ranges)
+	if er != nil {
+		return nil, er
+	}
+
+	results := make([][]byte, len(ranges))
+
+	for i, br := range ranges {
+		end := br.Offset + br.Length
+		if size >= 0 && end > size {
+			end = size
+		}
+
+		for _, chunk := range chunks {
+			chunkEnd := chunk.start + int64(len(chunk.data))
+
+			if br.Offset >= chunk.start && br.Offset < end && end <= chunkEnd {
+				results[i] = chunk.data[br.Offset-chunk.start : end-chunk.start]
+				break
+			}
+		}
+
+		if results[i] != nil {
+			continue
+		}
+
+		data, er := s3.getRange(ctx, path, br, opts)
+		if er != nil {
+			return nil, er
+		}
+
+		results[i] = data
+	}
+
+	return results, nil
+}
+
+// readRangeChunks extracts the byte ranges contained in the response to a GET of ranges, along
+// with the size of the object, or -1 if the response doesn't say. A full (200) response, which S3
+// sends when asked for several ranges, yields a single chunk from the start of the object to the
+// end of the last range.
+func readRangeChunks(resp *http.Response, ranges []ByteRange) ([]rangeChunk, int64, error) {
+	if resp.StatusCode != http.StatusPartialContent {
+		last := int64(0)
+		for _, br := range ranges {
+			if end := br.Offset + br.Length; end > last {
+				last = end
+			}
+		}
+
+		data, er := io.ReadAll(io.LimitReader(resp.Body, last))
+		if er != nil {
+			return nil, 0, er
+		}
+
+		size := resp.ContentLength
+		if int64(len(data)) < last {
+			size = int64(len(data))
+		}
+
+		return []rangeChunk{{start: 0, data: data}}, size, nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	if mediaType != "multipart/byteranges" {
+		start, _, size, er := parseContentRange(resp.Header.Get("Content-Range"))
+		if er != nil {
+			return nil, 0, er
+		}
+
+		data, er := io.ReadAll(resp.Body)
+		if er != nil {
+			return nil, 0, er
+		}
+
+		return []rangeChunk{{start: start, data: data}}, size, nil
+	}
+
+	chunks := []rangeChunk{}
+	size := int64(-1)
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+
+	for {
+		part, er := mr.NextPart()
+		if er == io.EOF {
+			return chunks, size, nil
+
+		} else if er != nil {
+			return nil, 0, er
+		}
+
+		start, _, total, er := parseContentRange(part.Header.Get("Content-Range"))
+		if er != nil {
+			return nil, 0, er
+		}
+
+		data, er := io.ReadAll(part)
+		if er != nil {
+			return nil, 0, er
+		}
+
+		chunks = append(chunks, rangeChunk{start: start, data: data})
+		size = total
+	}
+}
+
+// getRange fetches a single byte range of the object at path.
+func (s3 *S3) getRange(ctx context.Context, path string, br ByteRange, opts []RequestOption) ([]byte, error) {
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource(path, nil), nil)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Range", "bytes="+br.spec())
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	/* The server ignored the Range header and sent the whole object */
+	if resp.StatusCode != http.StatusPartialContent {
+		if _, er := io.CopyN(io.Discard, resp.Body, br.Offset); er != nil && er != io.EOF {
+			return nil, er
+		}
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, br.Length))
+}
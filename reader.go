@@ -0,0 +1,201 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// readerBlockSize is how much an ObjectReader fetches at once to satisfy small reads.
+const readerBlockSize = 1024 * 1024
+
+// ObjectReader gives random access to an object with ranged GETs, implementing io.ReadSeeker and
+// io.ReaderAt so that the object can be handed to consumers that need to seek, such as
+// archive/zip or Parquet readers. Small reads are served from a buffered block of the object;
+// reads at least as large as a block are fetched directly.
+//
+// Every request is made with If-Match set to the ETag the object had when the reader was
+// created, so reads fail rather than mix content if the object is replaced in the meantime.
+// ReadAt may be called concurrently, but Read and Seek may not.
+type ObjectReader struct {
+	s3   *S3
+	ctx  context.Context
+	path string
+	opts []RequestOption
+	size int64
+
+	offset int64
+
+	lock        sync.Mutex
+	block       []byte
+	blockOffset int64
+}
+
+var (
+	_ io.ReadSeeker = (*ObjectReader)(nil)
+	_ io.ReaderAt   = (*ObjectReader)(nil)
+)
+
+// NewReader returns an ObjectReader for the object at path, whose requests are made with ctx and
+// opts. The object's size and ETag are found with a HEAD request straight away.
+func (s3 *S3) NewReader(ctx context.Context, path string, opts ...RequestOption) (*ObjectReader, error) {
+	header, er := s3.Head(ctx, path, opts...)
+	if er != nil {
+		return nil, er
+	}
+
+	return s3.newObjectReader(ctx, path, header, opts)
+}
+
+// newObjectReader returns an ObjectReader for the object at path, whose HEAD response was header.
+func (s3 *S3) newObjectReader(ctx context.Context, path string, header http.Header, opts []RequestOption) (*ObjectReader, error) {
+	size, er := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if er != nil {
+		return nil, fmt.Errorf("s3: missing size of %s", path)
+	}
+
+	allOpts := append([]RequestOption{}, opts...)
+	if etag := header.Get("ETag"); etag != "" {
+		allOpts = append(allOpts, WithHeader("If-Match", etag))
+	}
+
+	return &ObjectReader{
+		s3:   s3,
+		ctx:  ctx,
+		path: path,
+		opts: allOpts,
+		size: size,
+	}, nil
+}
+
+// Size returns the size of the object.
+func (rd *ObjectReader) Size() int64 {
+	return rd.size
+}
+
+// Read reads from the current offset, advancing it.
+func (rd *ObjectReader) Read(p []byte) (int, error) {
+	n, er := rd.ReadAt(p, rd.offset)
+	rd.offset += int64(n)
+
+	/* Unlike ReadAt, Read may return less than asked for without an error */
+	if er == io.EOF && n > 0 {
+		er = nil
+	}
+
+	return n, er
+}
+
+// Seek sets the offset for the next Read.
+func (rd *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rd.offset
+	case io.SeekEnd:
+		offset += rd.size
+	default:
+		return rd.offset, fmt.Errorf("s3: invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return rd.offset, fmt.Errorf("s3: cannot seek to negative offset %d", offset)
+	}
+
+	rd.offset = offset
+	return offset, nil
+}
+
+// ReadAt reads len(p) bytes from offset off, unless the object ends first, in which case it
+// returns io.EOF along with what there was.
+func (rd *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("s3: cannot read at negative offset %d", off)
+	}
+
+	if off >= rd.size {
+		return 0, io.EOF
+	}
+
+	want := int64(len(p))
+	if off+want > rd.size {
+		want = rd.size - off
+	}
+
+	if want >= readerBlockSize {
+		data, er := rd.s3.getRange(rd.ctx, rd.path, ByteRange{Offset: off, Length: want}, rd.opts)
+		n := copy(p, data)
+		return n, rd.result(n, len(p), er)
+	}
+
+	n := 0
+	for int64(n) < want {
+		block, blockOffset, er := rd.blockAt(off + int64(n))
+		if er != nil {
+			return n, er
+		}
+
+		n += copy(p[n:want], block[off+int64(n)-blockOffset:])
+	}
+
+	return n, rd.result(n, len(p), nil)
+}
+
+// result returns the error ReadAt should return after reading n of the wanted bytes.
+func (rd *ObjectReader) result(n, wanted int, er error) error {
+	if er != nil {
+		return er
+	}
+
+	if n < wanted {
+		return io.EOF
+	}
+
+	return nil
+}
+
+// blockAt returns the block containing offset off, fetching it unless it is already buffered.
+func (rd *ObjectReader) blockAt(off int64) ([]byte, int64, error) {
+	rd.lock.Lock()
+	block, blockOffset := rd.block, rd.blockOffset
+	rd.lock.Unlock()
+
+	if off >= blockOffset && off < blockOffset+int64(len(block)) {
+		return block, blockOffset, nil
+	}
+
+	/* Blocks are aligned, so that reads moving backwards reuse them as well as forwards */
+	blockOffset = off - off%readerBlockSize
+
+	length := int64(readerBlockSize)
+	if blockOffset+length > rd.size {
+		length = rd.size - blockOffset
+	}
+
+	block, er := rd.s3.getRange(rd.ctx, rd.path, ByteRange{Offset: blockOffset, Length: length}, rd.opts)
+	if er != nil {
+		return nil, 0, er
+	}
+
+	if int64(len(block)) <= off-blockOffset {
+		return nil, 0, fmt.Errorf("s3: object shrank while it was being read")
+	}
+
+	rd.lock.Lock()
+	rd.block, rd.blockOffset = block, blockOffset
+	rd.lock.Unlock()
+
+	return block, blockOffset, nil
+}
+
+// Close releases the buffered block. The reader must not be used afterwards.
+func (rd *ObjectReader) Close() error {
+	rd.lock.Lock()
+	rd.block = nil
+	rd.lock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultPartRetryPolicy is the part retry policy used unless SetPartRetryPolicy is called.
+var DefaultPartRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.5,
+}
+
+// SetPartRetryPolicy sets the policy for uploading a part of a multipart upload again when it has
+// failed, once the retries of its request (see SetRetryPolicy) are exhausted, or when it failed
+// in a way a request isn't retried for, such as S3 reporting that the part arrived corrupted.
+// This only happens when the part can be read again from its source, as PutReaderAt, PutFile and
+// RunTask can, so that a single failed part doesn't fail the whole upload.
+func (s3 *S3) SetPartRetryPolicy(policy RetryPolicy) {
+	s3.partRetry = &policy
+}
+
+func (s3 *S3) partRetryPolicy() RetryPolicy {
+	if s3.partRetry == nil {
+		return DefaultPartRetryPolicy
+	}
+
+	return *s3.partRetry
+}
+
+// partRetryable reports whether a part that failed with er may succeed if it is uploaded again:
+// unless the upload was aborted or cancelled, or S3 rejected the part for a reason that won't
+// change, such as AccessDenied or NoSuchUpload.
+func partRetryable(er error) bool {
+	if errors.Is(er, context.Canceled) || errors.Is(er, context.DeadlineExceeded) || errors.Is(er, ErrAborted) {
+		return false
+	}
+
+	var s3er *S3Error
+	if errors.As(er, &s3er) {
+		code := s3er.awsCode()
+		return s3er.Temporary() || code == "BadDigest" || code == "IncompleteBody"
+	}
+
+	return true
+}
+
+// retryPart calls send to upload a part, calling it again when it fails, as the part retry policy
+// allows. send must read the part afresh from its source each time.
+func (s3 *S3) retryPart(ctx context.Context, send func() error) error {
+	policy := s3.partRetryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		er := send()
+		if er == nil || attempt >= policy.MaxAttempts || !partRetryable(er) {
+			return er
+		}
+
+		select {
+		case <-time.After(policy.delay(attempt)):
+		case <-ctx.Done():
+			return er
+		}
+	}
+}
+
+// sectionMD5 returns the MD5 of size bytes of r from offset.
+func sectionMD5(r io.ReaderAt, offset, size int64) ([]byte, error) {
+	hash := md5.New()
+	if _, er := io.Copy(hash, io.NewSectionReader(r, offset, size)); er != nil {
+		return nil, er
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// addPartFrom uploads size bytes of r from offset as part partNumber, re-reading them to upload
+// the part again if it fails.
+func (mp *S3Multipart) addPartFrom(partNumber int, r io.ReaderAt, offset, size int64) error {
+	return mp.s3.retryPart(mp.ctx, func() error {
+		md5sum, er := sectionMD5(r, offset, size)
+		if er != nil {
+			return er
+		}
+
+		section := io.NewSectionReader(r, offset, size)
+
+		er = mp.addPartAt(partNumber, section, size, md5sum)
+		if er != nil {
+			/* What the failed attempt sent no longer counts towards the progress */
+			sent, _ := section.Seek(0, io.SeekCurrent)
+			mp.progress.add(-sent)
+		}
+
+		return er
+	})
+}
+
+// PutReaderAt uploads size bytes of r to path, as Put does, except that the parts of a multipart
+// upload are read directly from r rather than buffered in memory, up to the number set with
+// SetUploadConcurrency at once, and a part that fails is read again and retried on its own (see
+// SetPartRetryPolicy) rather than failing the whole upload. Any opts are applied to the upload
+// request (or the initiation of the multipart upload).
+func (s3 *S3) PutReaderAt(ctx context.Context, r io.ReaderAt, size int64, path, contentType string, opts ...RequestOption) error {
+	return s3.putReaderAt(ctx, r, size, path, nil, contentType, opts)
+}
+
+// putReaderAt implements PutReaderAt, sending md5sum with single-request uploads if it isn't nil.
+func (s3 *S3) putReaderAt(ctx context.Context, r io.ReaderAt, size int64, path string, md5sum []byte, contentType string, opts []RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("PutReaderAt", path, size, start, er)
+	}(time.Now())
+
+	ctx, endSpan := s3.startSpan(ctx, "PutReaderAt", path)
+	defer func() {
+		endSpan(er)
+	}()
+
+	if size <= multipartThreshold {
+		return s3.putSized(ctx, io.NewSectionReader(r, 0, size), size, path, md5sum, contentType, opts)
+	}
+
+	mp, er := s3.startMultipart(ctx, path, s3.multipartHeader(contentType), append(opts, nestedAudit()))
+	if er != nil {
+		return er
+	}
+	defer func() {
+		if er != nil {
+			er = mp.abandon(er)
+		}
+	}()
+
+	mp.progress.setTotal(size)
+
+	if er := s3.uploadPartsFrom(mp, r, size); er != nil {
+		return er
+	}
+
+	return mp.Complete(contentType)
+}
+
+// uploadPartsFrom adds size bytes of r to mp in parts of the part size, sending up to the upload
+// concurrency at once, and stopping at the first part that fails for good.
+func (s3 *S3) uploadPartsFrom(mp *S3Multipart, r io.ReaderAt, size int64) error {
+	concurrency := s3.uploadConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	tasks := planTasks(size, s3.uploadPartSize())
+
+	mp.lock.Lock()
+	mp.etags = append(mp.etags, make([]string, len(tasks))...)
+	mp.lock.Unlock()
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+
+	queue := make(chan TransferTask)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for task := range queue {
+				if er := mp.addPartFrom(task.Part, r, task.Offset, task.Length); er != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = er
+					}
+					errLock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		errLock.Lock()
+		failed := firstErr != nil
+		errLock.Unlock()
+
+		if failed {
+			break
+		}
+
+		queue <- task
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
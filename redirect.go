@@ -0,0 +1,144 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+// Redirect describes where S3 said a request should have been sent instead.
+type Redirect struct {
+	// Permanent is true for a 301 PermanentRedirect, which S3 sends when the bucket lives in a
+	// different region than the endpoint the request was sent to, and false for a 307
+	// TemporaryRedirect, which it sends while DNS for a newly created bucket propagates.
+	Permanent bool
+
+	// Endpoint is the host the request should be sent to. Region is the region the bucket
+	// lives in, if S3 said; either may be empty, but not both.
+	Endpoint string
+	Region   string
+}
+
+// RedirectAction says what to do about a redirect.
+type RedirectAction int
+
+const (
+	// RedirectRetarget sends the request to the new endpoint, along with every request made
+	// after it. This is the default.
+	RedirectRetarget RedirectAction = iota
+
+	// RedirectFollow sends the request to the new endpoint, but leaves later requests going
+	// where they went before.
+	RedirectFollow
+
+	// RedirectRefuse fails the request with the *S3Error carrying the redirect, whose Redirect
+	// method describes it.
+	RedirectRefuse
+)
+
+// RedirectPolicy decides how to handle a redirect from S3.
+type RedirectPolicy func(Redirect) RedirectAction
+
+// SetRedirectPolicy sets the policy consulted whenever S3 redirects a request. A nil policy (the
+// default) retargets the S3 at whatever endpoint it is redirected to. Refusing redirects is
+// useful when the endpoint is fixed deliberately, for example to keep data in one region, and a
+// policy can also log the redirect so that the configuration can be corrected.
+func (s3 *S3) SetRedirectPolicy(policy RedirectPolicy) {
+	s3.redirectPolicy = policy
+}
+
+// redirectAction consults the redirect policy about redirect.
+func (s3 *S3) redirectAction(redirect Redirect) RedirectAction {
+	if s3.redirectPolicy == nil {
+		return RedirectRetarget
+	}
+
+	return s3.redirectPolicy(redirect)
+}
+
+// Redirect returns the redirect err describes, or nil if it isn't one.
+func (err *S3Error) Redirect() *Redirect {
+	var permanent bool
+
+	switch err.Code {
+	case http.StatusMovedPermanently:
+		permanent = true
+	case http.StatusTemporaryRedirect:
+	default:
+		return nil
+	}
+
+	msg := S3NewEndpointError{}
+	xml.Unmarshal(err.Body, &msg)
+
+	if msg.Code != "" && msg.Code != "PermanentRedirect" && msg.Code != "TemporaryRedirect" {
+		return nil
+	}
+
+	redirect := &Redirect{
+		Permanent: permanent,
+		Endpoint:  msg.Endpoint,
+		Region:    err.Header.Get("x-amz-bucket-region"),
+	}
+
+	if redirect.Endpoint == "" && redirect.Region == "" {
+		return nil
+	}
+
+	return redirect
+}
+
+// redirectHost returns the host to send requests to in order to follow redirect. Responses to
+// HEAD requests have no body, so only the region is known; the endpoint can then be worked out
+// for AWS, but not for other services. Dual-stack clients stay on the dual-stack endpoint of the
+// region, since the endpoint S3 names may not be reachable from where they are.
+func (s3 *S3) redirectHost(redirect *Redirect) string {
+	if redirect.Endpoint != "" && !(s3.dualStack && redirect.Region != "") {
+		return redirect.Endpoint
+	}
+
+	if !strings.HasSuffix(s3.baseHost, ".amazonaws.com") {
+		return ""
+	}
+
+	host := "s3." + redirect.Region + ".amazonaws.com"
+	if s3.dualStack {
+		host = "s3.dualstack." + redirect.Region + ".amazonaws.com"
+	}
+
+	if !s3.pathStyle && s3.bucket != "" {
+		host = s3.bucket + "." + host
+	}
+
+	return host
+}
+
+// followRedirect prepares req to be sent again if s3er is a redirect the policy allows following,
+// retargeting the S3 too if the policy says so.
+func (s3 *S3) followRedirect(req *http.Request, s3er *S3Error) bool {
+	redirect := s3er.Redirect()
+	if redirect == nil {
+		return false
+	}
+
+	host := s3.redirectHost(redirect)
+	if host == "" {
+		return false
+	}
+
+	action := s3.redirectAction(*redirect)
+	if action == RedirectRefuse || !rewindBody(req) {
+		return false
+	}
+
+	if action == RedirectRetarget {
+		s3.retargetTo(host, redirect.Region)
+	}
+
+	req.URL.Host = host
+	req.Host = ""
+	req.Header.Set("Host", host)
+	s3.applySigningHost(req)
+
+	return true
+}
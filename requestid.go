@@ -0,0 +1,66 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// defaultUserAgent is the User-Agent every request is sent with, followed by any suffix set with
+// SetUserAgent.
+const defaultUserAgent = "github.com/lye/s3/v2"
+
+// SetUserAgent appends suffix, such as the name and version of the application, to the
+// User-Agent requests are sent with, so that they can be told apart in server access logs,
+// CloudTrail, and by AWS support. Passing "" restores the default. A User-Agent set WithHeader
+// replaces it entirely.
+func (s3 *S3) SetUserAgent(suffix string) {
+	s3.userAgentSuffix = strings.TrimSpace(suffix)
+}
+
+func (s3 *S3) userAgent() string {
+	if s3.userAgentSuffix == "" {
+		return defaultUserAgent
+	}
+
+	return defaultUserAgent + " " + s3.userAgentSuffix
+}
+
+// ResponseMetadata identifies the response to a request, as AWS support asks for when
+// investigating it. An *S3Error carries the same identifiers of a request that failed.
+type ResponseMetadata struct {
+	RequestId string // The x-amz-request-id of the response.
+	HostId    string // The x-amz-id-2 of the response, identifying the host that handled it.
+	Attempts  int    // How many times the request was sent, including retries.
+}
+
+// ReceiveResponseMetadata stores the metadata of the response to the operation in *meta, whether
+// it succeeded or S3 responded with an error. For operations that make several requests, such as
+// a multipart upload, it is that of the last request made. It is left empty if no response was
+// received, such as when the connection failed.
+func ReceiveResponseMetadata(meta *ResponseMetadata) RequestOption {
+	return func(config *requestConfig) {
+		config.metadata = meta
+	}
+}
+
+// receiveMetadata stores the metadata of resp, or of the *S3Error er, which was the outcome of
+// the last of attempts, where config asks for it.
+func (config *requestConfig) receiveMetadata(resp *http.Response, er error, attempts int) {
+	if config.metadata == nil {
+		return
+	}
+
+	meta := ResponseMetadata{Attempts: attempts}
+
+	var s3er *S3Error
+	if resp != nil {
+		meta.RequestId = resp.Header.Get("x-amz-request-id")
+		meta.HostId = resp.Header.Get("x-amz-id-2")
+	} else if errors.As(er, &s3er) {
+		meta.RequestId = s3er.RequestId
+		meta.HostId = s3er.HostId
+	}
+
+	*config.metadata = meta
+}
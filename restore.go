@@ -0,0 +1,301 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Retrieval tiers for restoring archived objects, from quickest and most expensive to slowest and
+// cheapest. Expedited retrievals aren't available for DEEP_ARCHIVE.
+const (
+	RestoreExpedited = "Expedited"
+	RestoreStandard  = "Standard"
+	RestoreBulk      = "Bulk"
+)
+
+// archivedClasses are the storage classes whose objects must be restored before they can be read.
+var archivedClasses = map[string]bool{
+	StorageGlacier:     true,
+	StorageDeepArchive: true,
+}
+
+type s3restoreReq struct {
+	XMLName              xml.Name `xml:"RestoreRequest"`
+	Days                 int
+	GlacierJobParameters struct {
+		Tier string
+	}
+}
+
+// RestoreState is the state of the restore of an archived object, as returned by ParseRestore.
+type RestoreState struct {
+	Ongoing bool      // The restore has been requested, and the object can't be read yet.
+	Expiry  time.Time // When the restored copy will be removed, once the restore is complete.
+}
+
+var (
+	restoreOngoingRe = regexp.MustCompile(`ongoing-request="(true|false)"`)
+	restoreExpiryRe  = regexp.MustCompile(`expiry-date="([^"]*)"`)
+)
+
+// ParseRestore parses the x-amz-restore header of a response to Head or Get, such as
+// `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`, to tell whether a restore
+// requested with RestoreObject has completed. It returns nil if no restore has been requested.
+func ParseRestore(header http.Header) (*RestoreState, error) {
+	value := header.Get("x-amz-restore")
+	if value == "" {
+		return nil, nil
+	}
+
+	ongoing := restoreOngoingRe.FindStringSubmatch(value)
+	if ongoing == nil {
+		return nil, fmt.Errorf("s3: invalid x-amz-restore header %#v", value)
+	}
+
+	state := &RestoreState{Ongoing: ongoing[1] == "true"}
+
+	if expiry := restoreExpiryRe.FindStringSubmatch(value); expiry != nil {
+		t, er := http.ParseTime(expiry[1])
+		if er != nil {
+			return nil, fmt.Errorf("s3: invalid expiry in x-amz-restore header %#v", value)
+		}
+
+		state.Expiry = t
+	}
+
+	return state, nil
+}
+
+// RestoreObject asks S3 to restore a temporary copy of the archived object at path (in the GLACIER
+// or DEEP_ARCHIVE storage class) for days, using the retrieval tier. It reports whether a restore
+// was already in progress, which is not an error. Restores take from minutes to two days, depending
+// on the class and tier; poll the object with Head and ParseRestore to find out when it's ready.
+func (s3 *S3) RestoreObject(ctx context.Context, path string, days int, tier string) (inProgress bool, er error) {
+	defer func(start time.Time) {
+		s3.audit("RestoreObject", path, 0, start, er)
+	}(time.Now())
+
+	body := s3restoreReq{Days: days}
+	body.GlacierJobParameters.Tier = tier
+
+	xmlBody, er := xml.Marshal(body)
+	if er != nil {
+		return false, er
+	}
+
+	values := url.Values{}
+	values.Set("restore", "")
+
+	req, er := http.NewRequestWithContext(ctx, "POST", s3.resource(path, values), bytes.NewReader(xmlBody))
+	if er != nil {
+		return false, er
+	}
+
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(xmlBody)))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Host", req.URL.Host)
+	req.ContentLength = int64(len(xmlBody))
+
+	resp, er := s3.do(req)
+
+	var s3er *S3Error
+	if errors.As(er, &s3er) && s3er.Code == http.StatusConflict && s3er.awsCode() == "RestoreAlreadyInProgress" {
+		return true, nil
+
+	} else if er != nil {
+		return false, er
+	}
+	resp.Body.Close()
+
+	return false, nil
+}
+
+// RestoreLedger tracks the batch restore of every archived object under a prefix, as made by
+// PlanRestore. Restoring from DEEP_ARCHIVE takes up to two days, so the ledger is meant to outlive
+// the process that starts the restores: save it with Save, reload it with LoadRestoreLedger, and
+// call CheckRestore from time to time until Ready reports that every object can be downloaded.
+type RestoreLedger struct {
+	Bucket  string                    `json:"bucket"`
+	Prefix  string                    `json:"prefix"`
+	Days    int                       `json:"days"`
+	Tier    string                    `json:"tier"`
+	Objects map[string]*RestoreStatus `json:"objects"`
+}
+
+// RestoreStatus records the progress of the restore of one object in a RestoreLedger.
+type RestoreStatus struct {
+	StorageClass string    `json:"storage_class"`
+	Requested    bool      `json:"requested"`
+	Ready        bool      `json:"ready"`
+	Expiry       time.Time `json:"expiry,omitempty"` // When the restored copy will be removed.
+}
+
+// RestoreProgress summarizes a RestoreLedger.
+type RestoreProgress struct {
+	Total     int // Archived objects in the ledger.
+	Requested int // Objects whose restore has been requested.
+	Ready     int // Objects that have been restored.
+}
+
+// PlanRestore lists the objects under prefix, and returns a ledger for restoring the ones in an
+// archived storage class (GLACIER or DEEP_ARCHIVE) for days, using the retrieval tier.
+func (s3 *S3) PlanRestore(ctx context.Context, prefix string, days int, tier string) (*RestoreLedger, error) {
+	ledger := &RestoreLedger{
+		Bucket:  s3.bucket,
+		Prefix:  prefix,
+		Days:    days,
+		Tier:    tier,
+		Objects: map[string]*RestoreStatus{},
+	}
+
+	er := s3.Walk(ctx, prefix, func(obj ObjectSummary) error {
+		if archivedClasses[obj.StorageClass] {
+			ledger.Objects[obj.Key] = &RestoreStatus{StorageClass: obj.StorageClass}
+		}
+
+		return nil
+	})
+	if er != nil {
+		return nil, er
+	}
+
+	return ledger, nil
+}
+
+// StartRestore requests the restore of every object in ledger that hasn't been requested yet,
+// sending no more than rate requests a second (or as quickly as possible if rate isn't positive),
+// so that the restores of a large archive don't crowd out the bucket's other traffic. The ledger
+// is updated as restores are requested, so if StartRestore fails or ctx is cancelled, it can be
+// saved and the remaining restores requested later.
+func (s3 *S3) StartRestore(ctx context.Context, ledger *RestoreLedger, rate float64) error {
+	if ledger.Bucket != s3.bucket {
+		return fmt.Errorf("s3: the ledger is for bucket %s, not %s", ledger.Bucket, s3.bucket)
+	}
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	first := true
+
+	for _, key := range ledger.keys() {
+		status := ledger.Objects[key]
+		if status.Requested {
+			continue
+		}
+
+		if tick != nil && !first {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		first = false
+
+		if _, er := s3.RestoreObject(ctx, key, ledger.Days, ledger.Tier); er != nil {
+			return fmt.Errorf("s3: restoring %s: %w", key, er)
+		}
+
+		status.Requested = true
+	}
+
+	return nil
+}
+
+// CheckRestore finds out which of the requested restores in ledger have completed since it was
+// last checked, with a HEAD request for each, and returns the progress made.
+func (s3 *S3) CheckRestore(ctx context.Context, ledger *RestoreLedger) (RestoreProgress, error) {
+	for _, key := range ledger.keys() {
+		status := ledger.Objects[key]
+		if !status.Requested || status.Ready {
+			continue
+		}
+
+		header, er := s3.Head(ctx, key)
+		if er != nil {
+			return ledger.Progress(), fmt.Errorf("s3: checking the restore of %s: %w", key, er)
+		}
+
+		state, er := ParseRestore(header)
+		if er != nil {
+			return ledger.Progress(), er
+		}
+
+		/* An object that is no longer archived (its class was changed) needs no restore */
+		switch {
+		case state != nil && !state.Ongoing:
+			status.Ready = true
+			status.Expiry = state.Expiry
+		case state == nil && !archivedClasses[header.Get("x-amz-storage-class")]:
+			status.Ready = true
+		}
+	}
+
+	return ledger.Progress(), nil
+}
+
+// Progress counts the objects in the ledger by how far their restore has got.
+func (ledger *RestoreLedger) Progress() RestoreProgress {
+	progress := RestoreProgress{Total: len(ledger.Objects)}
+
+	for _, status := range ledger.Objects {
+		if status.Requested {
+			progress.Requested++
+		}
+
+		if status.Ready {
+			progress.Ready++
+		}
+	}
+
+	return progress
+}
+
+// Ready reports whether every object in the ledger has been restored and can be downloaded.
+func (ledger *RestoreLedger) Ready() bool {
+	progress := ledger.Progress()
+	return progress.Ready == progress.Total
+}
+
+// keys returns the keys in the ledger in sorted order, so that restores are requested in the
+// order the objects were listed.
+func (ledger *RestoreLedger) keys() []string {
+	return sortedKeys(ledger.Objects)
+}
+
+// Save writes the ledger to the file at path as JSON, replacing it atomically.
+func (ledger *RestoreLedger) Save(path string) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(ledger)
+	})
+}
+
+// LoadRestoreLedger reads a ledger written by RestoreLedger.Save.
+func LoadRestoreLedger(path string) (*RestoreLedger, error) {
+	data, er := os.ReadFile(path)
+	if er != nil {
+		return nil, er
+	}
+
+	ledger := &RestoreLedger{}
+	if er := json.Unmarshal(data, ledger); er != nil {
+		return nil, fmt.Errorf("s3: invalid restore ledger %s: %w", path, er)
+	}
+
+	return ledger, nil
+}
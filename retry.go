@@ -0,0 +1,117 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail for transient reasons (see IsTemporary) are
+// retried, such as 500 Internal Error and 503 Slow Down responses, and connections that were
+// reset or closed mid-request. The delay before each retry starts at BaseDelay and doubles with
+// every attempt, up to MaxDelay, and is then randomly reduced by up to the fraction Jitter
+// (between 0 and 1) so that clients throttled at the same time don't retry in lockstep.
+type RetryPolicy struct {
+	MaxAttempts int // Attempts in total, including the first; 1 or less disables retries.
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+}
+
+// DefaultRetryPolicy is the retry policy used unless SetRetryPolicy is called.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.5,
+}
+
+// SetRetryPolicy sets the policy for retrying failed requests. Requests are only retried if
+// their body can be sent again, which is the case for the buffers Put and the multipart
+// uploads create internally, and for any io.ReadSeeker passed to Put or AddPart. Waiting for a
+// retry ends early if the request's context is cancelled.
+func (s3 *S3) SetRetryPolicy(policy RetryPolicy) {
+	s3.retry = &policy
+}
+
+func (s3 *S3) retryPolicy() RetryPolicy {
+	if s3.retry == nil {
+		return DefaultRetryPolicy
+	}
+
+	return *s3.retry
+}
+
+// delay returns how long to wait after the given (1-based) failed attempt.
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	d := policy.BaseDelay
+	for i := 1; i < attempt && (policy.MaxDelay <= 0 || d < policy.MaxDelay); i++ {
+		d *= 2
+	}
+
+	if policy.MaxDelay > 0 && d > policy.MaxDelay {
+		d = policy.MaxDelay
+	}
+
+	if policy.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * policy.Jitter * float64(d))
+	}
+
+	return d
+}
+
+// IsTemporary reports whether er, returned by an operation, is a transient failure that may not
+// happen again: an *S3Error whose Temporary method says so, a connection that was reset, closed
+// or refused, or a network timeout (including ErrTimeout). Cancelled contexts and expired
+// deadlines are not temporary. Requests are retried (see SetRetryPolicy) for exactly these errors.
+func IsTemporary(er error) bool {
+	if er == nil || errors.Is(er, context.Canceled) || errors.Is(er, context.DeadlineExceeded) {
+		return false
+	}
+
+	var s3er *S3Error
+	if errors.As(er, &s3er) {
+		return s3er.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.Is(er, ErrTimeout) || errors.As(er, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(er, syscall.ECONNRESET) || errors.Is(er, syscall.ECONNREFUSED) || errors.Is(er, syscall.EPIPE) ||
+		errors.Is(er, io.EOF) || errors.Is(er, io.ErrUnexpectedEOF)
+}
+
+// makeRewindable lets req be sent more than once when its body r can seek back to where it
+// started; http.NewRequest already arranges this for in-memory bodies. The body is no longer
+// closed after the request is sent, since it is still needed to retry.
+func makeRewindable(req *http.Request, r io.Reader) {
+	if req.GetBody != nil {
+		return
+	}
+
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return
+	}
+
+	start, er := rs.Seek(0, io.SeekCurrent)
+	if er != nil {
+		return
+	}
+
+	req.Body = io.NopCloser(rs)
+	req.GetBody = func() (io.ReadCloser, error) {
+		if _, er := rs.Seek(start, io.SeekStart); er != nil {
+			return nil, er
+		}
+
+		return io.NopCloser(rs), nil
+	}
+}
@@ -2,46 +2,243 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"math/rand"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-// S3 provides a wrapper around your S3 credentials. It carries no other internal state
-// and can be copied freely.
+const (
+	// partSize is the default size of each part uploaded when Put switches to the multipart
+	// API (see SetPartSize).
+	partSize = 7 * 1024 * 1024
+
+	// maxRedirects is the number of TemporaryRedirect responses followed for a single request.
+	maxRedirects = 3
+
+	// defaultPutBufferLimit is the default largest unknown-length upload that Put will buffer
+	// in memory to send as a single request.
+	defaultPutBufferLimit = 16 * 1024 * 1024
+
+	// defaultExpectContinue is the default smallest upload sent with Expect: 100-continue.
+	defaultExpectContinue = 2 * 1024 * 1024
+)
+
+// multipartThreshold is the size above which Put switches to the multipart API. It is a variable
+// only so that tests can reach the multipart API without uploading gigabytes.
+var multipartThreshold int64 = 3 * 1024 * 1024 * 1024
+
+// v2SubResources lists the query parameters that are included in the resource string signed by
+// Signature Version 2.
+var v2SubResources = map[string]bool{
+	"accelerate":                   true,
+	"acl":                          true,
+	"attributes":                   true,
+	"cors":                         true,
+	"delete":                       true,
+	"legal-hold":                   true,
+	"lifecycle":                    true,
+	"location":                     true,
+	"logging":                      true,
+	"notification":                 true,
+	"partNumber":                   true,
+	"policy":                       true,
+	"requestPayment":               true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+	"restore":                      true,
+	"retention":                    true,
+	"select":                       true,
+	"select-type":                  true,
+	"tagging":                      true,
+	"torrent":                      true,
+	"uploadId":                     true,
+	"uploads":                      true,
+	"versionId":                    true,
+	"versioning":                   true,
+	"versions":                     true,
+	"website":                      true,
+}
+
+// S3 provides a wrapper around your S3 credentials. The Set methods configure it, and must not be
+// called once it is in use by several goroutines; after that, derive differently configured
+// copies with WithBucket, WithRegion and WithTimeout instead. Copies share the same credentials
+// (see SetCredentials). Everything else an S3 changes while making requests, such as the endpoint
+// it has been redirected to, is guarded, so an S3 may be used concurrently.
 type S3 struct {
-	bucket   string
-	accessId string
-	secret   string
-	endpoint string
+	bucket    string
+	creds     *credentialStore
+	anonymous bool
+	endpoint  string // The host requests are sent to, including the bucket unless path-style.
+	region    string
+	retarget  *retarget
+
+	scheme      string
+	baseHost    string
+	pathStyle   bool
+	accelerate  bool
+	dualStack   bool
+	signingHost string
+
+	client            *http.Client
+	putBufferLimit    int64
+	expectContinue    int64
+	uploadConcurrency int
+	partSize          int64
+	defaultOpts       []RequestOption
+	middleware        []Middleware
+	userAgentSuffix   string
+
+	checksum          *checksumHash
+	checksumAlgorithm string
+	sigV4             bool
+	signer            Signer       // Signs requests in place of the S3's own signatures, if set.
+	accessPoint       *accessPoint // The access point the bucket names, if it does.
+	bucketErr         error        // Why the bucket is invalid, if it is an invalid ARN.
+	streaming         bool
+	retry             *RetryPolicy
+	timeouts          Timeouts
+	partRetry         *RetryPolicy
+	strict            bool
+
+	clock          *clock
+	inFlight       chan struct{} // Holds a token for each request in flight, if they are limited.
+	express        *expressSession
+	auditSink      AuditSink
+	metrics        MetricsCollector
+	tracer         Tracer
+	logger         *slog.Logger
+	redirectPolicy RedirectPolicy
+	costs          *CostAccountant
+}
+
+// NewS3WithToken allocates a new S3 with temporary credentials, such as those returned by STS
+// AssumeRole or GetSessionToken. The session token is sent with every request in the
+// x-amz-security-token header, where it is covered by the signature, and included in presigned
+// URLs. Temporary credentials expire; see SetCredentialsRefresh or SetCredentialsProvider for
+// replacing them automatically.
+func NewS3WithToken(bucket, accessId, secret, token string) *S3 {
+	s3 := NewS3(bucket, accessId, secret)
+	s3.SetCredentials(accessId, secret, token)
+
+	return s3
+}
+
+// NewAnonymousS3 allocates a new S3 without credentials, whose requests are sent unsigned. This
+// is how public buckets, such as open datasets, are read without an AWS account. Presigned URLs
+// from an anonymous S3 are plain URLs, and presigned POST forms can't be made at all.
+func NewAnonymousS3(bucket string) *S3 {
+	s3 := NewS3(bucket, "", "")
+	s3.anonymous = true
+
+	return s3
 }
 
-// NewS3 allocates a new S3 with the provided credentials.
+// NewS3 allocates a new S3 with the provided credentials. It is equivalent to New with
+// WithCredentials, which allows configuring the rest of the S3 at the same time, except that an
+// invalid access point ARN is reported by every request rather than by NewS3.
 func NewS3(bucket, accessId, secret string) *S3 {
-	return &S3{
-		bucket:   bucket,
-		accessId: accessId,
-		secret:   secret,
-		endpoint: fmt.Sprintf("%s.s3.amazonaws.com", bucket),
+	s3 := newS3(bucket)
+	s3.creds = newCredentialStore(accessId, secret)
+
+	return s3
+}
+
+// NewAccountS3 allocates a new S3 with the provided credentials that isn't bound to any bucket,
+// for account-level requests such as ListBuckets. An S3 for one of the buckets is derived from it
+// with WithBucket; New("", ...) is the equivalent with options.
+func NewAccountS3(accessId, secret string) *S3 {
+	return NewS3("", accessId, secret)
+}
+
+// newS3 allocates an S3 for bucket, without credentials.
+func newS3(bucket string) *S3 {
+	s3 := &S3{
+		baseHost: defaultHost,
+		retarget: &retarget{},
+		clock:    &clock{},
+	}
+	s3.setBucket(bucket)
+
+	return s3
+}
+
+// signRequest adds an Authorization header to req. Directory buckets are signed with
+// Signature Version 4 using session credentials, then anything else with the signer set with
+// SetSigner, if there is one, access points with Version 4 (or 4A, for Multi-Region Access
+// Points), and everything else with Version 2 unless SetSignatureV4 says otherwise.
+func (s3 *S3) signRequest(req *http.Request) error {
+	if s3.anonymous {
+		return nil
 	}
+
+	if s3.express != nil {
+		return s3.signExpress(req)
+	}
+
+	if s3.signer != nil {
+		return s3.signer.Sign(req)
+	}
+
+	if ap := s3.accessPoint; ap != nil && ap.multiRegion {
+		creds, er := s3.signingCredentials(req)
+		if er != nil {
+			return er
+		}
+
+		return signV4A(req, creds.AccessId, creds.Secret, "s3", s3.now())
+	}
+
+	if s3.sigV4 || s3.accessPoint != nil {
+		return s3.SignerV4().Sign(req)
+	}
+
+	return s3.SignerV2().Sign(req)
+}
+
+func (s3 *S3) signRequestV2(req *http.Request, creds Credentials) {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", s3.now().UTC().Format(http.TimeFormat))
+	}
+
+	authStr := s3.v2StringToSign(req, req.Header, req.Header.Get("Date"))
+	auth := "AWS" + " " + creds.AccessId + ":" + v2Signature(creds.Secret, authStr)
+	req.Header.Set("Authorization", auth)
+}
+
+func v2Signature(secret, authStr string) string {
+	h := hmac.New(sha1.New, []byte(secret))
+	h.Write([]byte(authStr))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
-func (s3 *S3) signRequest(req *http.Request) {
+// v2StringToSign builds the Signature Version 2 string to sign for req, taking the x-amz-*
+// headers from header and using date in place of the Date header (presigned URLs put their
+// expiry time there). The query string of req is rewritten into sorted order along the way.
+func (s3 *S3) v2StringToSign(req *http.Request, header http.Header, date string) string {
 	amzHeaders := ""
-	resourceUrl, _ := url.Parse("/" + s3.bucket + req.URL.Path)
-	resource := resourceUrl.String()
+	resource := req.URL.EscapedPath()
+	if !s3.pathStyle && s3.bucket != "" {
+		resource = "/" + s3.bucket + resource
+	}
 
 	/* Ugh, AWS requires us to order the parameters in a specific ordering for
 	 * signing. Makes sense, but is annoying because a map does not have a defined
@@ -58,50 +255,64 @@ func (s3 *S3) signRequest(req *http.Request) {
 		sort.Strings(keys)
 
 		parts := []string{}
+		signedParts := []string{}
 
 		for _, key := range keys {
 			vals := query[key]
 
 			for _, val := range vals {
-				if val == "" {
-					parts = append(parts, url.QueryEscape(key))
+				part := url.QueryEscape(key)
+				if val != "" {
+					part = fmt.Sprintf("%s=%s", url.QueryEscape(key), url.QueryEscape(val))
+				}
+
+				parts = append(parts, part)
 
-				} else {
-					part := fmt.Sprintf("%s=%s", url.QueryEscape(key), url.QueryEscape(val))
-					parts = append(parts, part)
+				/* Only sub-resources are signed; ordinary parameters (like the
+				 * prefix of a listing) are left out of the string to sign */
+				if v2SubResources[key] {
+					signedParts = append(signedParts, part)
 				}
 			}
 		}
 
 		req.URL.RawQuery = strings.Join(parts, "&")
+
+		if len(signedParts) > 0 {
+			resource += "?" + strings.Join(signedParts, "&")
+		}
 	}
 
-	if req.URL.RawQuery != "" {
-		resource += "?" + req.URL.RawQuery
+	/* Every x-amz-* header has to be folded into the string to sign, with the names
+	 * lowercased and sorted, and multiple values joined with commas. */
+	amzKeys := []string{}
+	for k := range header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			amzKeys = append(amzKeys, lk)
+		}
 	}
 
-	if req.Header.Get("Date") == "" {
-		req.Header.Set("Date", time.Now().Format(time.RFC1123))
+	sort.Strings(amzKeys)
+
+	for _, k := range amzKeys {
+		vals := header[http.CanonicalHeaderKey(k)]
+		amzHeaders += k + ":" + strings.Join(vals, ",") + "\n"
 	}
 
-	authStr := strings.Join([]string{
+	return strings.Join([]string{
 		strings.TrimSpace(req.Method),
 		req.Header.Get("Content-MD5"),
 		req.Header.Get("Content-Type"),
-		req.Header.Get("Date"),
+		date,
 		amzHeaders + resource,
 	}, "\n")
-
-	h := hmac.New(sha1.New, []byte(s3.secret))
-	h.Write([]byte(authStr))
-
-	h64 := base64.StdEncoding.EncodeToString(h.Sum(nil))
-	auth := "AWS" + " " + s3.accessId + ":" + h64
-	req.Header.Set("Authorization", auth)
 }
 
+// resource returns the URL of the object at path, with the query parameters in values. Any byte of
+// path but a slash or an RFC 3986 unreserved character is percent-encoded, as AWS encodes paths
+// when signing them, so that any key can be used, and is signed as it is sent.
 func (s3 *S3) resource(path string, values url.Values) string {
-	tmp := fmt.Sprintf("https://%s/%s", s3.endpoint, path)
+	tmp := s3.baseURL() + uriEncode(path, false)
 
 	if values != nil {
 		tmp += "?" + values.Encode()
@@ -110,42 +321,491 @@ func (s3 *S3) resource(path string, values url.Values) string {
 	return tmp
 }
 
-func (s3 *S3) putMultipart(r io.Reader, size int64, path string, contentType string) (er error) {
-	mp, er := s3.StartMultipart(path)
+// SetHTTPClient sets the HTTP client used to send every request, which allows configuring
+// timeouts, proxies, connection pooling and TLS. Passing nil restores http.DefaultClient.
+func (s3 *S3) SetHTTPClient(client *http.Client) {
+	s3.client = client
+}
+
+// SetTLSConfig sets the TLS configuration used for HTTPS connections, such as a custom pool of
+// root CAs for a private S3-compatible service, client certificates, or InsecureSkipVerify for
+// test servers with self-signed certificates. Requests are always made over HTTPS unless an
+// http:// endpoint is set explicitly with SetEndpoint.
+//
+// The transport of the current HTTP client (see SetHTTPClient) is copied with config in place;
+// an error is returned if the client uses a custom RoundTripper, which has to be configured
+// directly instead.
+func (s3 *S3) SetTLSConfig(config *tls.Config) error {
+	base := s3.httpClient()
+
+	transport := http.DefaultTransport.(*http.Transport)
+	if base.Transport != nil {
+		var ok bool
+
+		if transport, ok = base.Transport.(*http.Transport); !ok {
+			return fmt.Errorf("s3: cannot set the TLS configuration of a custom http.RoundTripper")
+		}
+	}
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = config
+
+	client := *base
+	client.Transport = transport
+	s3.client = &client
+
+	return nil
+}
+
+func (s3 *S3) httpClient() *http.Client {
+	if s3.client == nil {
+		return http.DefaultClient
+	}
+
+	return s3.client
+}
+
+// do applies the default request options followed by opts to req, then signs and sends it. If
+// S3 responds with anything other than a 2xx status, the response is consumed and converted into
+// an *S3Error. When S3 redirects the request, whether temporarily (as it does for a while after
+// a bucket is created) or permanently (when the bucket is in another region), the request is
+// re-signed and sent to the endpoint named in the error, as are all subsequent requests unless
+// the redirect policy says otherwise (see SetRedirectPolicy). Failures that are likely to be
+// transient are retried according to the retry policy (see SetRetryPolicy).
+func (s3 *S3) do(req *http.Request, opts ...RequestOption) (*http.Response, error) {
+	allOpts := make([]RequestOption, 0, len(s3.defaultOpts)+len(opts))
+	allOpts = append(allOpts, s3.defaultOpts...)
+	allOpts = append(allOpts, opts...)
+	config := newRequestConfig(allOpts)
+
+	if s3.bucketErr != nil {
+		return nil, s3.bucketErr
+	}
+
+	req.Header.Set("User-Agent", s3.userAgent())
+	config.apply(req)
+	s3.applySigningHost(req)
+
+	if er := s3.checkLimits(req); er != nil {
+		return nil, er
+	}
+
+	policy := s3.retryPolicy()
+	redirects := 0
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		endSpan := s3.traceAttempt(req, attempt)
+
+		resp, er := s3.signAndSend(req)
+		if er == nil && config.embeddedErrors {
+			if er = embeddedError(resp); er != nil {
+				resp = nil
+			}
+		}
+
+		endSpan(resp, er)
+		s3.logAttempt(req, resp, er, attempt, start)
+
+		if er == nil {
+			config.receiveVersionId(resp.Header)
+			config.receiveMetadata(resp, nil, attempt)
+			return resp, nil
+		}
+
+		/* Redirects don't count as failed attempts; the request is simply sent again to
+		 * where S3 says the bucket is served from */
+		var s3er *S3Error
+		if errors.As(er, &s3er) && redirects < maxRedirects {
+			if s3.followRedirect(req, s3er) {
+				redirects++
+				attempt--
+				continue
+			}
+		}
+
+		if attempt >= policy.MaxAttempts || !IsTemporary(er) || !rewindBody(req) {
+			config.receiveMetadata(nil, er, attempt)
+			return nil, er
+		}
+
+		select {
+		case <-time.After(policy.delay(attempt)):
+		case <-req.Context().Done():
+			config.receiveMetadata(nil, er, attempt)
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// signAndSend makes a single attempt at sending req, failing over to the secondary credentials
+// (or a new directory bucket session) if need be, or correcting the clock (see SyncClock) if S3
+// says it is too far off.
+func (s3 *S3) signAndSend(req *http.Request) (*http.Response, error) {
+	if er := s3.signRequest(req); er != nil {
+		return nil, er
+	}
+
+	signedWith := s3.creds.peek().AccessId
+
+	resp, er := s3.send(req)
+	if er == nil {
+		return resp, nil
+	}
+
+	/* A directory bucket session can end before it expires, so one that is rejected is
+	 * replaced, and the request sent again with the new one */
+	var s3er *S3Error
+	if s3.express != nil && errors.As(er, &s3er) && (s3er.credentialsRejected() || s3er.awsCode() == "InvalidToken") {
+		s3.expireExpressSession()
+
+		if !rewindBody(req) {
+			return nil, er
+		}
+
+		if er := s3.signRequest(req); er != nil {
+			return nil, er
+		}
+
+		return s3.send(req)
+	}
+
+	/* If the credentials were rejected and there's a secondary pair to fall back to, the
+	 * request is retried with those, provided its body can be replayed */
+	if errors.As(er, &s3er) && s3er.credentialsRejected() && s3.failoverCredentials(signedWith) {
+		if !rewindBody(req) {
+			return nil, er
+		}
+
+		if er := s3.signRequest(req); er != nil {
+			return nil, er
+		}
+
+		return s3.send(req)
+	}
+
+	/* A request rejected for being dated too far from S3's clock is sent again dated by S3's
+	 * clock, as are all later requests; the Date header of the first is dropped so that a
+	 * Signature Version 2 request is dated afresh */
+	if errors.As(er, &s3er) && s3er.awsCode() == "RequestTimeTooSkewed" && s3.correctClock(s3er) {
+		if !rewindBody(req) {
+			return nil, er
+		}
+
+		req.Header.Del("Date")
+
+		if er := s3.signRequest(req); er != nil {
+			return nil, er
+		}
+
+		return s3.send(req)
+	}
+
+	return nil, er
+}
+
+// rewindBody prepares req to be sent again, reporting false if its body can't be replayed.
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+
+	if req.GetBody == nil {
+		return false
+	}
+
+	body, er := req.GetBody()
+	if er != nil {
+		return false
+	}
+
+	req.Body = body
+	return true
+}
+
+// send is like do, but sends req exactly as-is without signing it first. When S3 rejects a
+// request before its body has been sent, as it does with uploads it won't accept, the rest of the
+// body is abandoned rather than streamed to a server that has stopped reading it, and the error
+// S3 sent is returned rather than the broken connection.
+func (s3 *S3) send(req *http.Request) (*http.Response, error) {
+	release, er := s3.acquireSlot(req.Context())
+	if er != nil {
+		return nil, er
+	}
+
+	body := watchBody(req)
+
+	client := s3.sendClient()
+	start := time.Now()
+
+	resp, er := s3.sendTimed(client, req)
+	if er != nil && body.cutShort(er) && rewindBody(req) {
+		/* The transport loses the response if the connection is closed while the body is
+		 * still being written, so ask again with 100-continue, which has S3 respond before
+		 * any of the body is sent */
+		req.Header.Set("Expect", "100-continue")
+		body = watchBody(req)
+
+		resp, er = s3.sendTimed(client, req)
+	}
+
+	if er != nil {
+		release()
+		s3.observe(req, nil, er, start)
+		return nil, er
+	}
+
+	resp.Body = &slotBody{ReadCloser: resp.Body, release: release}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+
+		body.abandon()
+
+		er := wrapError(resp)
+
+		s3.account(req, nil)
+		s3.observe(req, resp, er, start)
+		return nil, er
+	}
+
+	s3.account(req, resp)
+	s3.observe(req, resp, nil, start)
+	return resp, nil
+}
+
+func (s3 *S3) putMultipart(ctx context.Context, r io.Reader, size int64, path string, contentType string, opts []RequestOption) (er error) {
+	mp, er := s3.startMultipart(ctx, path, s3.multipartHeader(contentType), append(opts, nestedAudit()))
 	if er != nil {
 		return er
 	}
 	defer func() {
 		if er != nil {
-			mp.Abort()
+			er = mp.abandon(er)
 		}
 	}()
 
-	var chunkSize int64 = 7 * 1024 * 1024
-	chunk := bytes.NewBuffer(make([]byte, chunkSize))
-	md5hash := md5.New()
+	mp.progress.setTotal(size)
+
+	if er := s3.uploadParts(mp, r, size); er != nil {
+		return er
+	}
+
+	return mp.Complete(contentType)
+}
+
+// putStream uploads everything that can be read from r with the multipart API, without knowing
+// its length in advance.
+func (s3 *S3) putStream(ctx context.Context, r io.Reader, path string, contentType string, opts []RequestOption) (er error) {
+	mp, er := s3.startMultipart(ctx, path, s3.multipartHeader(contentType), append(opts, nestedAudit()))
+	if er != nil {
+		return er
+	}
+	defer func() {
+		if er != nil {
+			er = mp.abandon(er)
+		}
+	}()
+
+	if er := s3.uploadParts(mp, r, -1); er != nil {
+		return er
+	}
+
+	return mp.Complete(contentType)
+}
+
+// uploadParts reads r in parts of the part size and adds them to mp, sending up to the upload
+// concurrency at once, until size bytes have been read or, if size is negative, r ends. The parts
+// are numbered in the order they were read, whatever order they finish in.
+func (s3 *S3) uploadParts(mp *S3Multipart, r io.Reader, size int64) error {
+	concurrency := s3.uploadConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type part struct {
+		number int
+		data   []byte
+		md5sum []byte
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+
+	/* Each worker holds one part while it is uploaded and one more is read ahead, so no more
+	 * than this many buffers are ever taken from the pool */
+	buffers := make(chan []byte, concurrency+1)
+	allocated := 0
+
+	parts := make(chan part)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for p := range parts {
+				er := mp.addPartAt(p.number, bytes.NewReader(p.data), int64(len(p.data)), p.md5sum)
+				buffers <- p.data[:cap(p.data)]
+
+				if er != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = er
+					}
+					errLock.Unlock()
+				}
+			}
+		}()
+	}
+
 	remaining := size
+	var readErr error
 
-	for ; remaining > 0; remaining -= chunkSize {
-		chunk.Reset()
-		md5hash.Reset()
+	for number := 1; size < 0 || remaining > 0 || number == 1; number++ {
+		errLock.Lock()
+		failed := firstErr != nil
+		errLock.Unlock()
 
-		if remaining < chunkSize {
+		if failed {
+			break
+		}
+
+		chunkSize := s3.uploadPartSize()
+		if size >= 0 && remaining < chunkSize {
 			chunkSize = remaining
 		}
+		remaining -= chunkSize
+
+		var buf []byte
+
+		select {
+		case buf = <-buffers:
+		default:
+			if allocated < cap(buffers) {
+				allocated++
+				buf = getPartBuffer(s3.uploadPartSize())
+			} else {
+				buf = <-buffers
+			}
+		}
 
-		wr := io.MultiWriter(chunk, md5hash)
+		n, er := io.ReadFull(r, buf[:chunkSize])
+		last := false
 
-		if _, er := io.CopyN(wr, r, chunkSize); er != nil {
-			return er
+		if er == io.EOF || er == io.ErrUnexpectedEOF {
+			if size >= 0 {
+				readErr = er
+				buffers <- buf
+				break
+			}
+
+			/* An empty stream is still uploaded as a single empty part */
+			if n == 0 && number > 1 {
+				buffers <- buf
+				break
+			}
+
+			last = true
+
+		} else if er != nil {
+			readErr = er
+			buffers <- buf
+			break
 		}
 
-		if er := mp.AddPart(chunk, chunkSize, md5hash.Sum(nil)); er != nil {
-			return er
+		mp.lock.Lock()
+		mp.etags = append(mp.etags, "")
+		mp.lock.Unlock()
+
+		md5sum := md5.Sum(buf[:n])
+		parts <- part{number: number, data: buf[:n], md5sum: md5sum[:]}
+
+		if last {
+			break
 		}
 	}
 
-	return mp.Complete(contentType)
+	close(parts)
+	wg.Wait()
+
+	close(buffers)
+	for buf := range buffers {
+		putPartBuffer(buf)
+	}
+
+	if readErr != nil {
+		return readErr
+	}
+
+	return firstErr
+}
+
+// SetPutBufferLimit sets the largest upload of unknown length (see Put) that will be buffered in
+// memory and sent as a single request. Passing 0 restores the default of 16MB.
+func (s3 *S3) SetPutBufferLimit(limit int64) {
+	s3.putBufferLimit = limit
+}
+
+// SetExpectContinue sets the smallest upload (by Put, or a part added to a multipart upload) that
+// is sent with an Expect: 100-continue header, which has S3 accept or reject the request before
+// any of its body is sent. When S3 is going to refuse an upload, because the credentials have
+// expired, the bucket is in another region or requests are being throttled, that is found out
+// after a round trip rather than after streaming the whole body. Passing 0 restores the default of
+// 2MB; a negative threshold never sends the header up front. The transport waits for S3's answer
+// for as long as its ExpectContinueTimeout (1s for http.DefaultTransport) allows; a transport
+// without one sends the body straight away regardless.
+func (s3 *S3) SetExpectContinue(threshold int64) {
+	s3.expectContinue = threshold
+}
+
+func (s3 *S3) expectContinueThreshold() int64 {
+	if s3.expectContinue == 0 {
+		return defaultExpectContinue
+	}
+
+	return s3.expectContinue
+}
+
+// expectContinueFor asks S3 to accept or reject req before its body is sent, if the body is size
+// bytes or more (see SetExpectContinue).
+func (s3 *S3) expectContinueFor(req *http.Request, size int64) {
+	if threshold := s3.expectContinueThreshold(); threshold > 0 && size >= threshold {
+		req.Header.Set("Expect", "100-continue")
+	}
+}
+
+// SetPartSize sets the size of the parts multipart uploads are split into by Put, PutStream,
+// PutReaderAt and PlanUpload, which must be from 5MB to 5GB. Larger parts allow larger objects,
+// since an upload has at most 10,000 parts, at the cost of holding more in memory and resending
+// more when a part fails. Passing 0 restores the default of 7MB.
+func (s3 *S3) SetPartSize(size int64) error {
+	if size != 0 && (size < minPartSize || size > maxPutSize) {
+		return fmt.Errorf("s3: part size %d is not from 5MB to 5GB", size)
+	}
+
+	s3.partSize = size
+	return nil
+}
+
+func (s3 *S3) uploadPartSize() int64 {
+	if s3.partSize == 0 {
+		return partSize
+	}
+
+	return s3.partSize
+}
+
+// SetUploadConcurrency sets how many parts of a multipart upload made by Put are sent at once.
+// Each part is buffered in memory while it is sent, so an upload holds at most n+1 parts (of 7MB,
+// unless SetPartSize says otherwise) in memory at a time; the buffers are pooled and reused by
+// later uploads. The default of 1 sends the parts one after another.
+func (s3 *S3) SetUploadConcurrency(n int) {
+	s3.uploadConcurrency = n
 }
 
 // Put uploads content to S3. The length of r must be passed as size. md5sum optionally contains
@@ -156,15 +816,130 @@ func (s3 *S3) putMultipart(r io.Reader, size int64, path string, contentType str
 // If the passed size exceeds 3GB, the multipart API is used, otherwise the single-request API is used.
 // It should be noted that the multipart API uploads in 7MB segments and computes checksums of each
 // one -- it does NOT use the passed md5sum, so don't bother with it if you're uploading huge files.
-func (s3 *S3) Put(r io.Reader, size int64, path string, md5sum []byte, contentType string) error {
-	if size > 3*1024*1024*1024 {
-		return s3.putMultipart(r, size, path, contentType)
+// The segments can be uploaded several at a time with SetUploadConcurrency. Any opts are applied
+// to the upload request (or the initiation of the multipart upload).
+//
+// If the length of r isn't known ahead of time, pass -1 as size. Put reads r into memory up to the
+// limit set by SetPutBufferLimit; if the content ends before then it is uploaded with a single
+// request, otherwise it is streamed to S3 with the multipart API (again ignoring md5sum).
+func (s3 *S3) Put(ctx context.Context, r io.Reader, size int64, path string, md5sum []byte, contentType string, opts ...RequestOption) (er error) {
+	defer func(start time.Time) {
+		s3.audit("Put", path, size, start, er)
+	}(time.Now())
+
+	ctx, endSpan := s3.startSpan(ctx, "Put", path)
+	defer func() {
+		endSpan(er)
+	}()
+
+	/* The size of the compressed content isn't known until it has been compressed */
+	if newRequestConfig(opts).gzip {
+		r, opts = gzipUpload(r, opts)
+		size, md5sum = -1, nil
+	}
+
+	if size < 0 {
+		limit := s3.putBufferLimit
+		if limit <= 0 {
+			limit = defaultPutBufferLimit
+		}
+
+		buf := putBuffers.Get().(*bytes.Buffer)
+		defer func() {
+			buf.Reset()
+			putBuffers.Put(buf)
+		}()
+
+		n, er := io.CopyN(buf, r, limit+1)
+		if er != nil && er != io.EOF {
+			return er
+		}
+
+		if n > limit {
+			return s3.putStream(ctx, io.MultiReader(buf, r), path, contentType, opts)
+		}
+
+		r = bytes.NewReader(buf.Bytes())
+		size = n
+	}
+
+	return s3.putSized(ctx, r, size, path, md5sum, contentType, opts)
+}
+
+// PutStream uploads everything that can be read from r to path, for content whose length isn't
+// known in advance, such as the output of a compressor or another network stream. The content is
+// read in parts of 7MB: if it ends within the first, it is uploaded with a single request,
+// otherwise each part is sent with the multipart API as it is read (up to the number set with
+// SetUploadConcurrency at once), so memory use stays bounded however long the stream is. Any
+// opts are applied to the upload request (or the initiation of the multipart upload).
+//
+// Unlike Put with a size of -1, PutStream never buffers more than a part before it starts
+// uploading.
+func (s3 *S3) PutStream(ctx context.Context, r io.Reader, path, contentType string, opts ...RequestOption) (er error) {
+	var size int64 = -1
+
+	defer func(start time.Time) {
+		s3.audit("PutStream", path, size, start, er)
+	}(time.Now())
+
+	ctx, endSpan := s3.startSpan(ctx, "PutStream", path)
+	defer func() {
+		endSpan(er)
+	}()
+
+	if newRequestConfig(opts).gzip {
+		r, opts = gzipUpload(r, opts)
+	}
+
+	buf := getPartBuffer(s3.uploadPartSize())
+	defer putPartBuffer(buf)
+
+	n, er := io.ReadFull(r, buf)
+	if er == io.EOF || er == io.ErrUnexpectedEOF {
+		size = int64(n)
+		return s3.putSized(ctx, bytes.NewReader(buf[:n]), size, path, nil, contentType, opts)
+
+	} else if er != nil {
+		return er
+	}
+
+	return s3.putStream(ctx, io.MultiReader(bytes.NewReader(buf), r), path, contentType, opts)
+}
+
+// putSized uploads size bytes read from r, using the multipart API if there are too many for a
+// single request.
+func (s3 *S3) putSized(ctx context.Context, r io.Reader, size int64, path string, md5sum []byte, contentType string, opts []RequestOption) error {
+	if s3.checksum != nil {
+		checksumOpt, er := s3.checksumHeader(r, size)
+		if er != nil {
+			return er
+		}
+
+		opts = append(append([]RequestOption{}, opts...), checksumOpt...)
+	}
+
+	if size > multipartThreshold {
+		return s3.putMultipart(ctx, r, size, path, contentType, opts)
 	}
 
-	req, er := http.NewRequest("PUT", s3.resource(path, nil), r)
+	/* Streamed uploads carry their checksum in a trailer, computed as they are sent */
+	var checksum string
+	if s3.checksumAlgorithm != "" && !s3.streamsUploads() {
+		var er error
+		if checksum, er = computeChecksum(s3.checksumAlgorithm, r, size); er != nil {
+			return er
+		}
+	}
+
+	req, er := http.NewRequestWithContext(ctx, "PUT", s3.resource(path, nil), r)
 	if er != nil {
 		return er
 	}
+	makeRewindable(req, r)
+
+	if checksum != "" {
+		req.Header.Set(checksumHeaderName(s3.checksumAlgorithm), checksum)
+	}
 
 	if md5sum != nil {
 		md5value := base64.StdEncoding.EncodeToString(md5sum)
@@ -179,180 +954,184 @@ func (s3 *S3) Put(r io.Reader, size int64, path string, md5sum []byte, contentTy
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", size))
 	req.Header.Set("Host", req.URL.Host)
 	req.ContentLength = size
+	trackBody(req, newProgress(opts, size))
+	s3.streamBody(req, size, s3.checksumAlgorithm)
+	s3.expectContinueFor(req, size)
 
-	s3.signRequest(req)
-
-	resp, er := http.DefaultClient.Do(req)
+	resp, er := s3.do(req, opts...)
 	if er != nil {
 		return er
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		er := wrapError(resp)
-
-		if newEndpoint := er.newEndpoint(); newEndpoint != "" {
-			s3.endpoint = newEndpoint
-			er.ShouldRetry = true
-		}
-
-		return er
-	}
+	resp.Body.Close()
 
 	return nil
 }
 
 // Get fetches content from S3, returning both a ReadCloser for the data and the HTTP headers
 // returned by S3. You can use the headers to extract the Content-Type that the data was sent
-// with.
-func (s3 *S3) Get(path string) (io.ReadCloser, http.Header, error) {
-	req, er := http.NewRequest("GET", s3.resource(path, nil), nil)
+// with. Any opts are applied to the request.
+//
+// Objects stored with "Content-Encoding: gzip", such as those uploaded with WithGzip, are
+// decompressed transparently, unless the Accept-Encoding header is set with WithAcceptEncoding.
+// When a checksum hash or algorithm is set with SetChecksumHash or SetChecksumAlgorithm, the
+// stored bytes are downloaded, verified and then decompressed.
+func (s3 *S3) Get(ctx context.Context, path string, opts ...RequestOption) (io.ReadCloser, http.Header, error) {
+	ctx, endSpan := s3.startSpan(ctx, "Get", path)
+
+	body, header, er := s3.get(ctx, path, opts)
+	endSpan(er)
+
+	return body, header, er
+}
+
+// get implements Get.
+func (s3 *S3) get(ctx context.Context, path string, opts []RequestOption) (io.ReadCloser, http.Header, error) {
+	req, er := http.NewRequestWithContext(ctx, "GET", s3.resource(path, nil), nil)
 	if er != nil {
 		return nil, http.Header{}, er
 	}
 
-	s3.signRequest(req)
+	/* The checksum covers the stored bytes, so they mustn't be decompressed on the way */
+	if s3.checksum != nil || s3.checksumAlgorithm != "" {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
 
-	resp, er := http.DefaultClient.Do(req)
+	if s3.checksumAlgorithm != "" {
+		req.Header.Set("x-amz-checksum-mode", "ENABLED")
+	}
+
+	resp, er := s3.do(req, opts...)
 	if er != nil {
 		return nil, http.Header{}, er
 	}
 
-	if resp.StatusCode != 200 {
-		er := wrapError(resp)
+	resp.Body = trackResponse(resp.Body, newProgress(opts, resp.ContentLength))
 
-		if newEndpoint := er.newEndpoint(); newEndpoint != "" {
-			s3.endpoint = newEndpoint
-			er.ShouldRetry = true
+	config := newRequestConfig(opts)
+	if max := config.maxSize; max > 0 {
+		if resp.Body, er = limitBody(resp, path, max); er != nil {
+			return nil, resp.Header, er
 		}
+	}
 
-		return nil, http.Header{}, er
+	body := s3.verifyObjectChecksum(resp, s3.verifyChecksum(resp, path), path)
+
+	/* Only decompress what the transport would have, had the checksums not needed the
+	 * stored bytes */
+	if config.header.Get("Accept-Encoding") == "" {
+		if body, er = gunzipBody(resp, body, path); er != nil {
+			return nil, resp.Header, er
+		}
 	}
 
-	return resp.Body, resp.Header, nil
+	return body, resp.Header, nil
 }
 
 // Head is similar to Get, but returns only the response headers. The response body is not
 // transferred across the network. This is useful for checking if a file exists remotely,
-// and what headers it was configured with.
-func (s3 *S3) Head(path string) (http.Header, error) {
-	req, er := http.NewRequest("HEAD", s3.resource(path, nil), nil)
-	if er != nil {
-		return http.Header{}, er
-	}
+// and what headers it was configured with. Any opts are applied to the request.
+func (s3 *S3) Head(ctx context.Context, path string, opts ...RequestOption) (http.Header, error) {
+	ctx, endSpan := s3.startSpan(ctx, "Head", path)
+
+	header, er := s3.head(ctx, path, opts)
+	endSpan(er)
 
-	s3.signRequest(req)
+	return header, er
+}
 
-	resp, er := http.DefaultClient.Do(req)
+// head implements Head.
+func (s3 *S3) head(ctx context.Context, path string, opts []RequestOption) (http.Header, error) {
+	req, er := http.NewRequestWithContext(ctx, "HEAD", s3.resource(path, nil), nil)
 	if er != nil {
 		return http.Header{}, er
 	}
 
-	if resp.StatusCode != 200 {
-		er := wrapError(resp)
-
-		if newEndpoint := er.newEndpoint(); newEndpoint != "" {
-			s3.endpoint = newEndpoint
-			er.ShouldRetry = true
-		}
-
+	resp, er := s3.do(req, opts...)
+	if er != nil {
 		return http.Header{}, er
 	}
+	resp.Body.Close()
 
 	return resp.Header, nil
 }
 
 // Test attempts to write and read back a single, short file from S3. It is intended to be
-// used to test runtime configuration to fail quickly when credentials are invalid.
-func (s3 *S3) Test() error {
-	testString := fmt.Sprintf("roundtrip-test-%d", rand.Int())
-	testReader := strings.NewReader(testString)
-
-	if er := s3.Put(testReader, int64(testReader.Len()), "writetest", nil, "text/x-empty"); er != nil {
-		if s3er, ok := er.(*S3Error); ok {
-			if s3er.ShouldRetry {
-				return s3.Test()
-			}
-		}
-
-		return er
-	}
+// used to test runtime configuration to fail quickly when credentials are invalid. It is Probe
+// with an instance name made from the host name and process ID, and probe objects expiring after
+// an hour.
+func (s3 *S3) Test(ctx context.Context) error {
+	return s3.Probe(ctx, defaultProbeInstance(), time.Hour)
+}
 
-	actualReader, header, er := s3.Get("writetest")
-	if er != nil {
-		if s3er, ok := er.(*S3Error); ok {
-			if s3er.ShouldRetry {
-				return s3.Test()
-			}
-		}
+// StartMultipart initiates a multipart upload. Any opts are applied to the initiation request,
+// which is where S3 expects settings that apply to the whole object.
+//
+// ctx governs the whole upload, not just its initiation: every part is sent with it, and if it
+// is cancelled before the upload is completed, the upload is aborted on S3 in the background (so
+// that the parts already sent don't keep accruing storage charges). Pass KeepOnCancel to leave
+// the parts in place instead, for uploads that will be resumed later.
+func (s3 *S3) StartMultipart(ctx context.Context, path string, opts ...RequestOption) (*S3Multipart, error) {
+	return s3.startMultipart(ctx, path, s3.multipartHeader(""), opts)
+}
 
-		return er
+// multipartHeader returns the headers to initiate a multipart upload of an object of contentType
+// with, which S3 takes the object's Content-Type from; the one passed to Complete is ignored.
+func (s3 *S3) multipartHeader(contentType string) http.Header {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
 	}
-	defer actualReader.Close()
 
-	actualBytes, er := ioutil.ReadAll(actualReader)
-	if er != nil {
-		return er
+	if s3.checksumAlgorithm != "" {
+		header.Set("x-amz-checksum-algorithm", s3.checksumAlgorithm)
 	}
 
-	if string(actualBytes) != testString {
-		return fmt.Errorf("String read back from S3 was different than what we put there.")
-	}
+	return header
+}
 
-	if header.Get("Content-Type") != "text/x-empty" {
-		return fmt.Errorf("Content served back from S3 had a different Content-Type than what we put there")
-	}
+// startMultipart initiates a multipart upload, sending any headers in header along with the
+// initiation request (which is where S3 expects per-object settings like Content-Type).
+func (s3 *S3) startMultipart(ctx context.Context, path string, header http.Header, opts []RequestOption) (mp *S3Multipart, er error) {
+	defer func(start time.Time) {
+		s3.auditNested("StartMultipart", path, 0, start, er, newRequestConfig(opts).nestedAudit)
+	}(time.Now())
 
-	return nil
-}
+	/* The upload itself is governed by ctx, so its parts aren't traced as part of starting it */
+	spanCtx, endSpan := s3.startSpan(ctx, "StartMultipart", path)
+	defer func() {
+		endSpan(er)
+	}()
 
-// StartMultipart initiates a multipart upload.
-func (s3 *S3) StartMultipart(path string) (*S3Multipart, error) {
-	req, er := http.NewRequest("POST", s3.resource(path, nil)+"?uploads", nil)
+	req, er := http.NewRequestWithContext(spanCtx, "POST", s3.resource(path, nil)+"?uploads", nil)
 	if er != nil {
 		return nil, er
 	}
 
-	req.Header.Set("Host", req.URL.Host)
+	for k, vals := range header {
+		req.Header[k] = vals
+	}
 
-	s3.signRequest(req)
+	req.Header.Set("Host", req.URL.Host)
 
-	resp, er := http.DefaultClient.Do(req)
+	resp, er := s3.do(req, opts...)
 	if er != nil {
 		return nil, er
 	}
 	defer resp.Body.Close()
 
-	xmlBytes, er := ioutil.ReadAll(resp.Body)
+	xmlBytes, er := io.ReadAll(resp.Body)
 	if er != nil {
 		return nil, er
 	}
 
-	if resp.StatusCode != 200 {
-		er := wrapError(resp)
-
-		if newEndpoint := er.newEndpoint(); newEndpoint != "" {
-			s3.endpoint = newEndpoint
-			er.ShouldRetry = true
-		}
-
-		return nil, er
-	}
-
 	var xmlResp s3multipartResp
 	if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
 		return nil, er
 	}
 
-	mp := &S3Multipart{
-		uploadId: xmlResp.UploadId,
-		key:      xmlResp.Key,
-		s3:       s3,
-	}
-
-	runtime.SetFinalizer(mp, func(mp *S3Multipart) {
-		mp.Abort()
-	})
+	/* Every part must then carry a checksum computed with the algorithm the upload was started with */
+	mp = newMultipart(ctx, s3, xmlResp.Key, xmlResp.UploadId, opts)
+	mp.checksumAlgorithm = strings.ToUpper(req.Header.Get("x-amz-checksum-algorithm"))
 
 	return mp, nil
 }
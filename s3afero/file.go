@@ -0,0 +1,268 @@
+package s3afero
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lye/s3/v2"
+)
+
+var errClosed = errors.New("s3afero: file already closed")
+
+// file implements afero.File by holding the whole object in memory.
+type file struct {
+	fs       *Fs
+	name     string
+	key      string
+	data     []byte
+	offset   int64
+	modTime  time.Time
+	writable bool
+	dirty    bool
+	dir      bool
+	closed   bool
+
+	entries []os.FileInfo // Directory entries not yet returned by Readdir.
+	listed  bool
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	n, er := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+
+	if er == io.EOF && n > 0 {
+		er = nil
+	}
+
+	return n, er
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, errClosed
+	}
+
+	if f.dir {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, errClosed
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	default:
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	n, er := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, er
+}
+
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, errClosed
+	}
+
+	if !f.writable {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+
+	if end := off + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+
+	copy(f.data[off:], p)
+	f.dirty = true
+
+	return len(p), nil
+}
+
+func (f *file) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *file) Truncate(size int64) error {
+	if f.closed {
+		return errClosed
+	}
+
+	if !f.writable {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrPermission}
+	}
+
+	if size < int64(len(f.data)) {
+		f.data = f.data[:size]
+	} else {
+		f.data = append(f.data, make([]byte, size-int64(len(f.data)))...)
+	}
+
+	f.dirty = true
+	return nil
+}
+
+// Sync uploads the file if it has been modified since it was opened or last synced.
+func (f *file) Sync() error {
+	if f.closed {
+		return errClosed
+	}
+
+	if !f.dirty {
+		return nil
+	}
+
+	r := bytes.NewReader(f.data)
+
+	if er := f.fs.s3.Put(context.Background(), r, int64(len(f.data)), f.key, nil, ""); er != nil {
+		return &os.PathError{Op: "sync", Path: f.name, Err: er}
+	}
+
+	f.dirty = false
+	f.modTime = time.Now()
+
+	return nil
+}
+
+// Close uploads the file if it has been modified, and releases its buffer.
+func (f *file) Close() error {
+	if f.closed {
+		return errClosed
+	}
+
+	er := f.Sync()
+
+	f.closed = true
+	f.data = nil
+
+	return er
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	if f.closed {
+		return nil, errClosed
+	}
+
+	return &fileInfo{
+		name:    pathpkg.Base(f.name),
+		size:    int64(len(f.data)),
+		modTime: f.modTime,
+		dir:     f.dir,
+	}, nil
+}
+
+// Readdir returns information about the entries in a directory. Sub-directories are
+// reported for each common prefix one level down.
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	if f.closed {
+		return nil, errClosed
+	}
+
+	if !f.dir {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+
+	if !f.listed {
+		prefix := f.key
+		if prefix != "" {
+			prefix += "/"
+		}
+
+		objects, prefixes, er := f.fs.s3.List(context.Background(), prefix, "/")
+		if er != nil {
+			return nil, &os.PathError{Op: "readdir", Path: f.name, Err: er}
+		}
+
+		for _, obj := range objects {
+			f.entries = append(f.entries, objectInfo(obj))
+		}
+
+		for _, dir := range prefixes {
+			name := pathpkg.Base(strings.TrimSuffix(dir, "/"))
+			f.entries = append(f.entries, &fileInfo{name: name, dir: true})
+		}
+
+		sort.Slice(f.entries, func(i, j int) bool {
+			return f.entries[i].Name() < f.entries[j].Name()
+		})
+
+		f.listed = true
+	}
+
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+
+	entries := f.entries[:count]
+	f.entries = f.entries[count:]
+
+	return entries, nil
+}
+
+func (f *file) Readdirnames(n int) ([]string, error) {
+	entries, er := f.Readdir(n)
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+
+	return names, er
+}
+
+func objectInfo(obj s3.ObjectSummary) *fileInfo {
+	return &fileInfo{
+		name:    pathpkg.Base(obj.Key),
+		size:    obj.Size,
+		modTime: obj.LastModified,
+	}
+}
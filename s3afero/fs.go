@@ -0,0 +1,261 @@
+// Package s3afero adapts an S3 bucket to the afero.Fs interface, so that applications written
+// against afero can use S3 as their filesystem:
+//
+//	var fs afero.Fs = s3afero.New(s3.NewS3(bucket, accessId, secret))
+//
+// S3 has no directories, so they are emulated: a "directory" exists whenever some key begins
+// with its name followed by a slash, Mkdir and MkdirAll do nothing, and permissions, ownership
+// and timestamps cannot be changed. Files are buffered in memory, being downloaded in full
+// when opened for reading and uploaded in full when closed (or synced) after writing. Since
+// afero.Fs has no notion of contexts, every request is made with context.Background().
+//
+// Being a separate module, github.com/lye/s3/v2/s3afero, it leaves afero out of the dependencies
+// of programs that use the s3 package alone.
+package s3afero
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lye/s3/v2"
+	"github.com/spf13/afero"
+)
+
+// ErrNotSupported is returned for operations S3 has no equivalent for.
+var ErrNotSupported = errors.New("s3afero: operation not supported by S3")
+
+// Fs implements afero.Fs on top of an S3 bucket.
+type Fs struct {
+	s3 *s3.S3
+}
+
+var _ afero.Fs = (*Fs)(nil)
+
+// New returns an afero.Fs backed by the bucket that client operates on.
+func New(client *s3.S3) *Fs {
+	return &Fs{s3: client}
+}
+
+// key converts a filesystem path into an object key.
+func key(name string) string {
+	return strings.TrimPrefix(pathpkg.Clean("/"+name), "/")
+}
+
+func isNotFound(er error) bool {
+	var s3er *s3.S3Error
+	return errors.As(er, &s3er) && s3er.Code == http.StatusNotFound
+}
+
+// Name returns the name of the filesystem.
+func (fs *Fs) Name() string {
+	return "s3"
+}
+
+// Create creates (or truncates) the named file, which is uploaded when it is closed.
+func (fs *Fs) Create(name string) (afero.File, error) {
+	return fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// Mkdir does nothing, since directories in S3 exist implicitly.
+func (fs *Fs) Mkdir(name string, perm os.FileMode) error {
+	return nil
+}
+
+// MkdirAll does nothing, since directories in S3 exist implicitly.
+func (fs *Fs) MkdirAll(path string, perm os.FileMode) error {
+	return nil
+}
+
+// Open opens the named file (or directory) for reading.
+func (fs *Fs) Open(name string) (afero.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file. Files opened with O_CREATE may be written to, and are uploaded
+// when closed; otherwise the file must already exist, and its content is downloaded.
+func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	k := key(name)
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	f := &file{fs: fs, name: name, key: k, writable: writable}
+
+	if k == "" && !writable {
+		f.dir = true
+		return f, nil
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		f.dirty = true
+		return f, nil
+	}
+
+	r, header, er := fs.s3.Get(context.Background(), k)
+	if isNotFound(er) {
+		if flag&os.O_CREATE != 0 {
+			f.dirty = true
+			return f, nil
+		}
+
+		if !writable && fs.isDir(k) {
+			f.dir = true
+			return f, nil
+		}
+
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+
+	} else if er != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: er}
+	}
+	defer r.Close()
+
+	if f.data, er = io.ReadAll(r); er != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: er}
+	}
+
+	f.modTime, _ = http.ParseTime(header.Get("Last-Modified"))
+
+	if flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.data))
+	}
+
+	return f, nil
+}
+
+// isDir reports whether any keys exist below k.
+func (fs *Fs) isDir(k string) bool {
+	if k == "" {
+		return true
+	}
+
+	found := false
+
+	fs.s3.Walk(context.Background(), k+"/", func(s3.ObjectSummary) error {
+		found = true
+		return s3.StopWalk
+	}, s3.ListPageSize(1))
+
+	return found
+}
+
+// Remove deletes the named file.
+func (fs *Fs) Remove(name string) error {
+	if er := fs.s3.Delete(context.Background(), key(name)); er != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: er}
+	}
+
+	return nil
+}
+
+// RemoveAll deletes the named file along with everything beneath it.
+func (fs *Fs) RemoveAll(path string) error {
+	k := key(path)
+	keys := []string{}
+
+	if k != "" {
+		keys = append(keys, k)
+		k += "/"
+	}
+
+	er := fs.s3.Walk(context.Background(), k, func(obj s3.ObjectSummary) error {
+		keys = append(keys, obj.Key)
+		return nil
+	})
+	if er != nil {
+		return &os.PathError{Op: "removeall", Path: path, Err: er}
+	}
+
+	results, er := fs.s3.DeleteMulti(context.Background(), keys)
+	if er != nil {
+		return &os.PathError{Op: "removeall", Path: path, Err: er}
+	}
+
+	for _, result := range results {
+		if !result.Deleted {
+			return &os.PathError{Op: "removeall", Path: result.Key, Err: errors.New(result.Message)}
+		}
+	}
+
+	return nil
+}
+
+// Rename moves a file with a server-side copy followed by a delete. Directories cannot be
+// renamed.
+func (fs *Fs) Rename(oldname, newname string) error {
+	if er := fs.s3.Move(context.Background(), key(oldname), key(newname)); er != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: er}
+	}
+
+	return nil
+}
+
+// Stat returns information about the named file or directory.
+func (fs *Fs) Stat(name string) (os.FileInfo, error) {
+	k := key(name)
+
+	header, er := fs.s3.Head(context.Background(), k)
+	if isNotFound(er) || k == "" {
+		if fs.isDir(k) {
+			return &fileInfo{name: pathpkg.Base(name), dir: true}, nil
+		}
+
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+
+	} else if er != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: er}
+	}
+
+	return headerInfo(name, header), nil
+}
+
+// Chmod is not supported.
+func (fs *Fs) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: ErrNotSupported}
+}
+
+// Chown is not supported.
+func (fs *Fs) Chown(name string, uid, gid int) error {
+	return &os.PathError{Op: "chown", Path: name, Err: ErrNotSupported}
+}
+
+// Chtimes is not supported.
+func (fs *Fs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: ErrNotSupported}
+}
+
+// fileInfo implements os.FileInfo for objects and emulated directories.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func headerInfo(name string, header http.Header) *fileInfo {
+	info := &fileInfo{name: pathpkg.Base(name)}
+
+	info.size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	info.modTime, _ = http.ParseTime(header.Get("Last-Modified"))
+
+	return info
+}
+
+func (info *fileInfo) Name() string       { return info.name }
+func (info *fileInfo) Size() int64        { return info.size }
+func (info *fileInfo) ModTime() time.Time { return info.modTime }
+func (info *fileInfo) IsDir() bool        { return info.dir }
+func (info *fileInfo) Sys() interface{}   { return nil }
+
+func (info *fileInfo) Mode() os.FileMode {
+	if info.dir {
+		return os.ModeDir | 0755
+	}
+
+	return 0644
+}
@@ -0,0 +1,198 @@
+package s3afero
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lye/s3/v2"
+	"github.com/lye/s3/v2/s3test"
+	"github.com/spf13/afero"
+)
+
+func newFs(t *testing.T) (*Fs, *s3test.Server) {
+	srv := s3test.NewServer("bucket")
+	t.Cleanup(srv.Close)
+
+	client, er := s3.New("bucket", s3.WithCredentials("id", "secret", ""), s3.WithEndpoint(srv.URL), s3.WithPathStyle())
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	return New(client), srv
+}
+
+func TestFs(t *testing.T) {
+	fs, srv := newFs(t)
+
+	if er := afero.WriteFile(fs, "/docs/guide.txt", []byte("guide"), 0644); er != nil {
+		t.Fatal(er)
+	}
+
+	if data, ok := srv.Object("bucket", "docs/guide.txt"); !ok || string(data) != "guide" {
+		t.Fatalf("Uploaded %q", data)
+	}
+
+	srv.PutObject("bucket", "docs/api/index.html", []byte("<h1>API</h1>"))
+	srv.PutObject("bucket", "readme", []byte("readme"))
+
+	if data, er := afero.ReadFile(fs, "docs/guide.txt"); er != nil || string(data) != "guide" {
+		t.Fatalf("Read %q: %v", data, er)
+	}
+
+	info, er := fs.Stat("docs/guide.txt")
+	if er != nil || info.IsDir() || info.Size() != 5 || info.Name() != "guide.txt" || info.ModTime().IsZero() {
+		t.Fatalf("Stat the file as %+v: %v", info, er)
+	}
+
+	for _, dir := range []string{"docs", "/docs/api/", "/"} {
+		if info, er := fs.Stat(dir); er != nil || !info.IsDir() {
+			t.Fatalf("Stat the directory %s as %+v: %v", dir, info, er)
+		}
+	}
+
+	if _, er := fs.Stat("missing"); !errors.Is(er, os.ErrNotExist) {
+		t.Fatalf("Stat a missing file: %v", er)
+	}
+
+	if _, er := fs.Open("docs/missing.txt"); !errors.Is(er, os.ErrNotExist) {
+		t.Fatalf("Opened a missing file: %v", er)
+	}
+
+	names, er := afero.ReadDir(fs, "docs")
+	if er != nil || len(names) != 2 || names[0].Name() != "api" || !names[0].IsDir() || names[1].Name() != "guide.txt" || names[1].Size() != 5 {
+		t.Fatalf("Read the directory as %v: %v", names, er)
+	}
+
+	if er := fs.Rename("readme", "docs/readme"); er != nil {
+		t.Fatal(er)
+	}
+
+	if _, ok := srv.Object("bucket", "readme"); ok {
+		t.Fatal("Renaming left the old object")
+	}
+
+	if er := fs.Remove("docs/readme"); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := fs.RemoveAll("docs"); er != nil {
+		t.Fatal(er)
+	}
+
+	if keys := srv.Keys("bucket"); len(keys) != 0 {
+		t.Fatalf("RemoveAll left %v", keys)
+	}
+
+	if er := fs.Chmod("docs", 0700); !errors.Is(er, ErrNotSupported) {
+		t.Fatalf("Changed the mode: %v", er)
+	}
+}
+
+func TestFile(t *testing.T) {
+	fs, srv := newFs(t)
+
+	srv.PutObject("bucket", "log", []byte("0123456789"))
+
+	f, er := fs.Open("log")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	buf := make([]byte, 4)
+	if n, er := f.ReadAt(buf, 8); n != 2 || er != io.EOF || string(buf[:n]) != "89" {
+		t.Fatalf("Read %q at the end: %v", buf[:n], er)
+	}
+
+	if pos, er := f.Seek(-3, io.SeekEnd); er != nil || pos != 7 {
+		t.Fatalf("Sought to %d: %v", pos, er)
+	}
+
+	if rest, er := io.ReadAll(f); er != nil || string(rest) != "789" {
+		t.Fatalf("Read %q: %v", rest, er)
+	}
+
+	if _, er := f.Write([]byte("x")); !errors.Is(er, os.ErrPermission) {
+		t.Fatalf("Wrote to a file opened for reading: %v", er)
+	}
+
+	if er := f.Close(); er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := f.Read(buf); er == nil {
+		t.Fatal("Read a closed file")
+	}
+
+	/* Appending downloads the object, and uploads it whole on closing */
+	f, er = fs.OpenFile("log", os.O_WRONLY|os.O_APPEND, 0644)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	f.WriteString("abc")
+
+	if data, _ := srv.Object("bucket", "log"); string(data) != "0123456789" {
+		t.Fatalf("Uploaded %q before closing", data)
+	}
+
+	if er := f.Close(); er != nil {
+		t.Fatal(er)
+	}
+
+	if data, _ := srv.Object("bucket", "log"); string(data) != "0123456789abc" {
+		t.Fatalf("Uploaded %q", data)
+	}
+
+	f, er = fs.OpenFile("log", os.O_RDWR, 0644)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if er := f.Truncate(4); er != nil {
+		t.Fatal(er)
+	}
+
+	if er := f.Sync(); er != nil {
+		t.Fatal(er)
+	}
+
+	if data, _ := srv.Object("bucket", "log"); string(data) != "0123" {
+		t.Fatalf("Synced %q", data)
+	}
+
+	f.Close()
+
+	/* A directory is read a few entries at a time */
+	for _, key := range []string{"dir/a", "dir/b", "dir/c/d"} {
+		srv.PutObject("bucket", key, []byte(key))
+	}
+
+	dir, er := fs.Open("dir")
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer dir.Close()
+
+	names := []string{}
+	for {
+		batch, er := dir.Readdirnames(2)
+		names = append(names, batch...)
+
+		if er == io.EOF {
+			break
+		} else if er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Fatalf("Read the directory as %v", names)
+	}
+
+	if _, er := dir.Read(buf); er == nil {
+		t.Fatal("Read a directory")
+	}
+}
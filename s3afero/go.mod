@@ -0,0 +1,12 @@
+module github.com/lye/s3/v2/s3afero
+
+go 1.23.0
+
+require (
+	github.com/lye/s3/v2 v2.0.0
+	github.com/spf13/afero v1.15.0
+)
+
+require golang.org/x/text v0.28.0 // indirect
+
+replace github.com/lye/s3/v2 => ../
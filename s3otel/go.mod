@@ -0,0 +1,11 @@
+module github.com/lye/s3/v2/s3otel
+
+go 1.23.0
+
+require (
+	github.com/lye/s3/v2 v2.0.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+replace github.com/lye/s3/v2 => ../
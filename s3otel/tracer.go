@@ -0,0 +1,58 @@
+// Package s3otel records the spans of an S3's operations with OpenTelemetry:
+//
+//	client.SetTracer(s3otel.New(otel.Tracer("github.com/lye/s3")))
+//
+// It is kept in a module of its own, github.com/lye/s3/v2/s3otel, so that OpenTelemetry is only
+// a dependency of the programs using it.
+package s3otel
+
+import (
+	"context"
+
+	"github.com/lye/s3/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer implements s3.Tracer with an OpenTelemetry tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ s3.Tracer = (*Tracer)(nil)
+
+// New returns a Tracer starting spans with tracer. They are of the client kind, since each
+// stands for calls to S3.
+func New(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// Start implements s3.Tracer.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, s3.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, otelSpan{span}
+}
+
+// otelSpan implements s3.Span with an OpenTelemetry span.
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	}
+}
+
+func (s otelSpan) End(er error) {
+	if er != nil {
+		s.span.RecordError(er)
+		s.span.SetStatus(codes.Error, er.Error())
+	}
+
+	s.span.End()
+}
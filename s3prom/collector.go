@@ -0,0 +1,97 @@
+// Package s3prom exports the metrics of the requests an S3 sends to Prometheus:
+//
+//	collector := s3prom.New("myapp")
+//	prometheus.MustRegister(collector)
+//	client.SetMetricsCollector(collector)
+//
+// Every metric is labelled with the S3 API operation, such as "GetObject" or "UploadPart":
+//
+//	<namespace>_s3_requests_total              requests sent, including retries
+//	<namespace>_s3_errors_total                requests that failed, also labelled by error code
+//	<namespace>_s3_request_duration_seconds    histogram of the time until the response arrived
+//	<namespace>_s3_uploaded_bytes_total        content uploaded
+//	<namespace>_s3_downloaded_bytes_total      content downloaded
+//
+// The package is a module of its own, github.com/lye/s3/v2/s3prom, so that only programs that
+// import it depend on the Prometheus client.
+package s3prom
+
+import (
+	"github.com/lye/s3/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is both an s3.MetricsCollector and a prometheus.Collector. One may be shared by
+// several S3s.
+type Collector struct {
+	requests   *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	uploaded   *prometheus.CounterVec
+	downloaded *prometheus.CounterVec
+}
+
+var _ s3.MetricsCollector = (*Collector)(nil)
+var _ prometheus.Collector = (*Collector)(nil)
+
+// New returns a Collector whose metrics are named within namespace, which may be empty.
+func New(namespace string) *Collector {
+	opts := func(name, help string) prometheus.Opts {
+		return prometheus.Opts{Namespace: namespace, Subsystem: "s3", Name: name, Help: help}
+	}
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts(opts("requests_total",
+			"S3 requests sent, including retries.")), []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts(opts("errors_total",
+			"S3 requests that failed, by error code.")), []string{"operation", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "s3",
+			Name:      "request_duration_seconds",
+			Help:      "Time until the responses to S3 requests arrived.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"operation"}),
+		uploaded: prometheus.NewCounterVec(prometheus.CounterOpts(opts("uploaded_bytes_total",
+			"Bytes of content uploaded to S3.")), []string{"operation"}),
+		downloaded: prometheus.NewCounterVec(prometheus.CounterOpts(opts("downloaded_bytes_total",
+			"Bytes of content downloaded from S3.")), []string{"operation"}),
+	}
+}
+
+// ObserveRequest implements s3.MetricsCollector.
+func (c *Collector) ObserveRequest(metrics s3.RequestMetrics) {
+	c.requests.WithLabelValues(metrics.Operation).Inc()
+	c.latency.WithLabelValues(metrics.Operation).Observe(metrics.Latency.Seconds())
+
+	if metrics.ErrorCode != "" {
+		c.errors.WithLabelValues(metrics.Operation, metrics.ErrorCode).Inc()
+	}
+
+	if metrics.BytesUploaded > 0 {
+		c.uploaded.WithLabelValues(metrics.Operation).Add(float64(metrics.BytesUploaded))
+	}
+}
+
+// ObserveDownload implements s3.MetricsCollector.
+func (c *Collector) ObserveDownload(operation string, bytes int64) {
+	c.downloaded.WithLabelValues(operation).Add(float64(bytes))
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.errors.Describe(ch)
+	c.latency.Describe(ch)
+	c.uploaded.Describe(ch)
+	c.downloaded.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.errors.Collect(ch)
+	c.latency.Collect(ch)
+	c.uploaded.Collect(ch)
+	c.downloaded.Collect(ch)
+}
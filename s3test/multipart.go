@@ -0,0 +1,223 @@
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minPartSize is the smallest S3 accepts any part but the last of a multipart upload to be.
+const minPartSize = 5 * 1024 * 1024
+
+type upload struct {
+	bucket, key string
+	header      http.Header // The headers stored with the object once it is completed.
+	parts       map[int]*object
+}
+
+// findUpload returns the upload named by the uploadId of r, which must be of key in bucketName.
+func (srv *Server) findUpload(r *http.Request, bucketName, key string) (*upload, *requestError) {
+	uploadId := r.URL.Query().Get("uploadId")
+
+	u := srv.uploads[uploadId]
+	if u == nil || u.bucket != bucketName || u.key != key {
+		return nil, errorf(http.StatusNotFound, "NoSuchUpload", "the upload %s does not exist", uploadId)
+	}
+
+	return u, nil
+}
+
+func (srv *Server) createUpload(w http.ResponseWriter, r *http.Request, bucketName, key string) *requestError {
+	srv.requestId++
+	uploadId := fmt.Sprintf("upload-%d", srv.requestId)
+	srv.uploads[uploadId] = &upload{bucket: bucketName, key: key, header: objectHeader(r), parts: map[int]*object{}}
+
+	return writeXML(w, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string
+		Key      string
+		UploadId string
+	}{Bucket: bucketName, Key: key, UploadId: uploadId})
+}
+
+func (srv *Server) uploadPart(w http.ResponseWriter, r *http.Request, bucketName, key string) *requestError {
+	u, er := srv.findUpload(r, bucketName, key)
+	if er != nil {
+		return er
+	}
+
+	number, convEr := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if convEr != nil || number < 1 || number > 10000 {
+		return errorf(http.StatusBadRequest, "InvalidArgument", "part numbers must be from 1 to 10000")
+	}
+
+	if r.Header.Get("x-amz-copy-source") == "" {
+		data, er := readBody(r)
+		if er != nil {
+			return er
+		}
+
+		part := newObject(data, nil)
+		u.parts[number] = part
+		w.Header().Set("ETag", part.etag)
+
+		return nil
+	}
+
+	source, er := srv.copySource(r)
+	if er != nil {
+		return er
+	}
+
+	data := source.data
+	if byteRange := r.Header.Get("x-amz-copy-source-range"); byteRange != "" {
+		var start, end int
+		if _, er := fmt.Sscanf(byteRange, "bytes=%d-%d", &start, &end); er != nil || start > end || end >= len(data) {
+			return errorf(http.StatusBadRequest, "InvalidArgument", "invalid copy source range %#v", byteRange)
+		}
+
+		data = data[start : end+1]
+	}
+
+	part := newObject(append([]byte(nil), data...), nil)
+	u.parts[number] = part
+
+	return writeXML(w, struct {
+		XMLName xml.Name `xml:"CopyPartResult"`
+		s3copyResult
+	}{s3copyResult: s3copyResult{ETag: part.etag, LastModified: part.modified}})
+}
+
+type s3part struct {
+	PartNumber   int
+	LastModified time.Time
+	ETag         string
+	Size         int64
+}
+
+type s3listPartsResult struct {
+	XMLName              xml.Name `xml:"ListPartsResult"`
+	Bucket               string
+	Key                  string
+	UploadId             string
+	PartNumberMarker     int
+	NextPartNumberMarker int
+	MaxParts             int
+	IsTruncated          bool
+	Part                 []s3part
+}
+
+func (srv *Server) listParts(w http.ResponseWriter, r *http.Request, bucketName, key string) *requestError {
+	u, er := srv.findUpload(r, bucketName, key)
+	if er != nil {
+		return er
+	}
+
+	query := r.URL.Query()
+	result := s3listPartsResult{Bucket: bucketName, Key: key, UploadId: query.Get("uploadId"), MaxParts: 1000}
+	result.PartNumberMarker, _ = strconv.Atoi(query.Get("part-number-marker"))
+
+	if maxParts, convEr := strconv.Atoi(query.Get("max-parts")); convEr == nil && maxParts > 0 {
+		result.MaxParts = min(maxParts, 1000)
+	}
+
+	for number := result.PartNumberMarker + 1; number <= 10000; number++ {
+		part := u.parts[number]
+		if part == nil {
+			continue
+		}
+
+		if len(result.Part) == result.MaxParts {
+			result.IsTruncated = true
+			break
+		}
+
+		result.Part = append(result.Part, s3part{
+			PartNumber:   number,
+			LastModified: part.modified,
+			ETag:         part.etag,
+			Size:         int64(len(part.data)),
+		})
+		result.NextPartNumberMarker = number
+	}
+
+	return writeXML(w, result)
+}
+
+type s3completeRequest struct {
+	Parts []struct {
+		PartNumber int
+		ETag       string
+	} `xml:"Part"`
+}
+
+// completeUpload assembles the parts listed in the request into the object. Its ETag is computed
+// as S3 does, from the MD5s of the parts.
+func (srv *Server) completeUpload(w http.ResponseWriter, r *http.Request, b *bucket, bucketName, key string) *requestError {
+	u, er := srv.findUpload(r, bucketName, key)
+	if er != nil {
+		return er
+	}
+
+	var req s3completeRequest
+	if er := xml.NewDecoder(r.Body).Decode(&req); er != nil || len(req.Parts) == 0 {
+		return errorf(http.StatusBadRequest, "MalformedXML", "the list of parts is malformed or empty")
+	}
+
+	data := []byte{}
+	sums := []byte{}
+	sizes := []int{}
+
+	for idx, listed := range req.Parts {
+		if idx > 0 && listed.PartNumber <= req.Parts[idx-1].PartNumber {
+			return errorf(http.StatusBadRequest, "InvalidPartOrder", "the parts must be listed in ascending order")
+		}
+
+		part := u.parts[listed.PartNumber]
+		if part == nil || strings.Trim(listed.ETag, `"`) != strings.Trim(part.etag, `"`) {
+			return errorf(http.StatusBadRequest, "InvalidPart", "part %d was not uploaded with that ETag", listed.PartNumber)
+		}
+
+		if idx < len(req.Parts)-1 && len(part.data) < minPartSize {
+			return errorf(http.StatusBadRequest, "EntityTooSmall", "part %d is smaller than the minimum", listed.PartNumber)
+		}
+
+		data = append(data, part.data...)
+		sizes = append(sizes, len(part.data))
+
+		sum, _ := hex.DecodeString(strings.Trim(part.etag, `"`))
+		sums = append(sums, sum...)
+	}
+
+	obj := newObject(data, u.header)
+	obj.parts = sizes
+	sum := md5.Sum(sums)
+	obj.etag = fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(req.Parts))
+
+	b.objects[key] = obj
+	delete(srv.uploads, r.URL.Query().Get("uploadId"))
+
+	return writeXML(w, struct {
+		XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+		Location string
+		Bucket   string
+		Key      string
+		ETag     string
+	}{Location: "/" + bucketName + "/" + key, Bucket: bucketName, Key: key, ETag: obj.etag})
+}
+
+func (srv *Server) abortUpload(w http.ResponseWriter, r *http.Request, bucketName, key string) *requestError {
+	if _, er := srv.findUpload(r, bucketName, key); er != nil {
+		return er
+	}
+
+	delete(srv.uploads, r.URL.Query().Get("uploadId"))
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -0,0 +1,359 @@
+package s3test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Interaction is a request and the response it received, as stored in a fixture file.
+type Interaction struct {
+	Request  RecordedRequest
+	Response RecordedResponse
+}
+
+// RecordedRequest is a request stored in a fixture file, with its credentials scrubbed.
+type RecordedRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   RecordedBody
+}
+
+// RecordedResponse is a response stored in a fixture file.
+type RecordedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       RecordedBody
+}
+
+// RecordedBody is the body of a request or response. It is stored as text when it is valid UTF-8,
+// as XML bodies are, so that fixtures can be read and edited, and as base64 otherwise.
+type RecordedBody []byte
+
+func (rb RecordedBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(rb) {
+		return json.Marshal(string(rb))
+	}
+
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(rb)})
+}
+
+func (rb *RecordedBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		*rb = RecordedBody(text)
+		return nil
+	}
+
+	var encoded struct {
+		Base64 []byte `json:"base64"`
+	}
+
+	if er := json.Unmarshal(data, &encoded); er != nil {
+		return er
+	}
+
+	*rb = encoded.Base64
+	return nil
+}
+
+const redacted = "REDACTED"
+
+// scrubbedHeaders are the headers whose values are replaced in fixtures, since they carry
+// credentials or encryption keys. Authorization is handled separately, by scrubAuthorization.
+var scrubbedHeaders = []string{
+	"X-Amz-Security-Token",
+	"X-Amz-Server-Side-Encryption-Customer-Key",
+	"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key",
+}
+
+// scrubbedParams are the query parameters whose values are replaced in fixtures, as for
+// scrubbedHeaders.
+var scrubbedParams = []string{
+	"AWSAccessKeyId", "Signature", "X-Amz-Credential", "X-Amz-Signature", "X-Amz-Security-Token",
+}
+
+// volatileHeaders and volatileParams differ each time a request is made, so they are ignored
+// when matching requests to those recorded.
+var volatileHeaders = []string{"Authorization", "Date", "X-Amz-Date", "User-Agent", "Accept-Encoding", "Content-Length"}
+var volatileParams = []string{"Expires", "X-Amz-Date", "X-Amz-Expires"}
+
+var authCredential = regexp.MustCompile(`(Credential=)[^,]*|(Signature=)[0-9a-f]*`)
+
+// scrubAuthorization redacts the access key ID and signature from an Authorization header,
+// keeping the algorithm and the list of signed headers.
+func scrubAuthorization(auth string) string {
+	if strings.HasPrefix(auth, "AWS ") {
+		return "AWS " + redacted
+	}
+
+	return authCredential.ReplaceAllString(auth, "${1}${2}"+redacted)
+}
+
+// recordRequest returns req as it is recorded, reading its body. The body of req is replaced so
+// that it can still be sent.
+func recordRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{Method: req.Method, Header: req.Header.Clone()}
+
+	if req.Body != nil {
+		body, er := io.ReadAll(req.Body)
+		req.Body.Close()
+		if er != nil {
+			return recorded, er
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		recorded.Body = body
+	}
+
+	if auth := recorded.Header.Get("Authorization"); auth != "" {
+		recorded.Header.Set("Authorization", scrubAuthorization(auth))
+	}
+
+	for _, name := range scrubbedHeaders {
+		if recorded.Header.Get(name) != "" {
+			recorded.Header.Set(name, redacted)
+		}
+	}
+
+	u := *req.URL
+	query := u.Query()
+	for _, name := range scrubbedParams {
+		if query.Has(name) {
+			query.Set(name, redacted)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	recorded.URL = u.String()
+
+	return recorded, nil
+}
+
+// matches reports whether recorded is the same request as req, which was recorded the same way,
+// ignoring what varies each time a request is made. Bodies sent aws-chunked are compared once
+// decoded, since their chunk signatures vary.
+func (recorded *RecordedRequest) matches(req *RecordedRequest) bool {
+	if recorded.Method != req.Method || !sameURL(recorded.URL, req.URL) {
+		return false
+	}
+
+	if signedHeaders(recorded.Header) != signedHeaders(req.Header) {
+		return false
+	}
+
+	strip := func(header http.Header) http.Header {
+		header = header.Clone()
+		for _, name := range volatileHeaders {
+			header.Del(name)
+		}
+
+		return header
+	}
+
+	if !reflect.DeepEqual(strip(recorded.Header), strip(req.Header)) {
+		return false
+	}
+
+	return bytes.Equal(content(recorded), content(req))
+}
+
+func sameURL(a, b string) bool {
+	ua, er := url.Parse(a)
+	if er != nil {
+		return false
+	}
+
+	ub, er := url.Parse(b)
+	if er != nil {
+		return false
+	}
+
+	qa, qb := ua.Query(), ub.Query()
+	for _, name := range volatileParams {
+		qa.Del(name)
+		qb.Del(name)
+	}
+
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host && ua.EscapedPath() == ub.EscapedPath() &&
+		reflect.DeepEqual(qa, qb)
+}
+
+// signedHeaders returns the list of headers a Signature Version 4 Authorization header covers.
+func signedHeaders(header http.Header) string {
+	_, list, _ := strings.Cut(header.Get("Authorization"), "SignedHeaders=")
+	list, _, _ = strings.Cut(list, ",")
+
+	return list
+}
+
+func content(req *RecordedRequest) []byte {
+	if !strings.Contains(req.Header.Get("Content-Encoding"), "aws-chunked") {
+		return req.Body
+	}
+
+	data, er := readChunked(bufio.NewReader(bytes.NewReader(req.Body)))
+	if er != nil {
+		return req.Body
+	}
+
+	return data
+}
+
+// Recorder is an http.RoundTripper that sends requests with another and records each, with the
+// response it received, to a fixture file a Replayer can replay. Access key IDs, signatures,
+// session tokens and encryption keys are scrubbed from what is recorded. To record, set it as the
+// transport of the HTTP client of an S3:
+//
+//	rec := s3test.NewRecorder("testdata/upload.json", http.DefaultTransport)
+//	defer rec.Close()
+//
+//	client.SetHTTPClient(&http.Client{Transport: rec})
+type Recorder struct {
+	path string
+	next http.RoundTripper
+
+	lock         sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder returns a Recorder sending requests with next and recording them to the file at
+// path once it is closed.
+func NewRecorder(path string, next http.RoundTripper) *Recorder {
+	return &Recorder{path: path, next: next, interactions: []Interaction{}}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, er := recordRequest(req)
+	if er != nil {
+		return nil, er
+	}
+
+	resp, er := rec.next.RoundTrip(req)
+	if er != nil {
+		return nil, er
+	}
+
+	body, er := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if er != nil {
+		return nil, er
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec.lock.Lock()
+	rec.interactions = append(rec.interactions, Interaction{
+		Request:  recorded,
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: body},
+	})
+	rec.lock.Unlock()
+
+	return resp, nil
+}
+
+// Close writes the interactions recorded to the fixture file, replacing it.
+func (rec *Recorder) Close() error {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+
+	data, er := json.MarshalIndent(rec.interactions, "", "\t")
+	if er != nil {
+		return er
+	}
+
+	return os.WriteFile(rec.path, append(data, '\n'), 0644)
+}
+
+// Replayer is an http.RoundTripper that answers requests with the responses recorded by a
+// Recorder, without making any network connections. Each request is answered with the response
+// to the first recorded request it matches that hasn't been replayed yet, so requests made
+// concurrently may arrive in any order. A request matches if it has the same method, URL,
+// headers and content, ignoring those that vary each time (such as the date and the signature,
+// which depends on it) but not the list of headers that were signed. A request that matches none
+// fails, so that changes in what is sent to S3 are caught, and responses are replayed verbatim,
+// so that changes in how they are parsed are.
+type Replayer struct {
+	lock         sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// NewReplayer returns a Replayer of the fixture file at path.
+func NewReplayer(path string) (*Replayer, error) {
+	data, er := os.ReadFile(path)
+	if er != nil {
+		return nil, fmt.Errorf("s3test: %w", er)
+	}
+
+	rp := &Replayer{}
+	if er := json.Unmarshal(data, &rp.interactions); er != nil {
+		return nil, fmt.Errorf("s3test: invalid fixture %s: %w", path, er)
+	}
+
+	rp.replayed = make([]bool, len(rp.interactions))
+	return rp, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rp *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, er := recordRequest(req)
+	if er != nil {
+		return nil, er
+	}
+
+	rp.lock.Lock()
+	defer rp.lock.Unlock()
+
+	for idx := range rp.interactions {
+		interaction := &rp.interactions[idx]
+		if rp.replayed[idx] || !interaction.Request.matches(&recorded) {
+			continue
+		}
+
+		rp.replayed[idx] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("s3test: no recorded request matches %s %s", req.Method, recorded.URL)
+}
+
+// Remaining returns how many of the recorded requests haven't been replayed, which is zero once
+// a test has made the same requests as when it was recorded.
+func (rp *Replayer) Remaining() int {
+	rp.lock.Lock()
+	defer rp.lock.Unlock()
+
+	remaining := 0
+	for _, replayed := range rp.replayed {
+		if !replayed {
+			remaining++
+		}
+	}
+
+	return remaining
+}
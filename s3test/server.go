@@ -0,0 +1,763 @@
+// Package s3test provides a fake S3 service held in memory, for testing code that uses S3
+// without credentials or a network connection:
+//
+//	srv := s3test.NewServer("bucket")
+//	defer srv.Close()
+//
+//	client := s3.NewS3("bucket", "id", "secret")
+//	client.SetEndpoint(srv.URL)
+//	client.SetPathStyle(true)
+//
+// The server supports uploading, downloading (including single ranges and conditional requests),
+// copying, deleting and listing objects, multipart uploads, and creating, deleting and listing
+// buckets. It answers other requests with a NotImplemented error. Signatures aren't checked, so any
+// credentials are accepted. It doesn't import the s3 package, so s3's own tests use it too.
+package s3test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake S3 service. Requests may address buckets either in the path (see
+// s3.SetPathStyle) or as a subdomain of the server's address.
+type Server struct {
+	*httptest.Server
+
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	uploads   map[string]*upload
+	requestId int
+}
+
+type bucket struct {
+	objects map[string]*object
+	created time.Time
+}
+
+type object struct {
+	data     []byte
+	etag     string
+	modified time.Time
+	header   http.Header // The headers stored with the object; see storedHeader.
+	parts    []int       // The sizes of the parts, if the object was uploaded in parts.
+}
+
+// NewServer starts a Server with the named buckets, which are empty. Call Close when done.
+func NewServer(buckets ...string) *Server {
+	srv := &Server{buckets: map[string]*bucket{}, uploads: map[string]*upload{}}
+	for _, name := range buckets {
+		srv.CreateBucket(name)
+	}
+
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.serve))
+	return srv
+}
+
+// CreateBucket creates the named bucket, unless it already exists.
+func (srv *Server) CreateBucket(name string) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if srv.buckets[name] == nil {
+		srv.buckets[name] = &bucket{objects: map[string]*object{}, created: time.Now().UTC().Truncate(time.Second)}
+	}
+}
+
+// PutObject stores data as the object key in the named bucket, creating the bucket if needed, for
+// seeding the server with the objects a test expects to find.
+func (srv *Server) PutObject(bucketName, key string, data []byte) {
+	srv.CreateBucket(bucketName)
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	srv.buckets[bucketName].objects[key] = newObject(data, http.Header{})
+}
+
+// SetLastModified changes when the object key in the named bucket was last modified, for testing
+// code that acts on the age of objects. It does nothing if there is no such object.
+func (srv *Server) SetLastModified(bucketName, key string, modified time.Time) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if b := srv.buckets[bucketName]; b != nil && b.objects[key] != nil {
+		b.objects[key].modified = modified.UTC()
+	}
+}
+
+// Object returns the content of the object key in the named bucket, and whether it exists, for
+// checking what a test uploaded.
+func (srv *Server) Object(bucketName, key string) ([]byte, bool) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	b := srv.buckets[bucketName]
+	if b == nil || b.objects[key] == nil {
+		return nil, false
+	}
+
+	return append([]byte(nil), b.objects[key].data...), true
+}
+
+// Keys returns the keys of the objects in the named bucket, in order.
+func (srv *Server) Keys(bucketName string) []string {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	keys := []string{}
+	if b := srv.buckets[bucketName]; b != nil {
+		keys = b.sortedKeys()
+	}
+
+	return keys
+}
+
+func newObject(data []byte, header http.Header) *object {
+	sum := md5.Sum(data)
+
+	return &object{
+		data:     data,
+		etag:     `"` + hex.EncodeToString(sum[:]) + `"`,
+		modified: time.Now().UTC(),
+		header:   header,
+	}
+}
+
+func (b *bucket) sortedKeys() []string {
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// s3error is the body of an error response.
+type s3error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string
+	Message   string
+	Resource  string
+	RequestId string
+}
+
+// requestError is a failure to be reported to the client.
+type requestError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (er *requestError) Error() string {
+	return er.code + ": " + er.message
+}
+
+func errorf(status int, code, format string, args ...interface{}) *requestError {
+	return &requestError{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// serve handles a request, holding the lock throughout so that requests are atomic.
+func (srv *Server) serve(w http.ResponseWriter, r *http.Request) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	srv.requestId++
+	requestId := fmt.Sprintf("%016X", srv.requestId)
+	w.Header().Set("x-amz-request-id", requestId)
+
+	if er := srv.route(w, r); er != nil {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(er.status)
+
+		if r.Method != "HEAD" {
+			io.WriteString(w, xml.Header)
+			xml.NewEncoder(w).Encode(s3error{
+				Code:      er.code,
+				Message:   er.message,
+				Resource:  r.URL.Path,
+				RequestId: requestId,
+			})
+		}
+	}
+}
+
+// route dispatches r to the handler of the operation it performs.
+func (srv *Server) route(w http.ResponseWriter, r *http.Request) *requestError {
+	bucketName, key := srv.address(r)
+	if bucketName == "" && r.Method == "GET" {
+		return srv.listBuckets(w)
+	} else if bucketName == "" {
+		return errorf(http.StatusMethodNotAllowed, "MethodNotAllowed", "the method %s is not allowed on the service", r.Method)
+	}
+
+	query := r.URL.Query()
+
+	if key == "" && r.Method == "PUT" && len(query) == 0 {
+		return srv.createBucket(w, bucketName)
+	}
+
+	b := srv.buckets[bucketName]
+	if b == nil {
+		return errorf(http.StatusNotFound, "NoSuchBucket", "the bucket %s does not exist", bucketName)
+	}
+
+	switch {
+	case key == "" && r.Method == "HEAD" && len(query) == 0:
+		return nil
+	case key == "" && r.Method == "DELETE" && len(query) == 0:
+		return srv.deleteBucket(w, bucketName, b)
+	case key == "" && r.Method == "GET" && query.Get("list-type") == "2":
+		return listObjects(w, b, query)
+	case key == "" && r.Method == "POST" && query.Has("delete"):
+		return deleteObjects(w, r, b)
+	case key == "" && r.Method == "GET" && query.Has("versioning"):
+		/* Buckets are never versioned, which is what an empty configuration says */
+		return writeXML(w, struct {
+			XMLName xml.Name `xml:"VersioningConfiguration"`
+		}{})
+
+	case key == "":
+		/* Other bucket operations aren't supported */
+
+	case r.Method == "POST" && query.Has("uploads"):
+		return srv.createUpload(w, r, bucketName, key)
+	case r.Method == "PUT" && query.Has("uploadId") && query.Has("partNumber"):
+		return srv.uploadPart(w, r, bucketName, key)
+	case r.Method == "GET" && query.Has("uploadId"):
+		return srv.listParts(w, r, bucketName, key)
+	case r.Method == "POST" && query.Has("uploadId"):
+		return srv.completeUpload(w, r, b, bucketName, key)
+	case r.Method == "DELETE" && query.Has("uploadId"):
+		return srv.abortUpload(w, r, bucketName, key)
+
+	case !plainObjectRequest(r.Method, query):
+		/* Nor are sub-resources of objects, such as ?acl or ?tagging */
+
+	case r.Method == "PUT":
+		return srv.putObject(w, r, b, key)
+	case r.Method == "GET" || r.Method == "HEAD":
+		return getObject(w, r, b, key)
+	case r.Method == "DELETE":
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	return errorf(http.StatusNotImplemented, "NotImplemented", "%s %s is not supported", r.Method, r.URL.RequestURI())
+}
+
+// plainObjectRequest reports whether a method request with query is an ordinary request for an
+// object, rather than for one of its sub-resources.
+func plainObjectRequest(method string, query url.Values) bool {
+	for name := range query {
+		if name != "versionId" && !(name == "partNumber" && (method == "GET" || method == "HEAD")) && !authParameter(name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// authParameter reports whether the query parameter name authenticates a presigned URL, rather
+// than naming a sub-resource.
+func authParameter(name string) bool {
+	switch name {
+	case "AWSAccessKeyId", "Expires", "Signature", "x-amz-security-token":
+		return true
+	}
+
+	return strings.HasPrefix(name, "X-Amz-")
+}
+
+// address returns the bucket and key r addresses, from its host if that is a subdomain of the
+// server's address, and otherwise from its path.
+func (srv *Server) address(r *http.Request) (string, string) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+
+	if host := srv.Listener.Addr().String(); strings.HasSuffix(r.Host, "."+host) {
+		return strings.TrimSuffix(r.Host, "."+host), path
+	}
+
+	bucketName, key, _ := strings.Cut(path, "/")
+	return bucketName, key
+}
+
+// listBuckets implements ListBuckets, listing every bucket at once.
+func (srv *Server) listBuckets(w http.ResponseWriter) *requestError {
+	type s3bucket struct {
+		Name         string
+		CreationDate time.Time
+	}
+
+	result := struct {
+		XMLName xml.Name   `xml:"ListAllMyBucketsResult"`
+		Buckets []s3bucket `xml:"Buckets>Bucket"`
+	}{Buckets: []s3bucket{}}
+
+	for name, b := range srv.buckets {
+		result.Buckets = append(result.Buckets, s3bucket{Name: name, CreationDate: b.created})
+	}
+
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		return result.Buckets[i].Name < result.Buckets[j].Name
+	})
+
+	return writeXML(w, result)
+}
+
+func (srv *Server) createBucket(w http.ResponseWriter, name string) *requestError {
+	if srv.buckets[name] != nil {
+		return errorf(http.StatusConflict, "BucketAlreadyOwnedByYou", "the bucket %s already exists", name)
+	}
+
+	srv.buckets[name] = &bucket{objects: map[string]*object{}, created: time.Now().UTC().Truncate(time.Second)}
+	w.Header().Set("Location", "/"+name)
+
+	return nil
+}
+
+func (srv *Server) deleteBucket(w http.ResponseWriter, name string, b *bucket) *requestError {
+	if len(b.objects) > 0 {
+		return errorf(http.StatusConflict, "BucketNotEmpty", "the bucket %s is not empty", name)
+	}
+
+	delete(srv.buckets, name)
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// storedHeader reports whether S3 stores the request header name with an object and returns it
+// when the object is downloaded.
+func storedHeader(name string) bool {
+	switch name = http.CanonicalHeaderKey(name); name {
+	case "Cache-Control", "Content-Disposition", "Content-Encoding", "Content-Language",
+		"Content-Type", "Expires", "X-Amz-Storage-Class", "X-Amz-Server-Side-Encryption",
+		"X-Amz-Website-Redirect-Location":
+		return true
+	}
+
+	return strings.HasPrefix(name, "X-Amz-Meta-")
+}
+
+// objectHeader returns the headers of r that are stored with the object it uploads.
+func objectHeader(r *http.Request) http.Header {
+	header := http.Header{}
+
+	for name, values := range r.Header {
+		if storedHeader(name) {
+			header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	/* aws-chunked describes how the request was sent, not the content */
+	if encodings := contentEncodings(header.Get("Content-Encoding")); len(encodings) > 0 {
+		header.Set("Content-Encoding", strings.Join(encodings, ","))
+	} else {
+		header.Del("Content-Encoding")
+	}
+
+	return header
+}
+
+// contentEncodings returns the encodings listed in a Content-Encoding header, besides aws-chunked.
+func contentEncodings(header string) []string {
+	encodings := []string{}
+
+	for _, encoding := range strings.Split(header, ",") {
+		if encoding = strings.TrimSpace(encoding); encoding != "" && encoding != "aws-chunked" {
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
+// readBody reads the content r uploads, decoding it if it was sent aws-chunked and checking it
+// against its Content-MD5, if it has one.
+func readBody(r *http.Request) ([]byte, *requestError) {
+	var data []byte
+	var er error
+
+	if strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") {
+		data, er = readChunked(bufio.NewReader(r.Body))
+	} else {
+		data, er = io.ReadAll(r.Body)
+	}
+
+	if er != nil {
+		return nil, errorf(http.StatusBadRequest, "IncompleteBody", "reading the body failed: %v", er)
+	}
+
+	if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" {
+		sum := md5.Sum(data)
+		if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return nil, errorf(http.StatusBadRequest, "BadDigest", "the Content-MD5 didn't match the content")
+		}
+	}
+
+	return data, nil
+}
+
+// readChunked decodes a body sent with the aws-chunked encoding, ignoring chunk signatures and
+// trailers.
+func readChunked(r *bufio.Reader) ([]byte, error) {
+	var data bytes.Buffer
+
+	for {
+		line, er := r.ReadString('\n')
+		if er != nil {
+			return nil, er
+		}
+
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+
+		size, er := strconv.ParseInt(sizeHex, 16, 64)
+		if er != nil {
+			return nil, fmt.Errorf("invalid chunk size %#v", sizeHex)
+		}
+
+		if size == 0 {
+			return data.Bytes(), nil
+		}
+
+		if _, er := io.CopyN(&data, r, size); er != nil {
+			return nil, er
+		}
+
+		if _, er := r.Discard(2); er != nil {
+			return nil, er
+		}
+	}
+}
+
+// copySource returns the object named by the x-amz-copy-source header of r.
+func (srv *Server) copySource(r *http.Request) (*object, *requestError) {
+	/* The version ID follows a question mark, which is escaped in the key itself */
+	source, _, _ := strings.Cut(r.Header.Get("x-amz-copy-source"), "?")
+
+	source, er := url.PathUnescape(source)
+	if er != nil {
+		return nil, errorf(http.StatusBadRequest, "InvalidArgument", "invalid copy source: %v", er)
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+
+	b := srv.buckets[bucketName]
+	if b == nil {
+		return nil, errorf(http.StatusNotFound, "NoSuchBucket", "the bucket %s does not exist", bucketName)
+	}
+
+	obj := b.objects[key]
+	if obj == nil {
+		return nil, errorf(http.StatusNotFound, "NoSuchKey", "the key %s does not exist", key)
+	}
+
+	return obj, nil
+}
+
+type s3copyResult struct {
+	ETag         string
+	LastModified time.Time
+}
+
+func (srv *Server) putObject(w http.ResponseWriter, r *http.Request, b *bucket, key string) *requestError {
+	if r.Header.Get("If-None-Match") == "*" && b.objects[key] != nil {
+		return errorf(http.StatusPreconditionFailed, "PreconditionFailed", "the key %s already exists", key)
+	}
+
+	if r.Header.Get("x-amz-copy-source") != "" {
+		source, er := srv.copySource(r)
+		if er != nil {
+			return er
+		}
+
+		header := source.header.Clone()
+		if r.Header.Get("x-amz-metadata-directive") == "REPLACE" {
+			header = objectHeader(r)
+		}
+
+		obj := newObject(append([]byte(nil), source.data...), header)
+		b.objects[key] = obj
+
+		return writeXML(w, struct {
+			XMLName xml.Name `xml:"CopyObjectResult"`
+			s3copyResult
+		}{s3copyResult: s3copyResult{ETag: obj.etag, LastModified: obj.modified}})
+	}
+
+	data, er := readBody(r)
+	if er != nil {
+		return er
+	}
+
+	obj := newObject(data, objectHeader(r))
+	b.objects[key] = obj
+	w.Header().Set("ETag", obj.etag)
+
+	return nil
+}
+
+func getObject(w http.ResponseWriter, r *http.Request, b *bucket, key string) *requestError {
+	obj := b.objects[key]
+	if obj == nil {
+		return errorf(http.StatusNotFound, "NoSuchKey", "the key %s does not exist", key)
+	}
+
+	w.Header().Set("Content-Type", "binary/octet-stream")
+	for name, values := range obj.header {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	if r.URL.Query().Has("partNumber") {
+		return getPart(w, r, obj)
+	}
+
+	/* ServeContent handles ranges and conditional requests, though S3 only honors a single range
+	 * and answers a request for several with the whole object */
+	if strings.Contains(r.Header.Get("Range"), ",") {
+		r.Header.Del("Range")
+	}
+
+	http.ServeContent(w, r, "", obj.modified, bytes.NewReader(obj.data))
+
+	return nil
+}
+
+// getPart serves the part of obj named by the partNumber of r. An object that wasn't uploaded in
+// parts has a single one.
+func getPart(w http.ResponseWriter, r *http.Request, obj *object) *requestError {
+	parts := obj.parts
+	if parts == nil {
+		parts = []int{len(obj.data)}
+	}
+
+	number, er := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if er != nil || number < 1 || number > len(parts) {
+		return errorf(http.StatusRequestedRangeNotSatisfiable, "InvalidPartNumber", "the object has %d parts", len(parts))
+	}
+
+	start := 0
+	for _, size := range parts[:number-1] {
+		start += size
+	}
+
+	data := obj.data[start : start+parts[number-1]]
+
+	w.Header().Set("Last-Modified", obj.modified.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+
+	if obj.parts != nil {
+		w.Header().Set("x-amz-mp-parts-count", strconv.Itoa(len(obj.parts)))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+len(data)-1, len(obj.data)))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+
+	if r.Method != "HEAD" {
+		w.Write(data)
+	}
+
+	return nil
+}
+
+type s3listResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string
+	Prefix                string
+	Delimiter             string `xml:",omitempty"`
+	MaxKeys               int
+	KeyCount              int
+	IsTruncated           bool
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	EncodingType          string `xml:",omitempty"`
+	Contents              []s3listObject
+	CommonPrefixes        []s3listPrefix
+}
+
+type s3listObject struct {
+	Key          string
+	LastModified time.Time
+	ETag         string
+	Size         int64
+	StorageClass string
+}
+
+type s3listPrefix struct {
+	Prefix string
+}
+
+// listObjects implements ListObjectsV2. The continuation token is the last key or prefix listed.
+func listObjects(w http.ResponseWriter, b *bucket, query url.Values) *requestError {
+	result := s3listResult{
+		Prefix:            query.Get("prefix"),
+		Delimiter:         query.Get("delimiter"),
+		MaxKeys:           1000,
+		ContinuationToken: query.Get("continuation-token"),
+		StartAfter:        query.Get("start-after"),
+	}
+
+	if maxKeys := query.Get("max-keys"); maxKeys != "" {
+		n, er := strconv.Atoi(maxKeys)
+		if er != nil || n < 0 {
+			return errorf(http.StatusBadRequest, "InvalidArgument", "invalid max-keys %#v", maxKeys)
+		}
+
+		result.MaxKeys = min(n, 1000)
+	}
+
+	after := result.StartAfter
+	if result.ContinuationToken != "" {
+		token, er := base64.URLEncoding.DecodeString(result.ContinuationToken)
+		if er != nil {
+			return errorf(http.StatusBadRequest, "InvalidArgument", "invalid continuation token")
+		}
+
+		after = string(token)
+	}
+
+	for _, key := range b.sortedKeys() {
+		if !strings.HasPrefix(key, result.Prefix) || key <= after {
+			continue
+		}
+
+		entry := key
+		rolledUp := false
+
+		if idx := strings.Index(key[len(result.Prefix):], result.Delimiter); result.Delimiter != "" && idx >= 0 {
+			entry = key[:len(result.Prefix)+idx+len(result.Delimiter)]
+			rolledUp = true
+
+			/* Keys beneath a prefix already listed */
+			if entry <= after {
+				continue
+			}
+		}
+
+		if result.KeyCount == result.MaxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(after))
+			break
+		}
+
+		if rolledUp {
+			result.CommonPrefixes = append(result.CommonPrefixes, s3listPrefix{Prefix: entry})
+			after = entry + "\U0010FFFF"
+		} else {
+			obj := b.objects[key]
+			result.Contents = append(result.Contents, s3listObject{
+				Key:          key,
+				LastModified: obj.modified,
+				ETag:         obj.etag,
+				Size:         int64(len(obj.data)),
+				StorageClass: storageClass(obj),
+			})
+			after = key
+		}
+
+		result.KeyCount++
+	}
+
+	if query.Get("encoding-type") == "url" {
+		encodeListing(&result)
+	}
+
+	return writeXML(w, result)
+}
+
+// encodeListing URL-encodes the keys and prefixes of result, as S3 does when a listing is
+// requested with encoding-type=url, so that keys with characters XML can't carry are listed.
+func encodeListing(result *s3listResult) {
+	result.EncodingType = "url"
+	result.Prefix = url.QueryEscape(result.Prefix)
+	result.Delimiter = url.QueryEscape(result.Delimiter)
+	result.StartAfter = url.QueryEscape(result.StartAfter)
+
+	for i := range result.Contents {
+		result.Contents[i].Key = url.QueryEscape(result.Contents[i].Key)
+	}
+
+	for i := range result.CommonPrefixes {
+		result.CommonPrefixes[i].Prefix = url.QueryEscape(result.CommonPrefixes[i].Prefix)
+	}
+}
+
+func storageClass(obj *object) string {
+	if class := obj.header.Get("X-Amz-Storage-Class"); class != "" {
+		return class
+	}
+
+	return "STANDARD"
+}
+
+type s3deleteRequest struct {
+	Quiet   bool
+	Objects []struct {
+		Key string
+	} `xml:"Object"`
+}
+
+type s3deleteResult struct {
+	XMLName xml.Name `xml:"DeleteResult"`
+	Deleted []struct {
+		Key string
+	}
+}
+
+func deleteObjects(w http.ResponseWriter, r *http.Request, b *bucket) *requestError {
+	var req s3deleteRequest
+	if er := xml.NewDecoder(r.Body).Decode(&req); er != nil {
+		return errorf(http.StatusBadRequest, "MalformedXML", "%v", er)
+	}
+
+	if len(req.Objects) > 1000 {
+		return errorf(http.StatusBadRequest, "MalformedXML", "more than 1000 keys to delete")
+	}
+
+	result := s3deleteResult{}
+
+	for _, obj := range req.Objects {
+		delete(b.objects, obj.Key)
+
+		if !req.Quiet {
+			result.Deleted = append(result.Deleted, struct{ Key string }{obj.Key})
+		}
+	}
+
+	return writeXML(w, result)
+}
+
+// writeXML writes v as the body of a successful response.
+func writeXML(w http.ResponseWriter, v interface{}) *requestError {
+	body, er := xml.Marshal(v)
+	if er != nil {
+		return errorf(http.StatusInternalServerError, "InternalError", "%v", er)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(body)))
+	io.WriteString(w, xml.Header)
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// sampleAttempts bounds the number of probes Sample makes per requested key, so that a prefix
+// with few distinct keys can't keep it probing forever.
+const sampleAttempts = 4
+
+// Sample returns up to n keys chosen approximately uniformly at random from those under prefix,
+// without listing the whole prefix. This is useful for spot checks and statistical audits of
+// huge buckets.
+//
+// If the prefix holds no more than a single page of keys, the sample is drawn exactly from that
+// page. Otherwise Sample probes the key space by listing the single key that follows each of a
+// series of random start-after markers, built from the characters seen in the first page of
+// keys. Keys that follow large gaps in the key space are somewhat more likely to be picked, so
+// the sample is only approximately uniform. Fewer than n keys are returned if the probes keep
+// landing on keys that have already been sampled.
+//
+// Sample relies on start-after, so it is not supported by directory buckets.
+func (s3 *S3) Sample(ctx context.Context, prefix string, n int) ([]ObjectSummary, error) {
+	if s3.express != nil {
+		return nil, fmt.Errorf("s3: Sample is not supported by directory buckets")
+	}
+
+	first, er := s3.listPage(ctx, prefix, "", "", nil)
+	if er != nil {
+		return nil, er
+	}
+
+	if first.next == "" {
+		sample := []ObjectSummary{}
+
+		for _, idx := range rand.Perm(len(first.objects)) {
+			if len(sample) == n {
+				break
+			}
+
+			sample = append(sample, first.objects[idx])
+		}
+
+		return sample, nil
+	}
+
+	/* Work out what the keys under the prefix look like, so that the random markers
+	 * land amongst them rather than off in some unused corner of the key space */
+	seenChars := map[byte]bool{}
+	maxLen := 1
+
+	for _, obj := range first.objects {
+		suffix := obj.Key[len(prefix):]
+
+		for i := 0; i < len(suffix); i++ {
+			seenChars[suffix[i]] = true
+		}
+
+		if len(suffix) > maxLen {
+			maxLen = len(suffix)
+		}
+	}
+
+	alphabet := []byte{}
+	for c := range seenChars {
+		alphabet = append(alphabet, c)
+	}
+	sort.Slice(alphabet, func(i, j int) bool { return alphabet[i] < alphabet[j] })
+
+	if len(alphabet) == 0 {
+		return []ObjectSummary{}, nil
+	}
+
+	sample := []ObjectSummary{}
+	sampled := map[string]bool{}
+
+	for attempt := 0; len(sample) < n && attempt < n*sampleAttempts; attempt++ {
+		marker := make([]byte, 1+rand.Intn(maxLen))
+		for i := range marker {
+			marker[i] = alphabet[rand.Intn(len(alphabet))]
+		}
+
+		page, er := s3.listPage(ctx, prefix, "", "", []ListOption{ListPageSize(1), ListStartAfter(prefix + string(marker))})
+		if er != nil {
+			return sample, er
+		}
+
+		if len(page.objects) == 0 || sampled[page.objects[0].Key] {
+			continue
+		}
+
+		sampled[page.objects[0].Key] = true
+		sample = append(sample, page.objects[0])
+	}
+
+	return sample, nil
+}
@@ -0,0 +1,315 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Formats of the objects S3 Select queries, and of the records it returns, as in SelectQuery.
+const (
+	SelectCSV     = "CSV"
+	SelectJSON    = "JSON"
+	SelectParquet = "Parquet" // Only objects can be in Parquet, not the records returned.
+)
+
+// maxEventSize bounds the messages of an event stream, which S3 keeps well below it.
+const maxEventSize = 16 * 1024 * 1024
+
+// SelectQuery is an SQL expression for SelectObjectContent, with the format of the object it
+// queries and of the records it returns.
+type SelectQuery struct {
+	Expression string // Such as "SELECT s.name FROM S3Object s WHERE CAST(s.age AS INT) > 30".
+
+	InputFormat      string // SelectCSV, SelectJSON or SelectParquet.
+	InputCompression string // "GZIP" or "BZIP2" for compressed CSV or JSON; uncompressed if empty.
+	CSVHeader        string // "USE" the first line of CSV as column names, "IGNORE" it, or "NONE".
+	CSVDelimiter     string // The field delimiter of CSV input and output; "," if empty.
+	JSONLines        bool   // JSON input has a document per line, rather than being one document.
+
+	// OutputFormat is SelectCSV or SelectJSON, which has a record per line. If it is empty,
+	// records are returned in the format of the object, or as CSV for Parquet.
+	OutputFormat string
+}
+
+// SelectStats counts the bytes an S3 Select query went through, which it is billed for.
+type SelectStats struct {
+	BytesScanned   int64
+	BytesProcessed int64 // The bytes scanned, after decompression.
+	BytesReturned  int64
+}
+
+type s3csvSerialization struct {
+	FileHeaderInfo string `xml:",omitempty"`
+	FieldDelimiter string `xml:",omitempty"`
+}
+
+type s3selectReq struct {
+	XMLName            xml.Name `xml:"SelectObjectContentRequest"`
+	Expression         string
+	ExpressionType     string
+	InputSerialization struct {
+		CompressionType string              `xml:",omitempty"`
+		CSV             *s3csvSerialization `xml:",omitempty"`
+		JSON            *struct {
+			Type string
+		} `xml:",omitempty"`
+		Parquet *struct{} `xml:",omitempty"`
+	}
+	OutputSerialization struct {
+		CSV  *s3csvSerialization `xml:",omitempty"`
+		JSON *struct{}           `xml:",omitempty"`
+	}
+}
+
+// SelectReader reads the records returned by SelectObjectContent, decoding them from the event
+// stream S3 responds with as they arrive. It must be closed.
+type SelectReader struct {
+	body    io.ReadCloser
+	resp    *http.Response
+	records []byte // What is left of the records of the last message read.
+	stats   SelectStats
+	er      error
+}
+
+// SelectObjectContent runs query against the object at path on S3's side, returning a reader for
+// the records it selects, so that only those are downloaded. The reader fails with
+// io.ErrUnexpectedEOF if the response ends before S3 says the query is complete, and with an
+// *S3Error if the query fails part way through, such as on a malformed record.
+func (s3 *S3) SelectObjectContent(ctx context.Context, path string, query SelectQuery, opts ...RequestOption) (*SelectReader, error) {
+	body := s3selectReq{Expression: query.Expression, ExpressionType: "SQL"}
+	body.InputSerialization.CompressionType = query.InputCompression
+
+	output := query.OutputFormat
+
+	switch query.InputFormat {
+	case SelectCSV:
+		body.InputSerialization.CSV = &s3csvSerialization{FileHeaderInfo: query.CSVHeader, FieldDelimiter: query.CSVDelimiter}
+	case SelectJSON:
+		body.InputSerialization.JSON = &struct{ Type string }{"DOCUMENT"}
+		if query.JSONLines {
+			body.InputSerialization.JSON.Type = "LINES"
+		}
+
+		if output == "" {
+			output = SelectJSON
+		}
+	case SelectParquet:
+		body.InputSerialization.Parquet = &struct{}{}
+	default:
+		return nil, fmt.Errorf("s3: S3 Select can't query objects in %#v format", query.InputFormat)
+	}
+
+	switch output {
+	case SelectCSV, "":
+		body.OutputSerialization.CSV = &s3csvSerialization{FieldDelimiter: query.CSVDelimiter}
+	case SelectJSON:
+		body.OutputSerialization.JSON = &struct{}{}
+	default:
+		return nil, fmt.Errorf("s3: S3 Select can't return records in %#v format", output)
+	}
+
+	xmlBody, er := xml.Marshal(body)
+	if er != nil {
+		return nil, er
+	}
+
+	values := url.Values{}
+	values.Set("select", "")
+	values.Set("select-type", "2")
+
+	req, er := http.NewRequestWithContext(ctx, "POST", s3.resource(path, values), bytes.NewReader(xmlBody))
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(xmlBody)))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Host", req.URL.Host)
+	req.ContentLength = int64(len(xmlBody))
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return nil, er
+	}
+
+	return &SelectReader{body: resp.Body, resp: resp}, nil
+}
+
+// Read reads the selected records.
+func (sr *SelectReader) Read(p []byte) (int, error) {
+	for len(sr.records) == 0 {
+		if sr.er != nil {
+			return 0, sr.er
+		}
+
+		sr.er = sr.next()
+	}
+
+	n := copy(p, sr.records)
+	sr.records = sr.records[n:]
+
+	return n, nil
+}
+
+// Stats returns the statistics S3 sends once the query is complete, which are zero until every
+// record has been read.
+func (sr *SelectReader) Stats() SelectStats {
+	return sr.stats
+}
+
+// Close closes the response S3 is streaming records on.
+func (sr *SelectReader) Close() error {
+	return sr.body.Close()
+}
+
+// next reads the next message of the event stream, returning io.EOF for the end of the query.
+func (sr *SelectReader) next() error {
+	headers, payload, er := readEvent(sr.body)
+	if er == io.EOF {
+		return io.ErrUnexpectedEOF
+
+	} else if er != nil {
+		return er
+	}
+
+	if headers[":message-type"] == "error" {
+		return &S3Error{
+			Code:      sr.resp.StatusCode,
+			Header:    sr.resp.Header,
+			ErrorCode: headers[":error-code"],
+			Message:   headers[":error-message"],
+			RequestId: sr.resp.Header.Get("x-amz-request-id"),
+			HostId:    sr.resp.Header.Get("x-amz-id-2"),
+		}
+	}
+
+	/* Progress and Cont (keep-alive) events carry nothing that is needed */
+	switch headers[":event-type"] {
+	case "Records":
+		sr.records = payload
+	case "Stats":
+		var xmlStats struct {
+			XMLName xml.Name `xml:"Stats"`
+			SelectStats
+		}
+
+		if er := xml.Unmarshal(payload, &xmlStats); er != nil {
+			return fmt.Errorf("s3: invalid S3 Select statistics: %w", er)
+		}
+
+		sr.stats = xmlStats.SelectStats
+	case "End":
+		return io.EOF
+	}
+
+	return nil
+}
+
+// readEvent reads a message of an AWS event stream from r, returning its string headers and its
+// payload. A message is framed by its total length, the length of its headers, and a checksum of
+// the two, and ends with a checksum of the whole message. It returns io.EOF only if r ends
+// between messages.
+func readEvent(r io.Reader) (map[string]string, []byte, error) {
+	var prelude [12]byte
+	if _, er := io.ReadFull(r, prelude[:]); er != nil {
+		return nil, nil, er
+	}
+
+	total := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, nil, fmt.Errorf("s3: event stream prelude checksum mismatch")
+	}
+
+	if total > maxEventSize || uint64(total) < 16+uint64(headersLen) {
+		return nil, nil, fmt.Errorf("s3: invalid event stream message length %d", total)
+	}
+
+	msg := make([]byte, total-12)
+	if _, er := io.ReadFull(r, msg); er == io.EOF {
+		return nil, nil, io.ErrUnexpectedEOF
+
+	} else if er != nil {
+		return nil, nil, er
+	}
+
+	crc := crc32.Update(crc32.ChecksumIEEE(prelude[:]), crc32.IEEETable, msg[:len(msg)-4])
+	if crc != binary.BigEndian.Uint32(msg[len(msg)-4:]) {
+		return nil, nil, fmt.Errorf("s3: event stream message checksum mismatch")
+	}
+
+	headers, er := parseEventHeaders(msg[:headersLen])
+	if er != nil {
+		return nil, nil, er
+	}
+
+	return headers, msg[headersLen : len(msg)-4], nil
+}
+
+// eventValueSizes is the size of the values of each fixed-size type of event stream header.
+var eventValueSizes = map[byte]int{
+	0: 0,  // true
+	1: 0,  // false
+	2: 1,  // byte
+	3: 2,  // short
+	4: 4,  // integer
+	5: 8,  // long
+	8: 8,  // timestamp
+	9: 16, // UUID
+}
+
+// parseEventHeaders parses the headers of an event stream message. Each is a name prefixed by its
+// length, then a byte giving the type of the value, then the value. Only the string values are
+// kept; the others are skipped.
+func parseEventHeaders(b []byte) (map[string]string, error) {
+	headers := map[string]string{}
+	invalid := fmt.Errorf("s3: invalid event stream headers")
+
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 2+nameLen {
+			return nil, invalid
+		}
+
+		name, valueType := string(b[1:1+nameLen]), b[1+nameLen]
+		b = b[2+nameLen:]
+
+		/* Byte arrays (6) and strings (7) are prefixed by their length */
+		if size, ok := eventValueSizes[valueType]; ok {
+			if len(b) < size {
+				return nil, invalid
+			}
+
+			b = b[size:]
+
+		} else if valueType == 6 || valueType == 7 {
+			if len(b) < 2 {
+				return nil, invalid
+			}
+
+			size := int(binary.BigEndian.Uint16(b))
+			if len(b) < 2+size {
+				return nil, invalid
+			}
+
+			if valueType == 7 {
+				headers[name] = string(b[2 : 2+size])
+			}
+
+			b = b[2+size:]
+
+		} else {
+			return nil, fmt.Errorf("s3: unknown event stream header type %d", valueType)
+		}
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"net/http"
+)
+
+// Signer authenticates a request before it is sent, by adding an Authorization header, or
+// whatever else the service expects. Sign is passed the request with every header set, Host
+// included; it may read the body only through GetBody, which leaves the body itself unread. A
+// request that is retried is signed again.
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// SignerFunc lets an ordinary function be used as a Signer.
+type SignerFunc func(req *http.Request) error
+
+// Sign calls f(req).
+func (f SignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// SetSigner makes the S3 sign requests with signer, for S3-compatible services that authenticate
+// requests in a way of their own, or with a variation on AWS's signatures, such as leaving
+// headers a proxy rewrites out of them. A custom signer can do its own work, and have SignerV2 or
+// SignerV4 do the rest. Passing nil restores the signatures the S3 makes otherwise.
+//
+// The signer must add the session token of temporary credentials itself, if there is one; the
+// signers from SignerV2 and SignerV4 do. Uploads aren't streamed with a custom signer (see
+// SetStreamingUploads), and it isn't used for presigned URLs and POST forms, nor for directory
+// buckets or anonymous requests.
+func (s3 *S3) SetSigner(signer Signer) {
+	s3.signer = signer
+}
+
+// SignerV2 returns a Signer that signs requests with AWS Signature Version 2, using the
+// credentials of the S3, as it does unless SetSignatureV4 is called. It signs requests for the
+// bucket of the S3, and so should only be used by it, and not by copies of it for other buckets.
+func (s3 *S3) SignerV2() Signer {
+	return SignerFunc(func(req *http.Request) error {
+		creds, er := s3.signingCredentials(req)
+		if er != nil {
+			return er
+		}
+
+		s3.signRequestV2(req, creds)
+		return nil
+	})
+}
+
+// SignerV4 returns a Signer that signs requests with AWS Signature Version 4, using the
+// credentials of the S3, as it does once SetSignatureV4 is called. It signs requests for the
+// region of the S3, and so should only be used by it, and not by copies of it for other regions.
+func (s3 *S3) SignerV4() Signer {
+	return SignerFunc(func(req *http.Request) error {
+		creds, er := s3.signingCredentials(req)
+		if er != nil {
+			return er
+		}
+
+		s3.signRequestV4(req, creds)
+		return nil
+	})
+}
+
+// signingCredentials returns the credentials to sign req with, setting its session token header
+// to that of the credentials.
+func (s3 *S3) signingCredentials(req *http.Request) (Credentials, error) {
+	creds, er := s3.creds.get()
+	if er != nil {
+		return Credentials{}, er
+	}
+
+	if creds.Token != "" {
+		req.Header.Set("x-amz-security-token", creds.Token)
+	} else {
+		req.Header.Del("x-amz-security-token")
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,180 @@
+package s3
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// v4aAlgorithm is the algorithm of Signature Version 4A, which signs requests with an ECDSA key
+// rather than an HMAC, so that a signature is valid in every region of a region set rather than
+// in one. Multi-Region Access Points require it.
+const v4aAlgorithm = "AWS4-ECDSA-P256-SHA256"
+
+// v4aKeyCache holds the most recently derived signing key, since deriving one takes a scalar
+// multiplication as well as a few HMACs.
+var v4aKeyCache struct {
+	lock             sync.Mutex
+	accessId, secret string
+	key              *ecdsa.PrivateKey
+}
+
+// v4aSigningKey derives the ECDSA key that accessId and secret sign requests with, as AWS does:
+// with the counter mode KDF of NIST SP 800-108, retrying with the next counter until the output is
+// a valid P-256 private key.
+func v4aSigningKey(accessId, secret string) (*ecdsa.PrivateKey, error) {
+	v4aKeyCache.lock.Lock()
+	defer v4aKeyCache.lock.Unlock()
+
+	cache := &v4aKeyCache
+	if cache.key != nil && cache.accessId == accessId && cache.secret == secret {
+		return cache.key, nil
+	}
+
+	curve := elliptic.P256()
+	nMinusTwo := new(big.Int).Sub(curve.Params().N, big.NewInt(2))
+
+	for counter := byte(1); counter < 0xff; counter++ {
+		mac := hmac.New(sha256.New, []byte("AWS4A"+secret))
+		binary.Write(mac, binary.BigEndian, uint32(1))
+		mac.Write([]byte(v4aAlgorithm))
+		mac.Write([]byte{0})
+		mac.Write([]byte(accessId))
+		mac.Write([]byte{counter})
+		binary.Write(mac, binary.BigEndian, uint32(256))
+
+		candidate := new(big.Int).SetBytes(mac.Sum(nil))
+		if candidate.Cmp(nMinusTwo) >= 0 {
+			continue
+		}
+
+		d := candidate.Add(candidate, big.NewInt(1))
+
+		private, er := ecdh.P256().NewPrivateKey(d.FillBytes(make([]byte, 32)))
+		if er != nil {
+			return nil, er
+		}
+
+		/* The public key is encoded uncompressed, as 0x04 followed by X and Y */
+		point := private.PublicKey().Bytes()
+
+		key := &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(point[1:33]),
+				Y:     new(big.Int).SetBytes(point[33:]),
+			},
+			D: d,
+		}
+
+		cache.accessId, cache.secret, cache.key = accessId, secret, key
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("s3: cannot derive a Signature Version 4A key")
+}
+
+// v4aScope returns the credential scope for requests made on date (formatted as YYYYMMDD), which
+// unlike that of Signature Version 4 names no region.
+func v4aScope(date, service string) string {
+	return date + "/" + service + "/aws4_request"
+}
+
+// v4aSignature computes the signature of canonicalRequest made at amzDate.
+func v4aSignature(key *ecdsa.PrivateKey, amzDate, service, canonicalRequest string) (string, error) {
+	stringToSign := strings.Join([]string{
+		v4aAlgorithm,
+		amzDate,
+		v4aScope(amzDate[:8], service),
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+
+	signature, er := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if er != nil {
+		return "", er
+	}
+
+	return hex.EncodeToString(signature), nil
+}
+
+// signV4A signs req with Signature Version 4A as of now, for every region, using the
+// Authorization header. As with signV4, the payload is left unsigned unless the caller has set
+// X-Amz-Content-Sha256.
+func signV4A(req *http.Request, accessId, secret, service string, now time.Time) error {
+	key, er := v4aSigningKey(accessId, secret)
+	if er != nil {
+		return er
+	}
+
+	amzDate := now.UTC().Format(v4TimeFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Region-Set", "*")
+
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = unsignedPayload
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	canonicalRequest, signedHeaders := v4CanonicalRequest(req, payloadHash)
+
+	signature, er := v4aSignature(key, amzDate, service, canonicalRequest)
+	if er != nil {
+		return er
+	}
+
+	auth := fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		v4aAlgorithm, accessId, v4aScope(amzDate[:8], service), signedHeaders, signature)
+	req.Header.Set("Authorization", auth)
+
+	return nil
+}
+
+// presignV4A is presignV4 for Signature Version 4A, presigning req for every region.
+func presignV4A(req *http.Request, accessId, secret, token, service string, now time.Time, expires time.Duration) error {
+	key, er := v4aSigningKey(accessId, secret)
+	if er != nil {
+		return er
+	}
+
+	amzDate := now.UTC().Format(v4TimeFormat)
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", v4aAlgorithm)
+	query.Set("X-Amz-Credential", accessId+"/"+v4aScope(amzDate[:8], service))
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int64(expires/time.Second)))
+	query.Set("X-Amz-Region-Set", "*")
+
+	if token != "" {
+		query.Set("X-Amz-Security-Token", token)
+	}
+
+	_, signedHeaders := v4CanonicalRequest(req, unsignedPayload)
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	req.URL.RawQuery = query.Encode()
+
+	canonicalRequest, _ := v4CanonicalRequest(req, unsignedPayload)
+
+	signature, er := v4aSignature(key, amzDate, service, canonicalRequest)
+	if er != nil {
+		return er
+	}
+
+	req.URL.RawQuery += "&X-Amz-Signature=" + signature
+	return nil
+}
@@ -0,0 +1,152 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ObjectInfo describes an object, as returned by Stat and ObjectInfoFromHeader.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	ContentType  string
+	LastModified time.Time
+	StorageClass string
+	VersionId    string // Empty unless the bucket has versioning enabled (or suspended).
+
+	// PartsCount is the number of parts the object was uploaded in, or zero if it was uploaded
+	// with a single request.
+	PartsCount int
+
+	// Metadata holds the user metadata of the object (see WithMetadata).
+	Metadata map[string]string
+
+	// Encryption describes how the object is encrypted at rest (see WithSSEKMS).
+	Encryption Encryption
+
+	// Restore is the state of the restore of an archived object, or nil if none was requested
+	// (see RestoreObject).
+	Restore *RestoreState
+}
+
+// ObjectInfoFromHeader describes the object at key from the headers of a response to Head or Get,
+// so that they needn't be parsed by hand. The size is that of the whole object even for a ranged
+// Get. PartsCount is only known to Stat.
+func ObjectInfoFromHeader(key string, header http.Header) *ObjectInfo {
+	info := &ObjectInfo{
+		Key:          key,
+		ETag:         header.Get("ETag"),
+		ContentType:  header.Get("Content-Type"),
+		StorageClass: StorageClass(header),
+		VersionId:    header.Get("x-amz-version-id"),
+		Metadata:     Metadata(header),
+		Encryption:   ServerSideEncryption(header),
+	}
+
+	info.Size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if _, _, total, er := parseContentRange(header.Get("Content-Range")); er == nil && total >= 0 {
+		info.Size = total
+	}
+
+	info.LastModified, _ = http.ParseTime(header.Get("Last-Modified"))
+	info.Restore, _ = ParseRestore(header)
+
+	return info
+}
+
+// PartInfo describes one part of an object uploaded with the multipart API. Offset is where the
+// part begins within the object. Checksums are only known to GetObjectAttributes.
+type PartInfo struct {
+	Number    int
+	Offset    int64
+	Size      int64
+	Checksums Checksums
+}
+
+// Stat fetches information about the object at path without downloading it.
+//
+// The HEAD request is made for the object's first part, which is how S3 reveals how many parts
+// it was uploaded in; the size is taken from the Content-Range of the response, so it is still
+// that of the whole object.
+func (s3 *S3) Stat(ctx context.Context, path string, opts ...RequestOption) (*ObjectInfo, error) {
+	header, er := s3.headPart(ctx, path, 1, opts)
+	if er != nil {
+		return nil, er
+	}
+
+	info := ObjectInfoFromHeader(path, header)
+	info.PartsCount, _ = strconv.Atoi(header.Get("x-amz-mp-parts-count"))
+
+	return info, nil
+}
+
+// StatParts lists the parts the object at path was uploaded in, with their sizes and offsets, so
+// that parallel downloads and verification can be aligned with them. An object uploaded with a
+// single request is reported as one part. Each part after the first takes a HEAD request.
+func (s3 *S3) StatParts(ctx context.Context, path string, opts ...RequestOption) ([]PartInfo, error) {
+	info, er := s3.Stat(ctx, path, opts...)
+	if er != nil {
+		return nil, er
+	}
+
+	if info.PartsCount == 0 {
+		return []PartInfo{{Number: 1, Size: info.Size}}, nil
+	}
+
+	parts := make([]PartInfo, 0, info.PartsCount)
+	var offset int64
+
+	for number := 1; number <= info.PartsCount; number++ {
+		header, er := s3.headPart(ctx, path, number, opts)
+		if er != nil {
+			return nil, er
+		}
+
+		size, er := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if er != nil {
+			return nil, fmt.Errorf("s3: missing size of part %d of %s", number, path)
+		}
+
+		parts = append(parts, PartInfo{Number: number, Offset: offset, Size: size})
+		offset += size
+	}
+
+	return parts, nil
+}
+
+// headPart makes a HEAD request for part number of the object at path, returning the response
+// headers.
+func (s3 *S3) headPart(ctx context.Context, path string, number int, opts []RequestOption) (http.Header, error) {
+	req, er := http.NewRequestWithContext(ctx, "HEAD", s3.resource(path, url.Values{"partNumber": {strconv.Itoa(number)}}), nil)
+	if er != nil {
+		return nil, er
+	}
+
+	resp, er := s3.do(req, opts...)
+	if er != nil {
+		return nil, er
+	}
+	resp.Body.Close()
+
+	return resp.Header, nil
+}
+
+// Exists reports whether there is an object at path, with a HEAD request. A missing object is not
+// an error; anything else that stops S3 from answering, including a 403 for credentials that may
+// not read the object, is.
+func (s3 *S3) Exists(ctx context.Context, path string, opts ...RequestOption) (bool, error) {
+	_, er := s3.Head(ctx, path, opts...)
+	if isNotFound(er) {
+		return false, nil
+
+	} else if er != nil {
+		return false, er
+	}
+
+	return true, nil
+}
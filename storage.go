@@ -0,0 +1,37 @@
+package s3
+
+import (
+	"net/http"
+)
+
+// Storage classes, which trade the price of storing objects against the price and speed of
+// reading them. Objects in StorageGlacier and StorageDeepArchive must be restored before they
+// can be read (see PlanRestore).
+const (
+	StorageStandard           = "STANDARD"
+	StorageIntelligentTiering = "INTELLIGENT_TIERING"
+	StorageStandardIA         = "STANDARD_IA"
+	StorageOneZoneIA          = "ONEZONE_IA"
+	StorageGlacierIR          = "GLACIER_IR"
+	StorageGlacier            = "GLACIER"
+	StorageDeepArchive        = "DEEP_ARCHIVE"
+)
+
+// WithStorageClass stores the object being uploaded in a storage class other than
+// StorageStandard, such as StorageStandardIA. It applies to Put, Copy and StartMultipart; a Copy
+// with it can also move an existing object to another class. Infrequent access classes bill for
+// at least 128KB per object and 30 days of storage, so they only pay off for large objects that
+// are kept.
+func WithStorageClass(class string) RequestOption {
+	return WithHeader("x-amz-storage-class", class)
+}
+
+// StorageClass returns the storage class of an object from the headers of a response to Head or
+// Get. S3 leaves the header out for StorageStandard.
+func StorageClass(header http.Header) string {
+	if class := header.Get("x-amz-storage-class"); class != "" {
+		return class
+	}
+
+	return StorageStandard
+}
@@ -0,0 +1,259 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	streamingPayload        = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingPayloadTrailer = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+
+	// streamingChunkSize is the size of each chunk of a streamed upload but the last.
+	streamingChunkSize = 64 * 1024
+
+	// emptySHA256 is the hex-encoded SHA-256 of nothing.
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// SetStreamingUploads makes Put and AddPart sign their content chunk by chunk as it is sent
+// (STREAMING-AWS4-HMAC-SHA256-PAYLOAD), rather than leaving it unsigned, so that it is
+// authenticated without having to be hashed before it is sent, even over plain HTTP. It only
+// applies to requests signed with Signature Version 4 (see SetSignatureV4).
+//
+// With a checksum algorithm set (see SetChecksumAlgorithm), the checksum is computed as the content
+// is sent, and sent after it in a signed trailer, so the content needn't be read twice: Put sends
+// a checksum for any content, and AddPart accepts parts that can't be rewound.
+func (s3 *S3) SetStreamingUploads(enabled bool) {
+	s3.streaming = enabled
+}
+
+// streamsUploads reports whether uploads are streamed in signed chunks.
+func (s3 *S3) streamsUploads() bool {
+	return s3.streaming && s3.sigV4 && s3.express == nil && !s3.anonymous && s3.signer == nil &&
+		(s3.accessPoint == nil || !s3.accessPoint.multiRegion)
+}
+
+// chunkedBody encodes the body of a streamed upload in signed chunks, with a trailer carrying
+// the checksum of the content if it has an algorithm. The signing parameters are filled in by
+// start once the request itself has been signed, since every chunk's signature chains on from
+// the one before it, beginning with the request's.
+type chunkedBody struct {
+	raw       io.ReadCloser
+	content   io.Reader // raw, limited to size.
+	size      int64
+	algorithm string
+
+	checksum hash.Hash
+	value    string // The base64-encoded checksum, once all of the content has been read.
+
+	key     []byte
+	amzDate string
+	scope   string
+	seedSig string
+	prevSig string
+
+	chunk   []byte
+	encoded bytes.Buffer
+	done    bool
+}
+
+// streamBody arranges for the size bytes of the body of req to be uploaded in signed chunks, if
+// the S3 streams uploads, returning the chunkedBody that will encode it (or nil if it doesn't). A
+// trailer carries the checksum of the content computed with algorithm, unless it is empty.
+func (s3 *S3) streamBody(req *http.Request, size int64, algorithm string) *chunkedBody {
+	if !s3.streamsUploads() {
+		return nil
+	}
+
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+
+	cb := &chunkedBody{size: size, algorithm: algorithm, chunk: make([]byte, streamingChunkSize)}
+	cb.reset(req.Body)
+
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			raw, er := getBody()
+			if er != nil {
+				return nil, er
+			}
+
+			cb.reset(raw)
+			return cb, nil
+		}
+	}
+
+	req.Body = cb
+	req.Header.Set("X-Amz-Content-Sha256", streamingPayload)
+	req.Header.Set("x-amz-decoded-content-length", strconv.FormatInt(size, 10))
+
+	if algorithm != "" {
+		req.Header.Set("X-Amz-Content-Sha256", streamingPayloadTrailer)
+		req.Header.Set("x-amz-trailer", checksumHeaderName(algorithm))
+	}
+
+	req.ContentLength = cb.encodedLength()
+	req.Header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+
+	return cb
+}
+
+// reset starts encoding raw from the beginning, with the signatures chaining on from the same
+// request signature as before, as when the request is sent again without being signed again.
+func (cb *chunkedBody) reset(raw io.ReadCloser) {
+	cb.raw = raw
+	cb.content = io.LimitReader(raw, cb.size)
+	cb.encoded.Reset()
+	cb.done = false
+	cb.value = ""
+	cb.prevSig = cb.seedSig
+
+	if cb.algorithm != "" {
+		cb.checksum = newChecksum(cb.algorithm)
+	}
+}
+
+// start has the chunks signed on from seedSig, the signature of the request, which was signed at
+// now for region with secret.
+func (cb *chunkedBody) start(secret, region string, now time.Time, seedSig string) {
+	cb.amzDate = now.UTC().Format(v4TimeFormat)
+	date := cb.amzDate[:8]
+
+	cb.key = v4SigningKey(secret, date, region, "s3")
+	cb.scope = v4Scope(date, region, "s3")
+	cb.seedSig = seedSig
+	cb.prevSig = seedSig
+}
+
+// encodedLength returns the length of the body once encoded, which is sent as its Content-Length.
+func (cb *chunkedBody) encodedLength() int64 {
+	frame := func(n int64) int64 {
+		return int64(len(strconv.FormatInt(n, 16))+len(";chunk-signature=")+64+2) + n + 2
+	}
+
+	length := (cb.size / streamingChunkSize) * frame(streamingChunkSize)
+	if rem := cb.size % streamingChunkSize; rem > 0 {
+		length += frame(rem)
+	}
+
+	/* The final, empty chunk has no data but does have the line ending it */
+	length += frame(0) - 2
+
+	if cb.algorithm == "" {
+		return length + 2
+	}
+
+	valueLen := base64.StdEncoding.EncodedLen(newChecksum(cb.algorithm).Size())
+	trailer := len(checksumHeaderName(cb.algorithm)) + 1 + valueLen + 2 + len("x-amz-trailer-signature:") + 64 + 2
+
+	return length + int64(trailer) + 2
+}
+
+// sign returns the signature of the next piece of the body, given the algorithm naming it and
+// the hash of its content.
+func (cb *chunkedBody) sign(algorithm, contentHash string) string {
+	parts := []string{algorithm, cb.amzDate, cb.scope, cb.prevSig}
+	if algorithm == "AWS4-HMAC-SHA256-PAYLOAD" {
+		parts = append(parts, emptySHA256)
+	}
+
+	stringToSign := strings.Join(append(parts, contentHash), "\n")
+	cb.prevSig = hex.EncodeToString(hmacSHA256(cb.key, stringToSign))
+
+	return cb.prevSig
+}
+
+// writeChunk encodes a chunk holding data.
+func (cb *chunkedBody) writeChunk(data []byte) {
+	signature := cb.sign("AWS4-HMAC-SHA256-PAYLOAD", sha256Hex(string(data)))
+	fmt.Fprintf(&cb.encoded, "%x;chunk-signature=%s\r\n", len(data), signature)
+
+	if len(data) > 0 {
+		cb.encoded.Write(data)
+		cb.encoded.WriteString("\r\n")
+	}
+}
+
+func (cb *chunkedBody) Read(p []byte) (int, error) {
+	for cb.encoded.Len() == 0 {
+		if cb.done {
+			return 0, io.EOF
+		}
+
+		n, er := io.ReadFull(cb.content, cb.chunk)
+		if er != nil && er != io.EOF && er != io.ErrUnexpectedEOF {
+			return 0, er
+		}
+
+		if n > 0 {
+			if cb.checksum != nil {
+				cb.checksum.Write(cb.chunk[:n])
+			}
+
+			cb.writeChunk(cb.chunk[:n])
+		}
+
+		if n == len(cb.chunk) {
+			continue
+		}
+
+		/* The content has ended: finish with an empty chunk, and then the trailer */
+		cb.writeChunk(nil)
+
+		if cb.checksum != nil {
+			cb.value = base64.StdEncoding.EncodeToString(cb.checksum.Sum(nil))
+
+			trailer := checksumHeaderName(cb.algorithm) + ":" + cb.value
+			signature := cb.sign("AWS4-HMAC-SHA256-TRAILER", sha256Hex(trailer+"\n"))
+
+			fmt.Fprintf(&cb.encoded, "%s\r\nx-amz-trailer-signature:%s\r\n", trailer, signature)
+		}
+
+		cb.encoded.WriteString("\r\n")
+		cb.done = true
+	}
+
+	return cb.encoded.Read(p)
+}
+
+func (cb *chunkedBody) Close() error {
+	return cb.raw.Close()
+}
+
+// startStreaming prepares req, whose body is a chunkedBody, to be signed: the content encoding is
+// marked, preserving any the content itself has.
+func startStreaming(req *http.Request) (*chunkedBody, bool) {
+	cb, ok := req.Body.(*chunkedBody)
+	if !ok {
+		return nil, false
+	}
+
+	if encoding := req.Header.Get("Content-Encoding"); encoding == "" {
+		req.Header.Set("Content-Encoding", "aws-chunked")
+
+	} else if !strings.HasPrefix(encoding, "aws-chunked") {
+		req.Header.Set("Content-Encoding", "aws-chunked,"+encoding)
+	}
+
+	return cb, true
+}
+
+// payloadLength returns the length of the content of req, which is not its Content-Length when
+// it is streamed in chunks.
+func payloadLength(req *http.Request) int64 {
+	if decoded, er := strconv.ParseInt(req.Header.Get("x-amz-decoded-content-length"), 10, 64); er == nil {
+		return decoded
+	}
+
+	return req.ContentLength
+}
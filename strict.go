@@ -0,0 +1,133 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits S3 places on requests, which strict mode checks before sending them.
+const (
+	maxKeyLength      = 1024
+	maxMetadataSize   = 2 * 1024
+	maxPartNumber     = 10000
+	minPartSize       = 5 * 1024 * 1024
+	maxPutSize        = 5 * 1024 * 1024 * 1024
+	maxTags           = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// LimitError is returned in strict mode (see SetStrict) for a request that S3 would reject for
+// breaking one of its limits. It is returned before anything is sent.
+type LimitError struct {
+	Key   string // The key of the object the request is for.
+	What  string // What breaks the limit, such as "key length" or "part number".
+	Value int64
+	Limit int64
+}
+
+func (err *LimitError) Error() string {
+	if err.Value < err.Limit {
+		return fmt.Sprintf("s3: %s of %d for %s is below S3's minimum of %d", err.What, err.Value, err.Key, err.Limit)
+	}
+
+	return fmt.Sprintf("s3: %s of %d for %s is above S3's maximum of %d", err.What, err.Value, err.Key, err.Limit)
+}
+
+// SetStrict turns strict mode on or off. In strict mode, requests are checked against the limits
+// S3 enforces before they are sent: keys of at most 1024 bytes, at most 2KB of user metadata, at
+// most 10 tags with keys of 128 and values of 256 characters, single uploads of at most 5GB, and
+// multipart uploads of at most 10,000 parts, each at least 5MB but the last. Breaking a limit
+// returns a *LimitError saying which, rather than the 400 S3 would respond with, after the body
+// had been sent.
+func (s3 *S3) SetStrict(strict bool) {
+	s3.strict = strict
+}
+
+// checkLimits returns a *LimitError if req breaks one of S3's limits, when in strict mode.
+func (s3 *S3) checkLimits(req *http.Request) error {
+	if !s3.strict {
+		return nil
+	}
+
+	key := s3.requestKey(req)
+
+	limitErr := func(what string, value, limit int64) error {
+		return &LimitError{Key: key, What: what, Value: value, Limit: limit}
+	}
+
+	if len(key) > maxKeyLength {
+		return limitErr("key length", int64(len(key)), maxKeyLength)
+	}
+
+	if number := req.URL.Query().Get("partNumber"); number != "" && req.URL.Query().Has("uploadId") {
+		n, er := strconv.ParseInt(number, 10, 64)
+		if er != nil || n < 1 {
+			return limitErr("part number", n, 1)
+
+		} else if n > maxPartNumber {
+			return limitErr("part number", n, maxPartNumber)
+		}
+
+	} else if size := payloadLength(req); req.Method == "PUT" && size > maxPutSize {
+		return limitErr("upload size", size, maxPutSize)
+	}
+
+	metadataSize := 0
+	for name, vals := range req.Header {
+		if lname := strings.ToLower(name); strings.HasPrefix(lname, metaPrefix) {
+			for _, val := range vals {
+				metadataSize += len(lname) - len(metaPrefix) + len(val)
+			}
+		}
+	}
+
+	if metadataSize > maxMetadataSize {
+		return limitErr("metadata size", int64(metadataSize), maxMetadataSize)
+	}
+
+	if tagging := req.Header.Get("x-amz-tagging"); tagging != "" {
+		tags, er := url.ParseQuery(tagging)
+		if er != nil {
+			return fmt.Errorf("s3: invalid x-amz-tagging header %#v: %w", tagging, er)
+		}
+
+		if len(tags) > maxTags {
+			return limitErr("tag count", int64(len(tags)), maxTags)
+		}
+
+		for name, vals := range tags {
+			if n := utf8.RuneCountInString(name); n > maxTagKeyLength {
+				return limitErr("tag key length", int64(n), maxTagKeyLength)
+			}
+
+			for _, val := range vals {
+				if n := utf8.RuneCountInString(val); n > maxTagValueLength {
+					return limitErr("tag value length", int64(n), maxTagValueLength)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkPartSizes returns a *LimitError if any part of mp numbered below last is too small, when
+// in strict mode. The lock must be held.
+func (mp *S3Multipart) checkPartSizes(last int) error {
+	if !mp.s3.strict {
+		return nil
+	}
+
+	for number, size := range mp.partSizes {
+		if number < last && size < minPartSize {
+			return &LimitError{Key: mp.key, What: fmt.Sprintf("size of part %d", number), Value: size, Limit: minPartSize}
+		}
+	}
+
+	return nil
+}
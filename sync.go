@@ -0,0 +1,324 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type syncOptions struct {
+	deleteExtraneous bool
+	dryRun           bool
+}
+
+// SyncOption customizes the behavior of SyncUp and SyncDown.
+type SyncOption func(*syncOptions)
+
+// SyncDelete makes a sync delete the files (or objects) at the destination that don't exist at
+// the source, so that the destination ends up a mirror of it.
+func SyncDelete() SyncOption {
+	return func(opts *syncOptions) {
+		opts.deleteExtraneous = true
+	}
+}
+
+// SyncDryRun makes a sync work out what it would do, and report it in its SyncResult, without
+// transferring or deleting anything.
+func SyncDryRun() SyncOption {
+	return func(opts *syncOptions) {
+		opts.dryRun = true
+	}
+}
+
+// SyncResult reports what a sync did (or, for a dry run, would have done). Paths are keys for
+// objects, and slash-separated paths relative to the local directory for files.
+type SyncResult struct {
+	Transferred []string // Sources copied to the destination, because they were new or changed.
+	Deleted     []string // Extraneous destination paths deleted; only with SyncDelete.
+	Unchanged   int      // Sources that were already up to date at the destination.
+}
+
+// localFile is a regular file found under the local directory of a sync.
+type localFile struct {
+	path    string // The path of the file on disk.
+	size    int64
+	modTime time.Time
+}
+
+// SyncUp uploads the files under localDir that are missing or different under prefix, as
+// "aws s3 sync" does, using each file's path relative to localDir (with slashes) as the rest of
+// its key. See SyncDown for how files and objects are compared.
+func (s3 *S3) SyncUp(ctx context.Context, localDir, prefix string, opts ...SyncOption) (*SyncResult, error) {
+	options := syncOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	local, er := localFiles(localDir)
+	if er != nil {
+		return nil, er
+	}
+
+	remote, er := s3.remoteObjects(ctx, prefix)
+	if er != nil {
+		return nil, er
+	}
+
+	result := &SyncResult{}
+
+	for _, rel := range sortedKeys(local) {
+		file := local[rel]
+		key := prefix + rel
+
+		if obj, ok := remote[key]; ok {
+			changed, er := s3.syncChanged(ctx, file, obj, true)
+			if er != nil {
+				return result, er
+			}
+
+			if !changed {
+				result.Unchanged++
+				continue
+			}
+		}
+
+		if !options.dryRun {
+			if er := s3.PutFile(ctx, file.path, key); er != nil {
+				return result, er
+			}
+		}
+
+		result.Transferred = append(result.Transferred, key)
+	}
+
+	if !options.deleteExtraneous {
+		return result, nil
+	}
+
+	extraneous := []string{}
+	for _, key := range sortedKeys(remote) {
+		if _, ok := local[strings.TrimPrefix(key, prefix)]; !ok {
+			extraneous = append(extraneous, key)
+		}
+	}
+
+	if !options.dryRun && len(extraneous) > 0 {
+		results, er := s3.DeleteMulti(ctx, extraneous)
+		if er != nil {
+			return result, er
+		}
+
+		for _, res := range results {
+			if !res.Deleted {
+				return result, fmt.Errorf("s3: deleting %s: %s %s", res.Key, res.Code, res.Message)
+			}
+		}
+	}
+
+	result.Deleted = extraneous
+	return result, nil
+}
+
+// SyncDown downloads the objects under prefix that are missing or different under localDir, as
+// "aws s3 sync" does, saving each object at the rest of its key (after prefix) relative to
+// localDir. Downloaded files are given the modification time of their object.
+//
+// A file and an object are the same if they have the same size and the object's ETag is the MD5
+// of the file's content (or, for multipart uploads, the ETag this package would have given it).
+// When an object's ETag can't be compared, because it was uploaded in parts of a different size
+// or is encrypted with SSE-KMS, the file is only transferred if the source is newer than the
+// destination. Listings don't say how objects are encrypted, so an object whose ETag looks like
+// an MD5 but doesn't match the file's is checked for SSE-KMS with a Head.
+//
+// An object whose key, after prefix, isn't a local path (see filepath.IsLocal), such as
+// "prefix/../../.bashrc", would be saved outside localDir, so the sync fails without transferring
+// anything if there is one.
+func (s3 *S3) SyncDown(ctx context.Context, prefix, localDir string, opts ...SyncOption) (*SyncResult, error) {
+	options := syncOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	remote, er := s3.remoteObjects(ctx, prefix)
+	if er != nil {
+		return nil, er
+	}
+
+	local, er := localFiles(localDir)
+	if er != nil {
+		return nil, er
+	}
+
+	for key := range remote {
+		if rel := strings.TrimPrefix(key, prefix); !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, fmt.Errorf("s3: refusing to sync %#v outside %s", key, localDir)
+		}
+	}
+
+	result := &SyncResult{}
+
+	for _, key := range sortedKeys(remote) {
+		obj := remote[key]
+		rel := strings.TrimPrefix(key, prefix)
+
+		if file, ok := local[rel]; ok {
+			changed, er := s3.syncChanged(ctx, file, obj, false)
+			if er != nil {
+				return result, er
+			}
+
+			if !changed {
+				result.Unchanged++
+				continue
+			}
+		}
+
+		if !options.dryRun {
+			path := filepath.Join(localDir, filepath.FromSlash(rel))
+
+			if er := os.MkdirAll(filepath.Dir(path), 0755); er != nil {
+				return result, er
+			}
+
+			if er := s3.GetToFile(ctx, key, path); er != nil {
+				return result, er
+			}
+
+			if er := os.Chtimes(path, obj.LastModified, obj.LastModified); er != nil {
+				return result, er
+			}
+		}
+
+		result.Transferred = append(result.Transferred, key)
+	}
+
+	if !options.deleteExtraneous {
+		return result, nil
+	}
+
+	for _, rel := range sortedKeys(local) {
+		if _, ok := remote[prefix+rel]; ok || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			continue
+		}
+
+		if !options.dryRun {
+			if er := os.Remove(local[rel].path); er != nil {
+				return result, er
+			}
+		}
+
+		result.Deleted = append(result.Deleted, rel)
+	}
+
+	return result, nil
+}
+
+// syncChanged reports whether file and obj differ, for a sync in the direction given by up.
+func (s3 *S3) syncChanged(ctx context.Context, file localFile, obj ObjectSummary, up bool) (bool, error) {
+	if file.size != obj.Size {
+		return true, nil
+	}
+
+	f, er := os.Open(file.path)
+	if er != nil {
+		return false, er
+	}
+	defer f.Close()
+
+	etag, _, er := s3.fileETag(f, file.size)
+	if er != nil {
+		return false, er
+	}
+
+	remoteETag := strings.Trim(obj.ETag, `"`)
+	if remoteETag == etag {
+		return false, nil
+	}
+
+	/* A plain MD5 that doesn't match means the content differs; anything else can't be
+	 * compared, so the newer side wins. The ETags of SSE-KMS objects look like MD5s but
+	 * aren't, and only a Head tells them apart */
+	if len(remoteETag) == 32 && !strings.Contains(remoteETag, "-") && !strings.Contains(etag, "-") {
+		header, er := s3.Head(ctx, obj.Key)
+		if er != nil {
+			return false, er
+		}
+
+		if !strings.HasPrefix(header.Get("x-amz-server-side-encryption"), "aws:kms") {
+			return true, nil
+		}
+	}
+
+	if up {
+		return file.modTime.After(obj.LastModified), nil
+	}
+
+	return obj.LastModified.After(file.modTime), nil
+}
+
+// localFiles finds the regular files under dir, by their slash-separated path relative to it.
+func localFiles(dir string) (map[string]localFile, error) {
+	files := map[string]localFile{}
+
+	er := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, er error) error {
+		/* A directory that doesn't exist yet is simply empty */
+		if er != nil && path == dir && errors.Is(er, fs.ErrNotExist) {
+			return filepath.SkipDir
+
+		} else if er != nil {
+			return er
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, er := entry.Info()
+		if er != nil {
+			return er
+		}
+
+		rel, er := filepath.Rel(dir, path)
+		if er != nil {
+			return er
+		}
+
+		files[filepath.ToSlash(rel)] = localFile{path: path, size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+
+	return files, er
+}
+
+// remoteObjects lists the objects under prefix by key, leaving out the empty "directory" objects
+// some tools create.
+func (s3 *S3) remoteObjects(ctx context.Context, prefix string) (map[string]ObjectSummary, error) {
+	objects := map[string]ObjectSummary{}
+
+	er := s3.Walk(ctx, prefix, func(obj ObjectSummary) error {
+		if !strings.HasSuffix(obj.Key, "/") {
+			objects[obj.Key] = obj
+		}
+
+		return nil
+	})
+
+	return objects, er
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
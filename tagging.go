@@ -0,0 +1,138 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type s3tag struct {
+	Key   string
+	Value string
+}
+
+type s3tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Tags    []s3tag  `xml:"TagSet>Tag"`
+}
+
+// WithTags tags the object being created with tags. It applies to Put, Copy and StartMultipart;
+// a Copy with it replaces the tags of the source rather than copying them. Objects can have at
+// most 10 tags (see SetStrict).
+func WithTags(tags map[string]string) RequestOption {
+	values := url.Values{}
+	for name, value := range tags {
+		values.Set(name, value)
+	}
+
+	return WithHeader("x-amz-tagging", values.Encode())
+}
+
+// tagging returns a request for the tagging sub-resource of the object at path.
+func (s3 *S3) tagging(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	values := url.Values{}
+	values.Set("tagging", "")
+
+	req, er := http.NewRequestWithContext(ctx, method, s3.resource(path, values), body)
+	if er != nil {
+		return nil, er
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+	return req, nil
+}
+
+// GetObjectTagging returns the tags of the object at path.
+func (s3 *S3) GetObjectTagging(ctx context.Context, path string) (map[string]string, error) {
+	req, er := s3.tagging(ctx, "GET", path, nil)
+	if er != nil {
+		return nil, er
+	}
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return nil, er
+	}
+	defer resp.Body.Close()
+
+	xmlBytes, er := io.ReadAll(resp.Body)
+	if er != nil {
+		return nil, er
+	}
+
+	var xmlResp s3tagging
+	if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+		return nil, fmt.Errorf("s3: invalid tagging for %s: %w", path, er)
+	}
+
+	tags := map[string]string{}
+	for _, tag := range xmlResp.Tags {
+		tags[tag.Key] = tag.Value
+	}
+
+	return tags, nil
+}
+
+// PutObjectTagging replaces the tags of the object at path with tags.
+func (s3 *S3) PutObjectTagging(ctx context.Context, path string, tags map[string]string) (er error) {
+	defer func(start time.Time) {
+		s3.audit("PutObjectTagging", path, 0, start, er)
+	}(time.Now())
+
+	body := s3tagging{}
+	for _, name := range sortedKeys(tags) {
+		body.Tags = append(body.Tags, s3tag{Key: name, Value: tags[name]})
+	}
+
+	xmlBody, er := xml.Marshal(body)
+	if er != nil {
+		return er
+	}
+
+	md5sum := md5.Sum(xmlBody)
+
+	req, er := s3.tagging(ctx, "PUT", path, bytes.NewReader(xmlBody))
+	if er != nil {
+		return er
+	}
+
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md5sum[:]))
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(xmlBody)))
+	req.Header.Set("Content-Type", "application/xml")
+	req.ContentLength = int64(len(xmlBody))
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// DeleteObjectTagging removes every tag from the object at path.
+func (s3 *S3) DeleteObjectTagging(ctx context.Context, path string) (er error) {
+	defer func(start time.Time) {
+		s3.audit("DeleteObjectTagging", path, 0, start, er)
+	}(time.Now())
+
+	req, er := s3.tagging(ctx, "DELETE", path, nil)
+	if er != nil {
+		return er
+	}
+
+	resp, er := s3.do(req)
+	if er != nil {
+		return er
+	}
+	resp.Body.Close()
+
+	return nil
+}
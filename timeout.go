@@ -0,0 +1,201 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is wrapped by the error of a request that was abandoned because one of its timeouts
+// (see SetTimeouts) expired. It is temporary, so the request is retried if the retry policy
+// allows.
+var ErrTimeout = errors.New("s3: request timed out")
+
+// Timeouts bound the phases of each attempt at sending a request, independently of its context,
+// so that a connection that has silently died is noticed without limiting how long a transfer
+// that is making progress may take. A zero duration leaves a phase unbounded.
+type Timeouts struct {
+	Connect   time.Duration // Obtaining a connection, including its TLS handshake.
+	FirstByte time.Duration // Waiting for the response once the request has been sent.
+	Stall     time.Duration // Sending or receiving a body without any bytes moving.
+}
+
+// SetTimeouts sets the timeouts of every attempt at sending a request. Unlike a timeout on the
+// HTTP client (see WithTimeout), which bounds the whole of a request including reading its
+// response body, these only expire when a request stops making progress, so they suit multipart
+// uploads and downloads that take hours. The Stall timeout also applies to reading the body
+// returned by Get, whose reads fail with ErrTimeout once S3 stops sending it; time spent by the
+// caller between reads doesn't count.
+func (s3 *S3) SetTimeouts(timeouts Timeouts) {
+	s3.timeouts = timeouts
+}
+
+// sendTimed sends req with client, enforcing the timeouts of the S3.
+func (s3 *S3) sendTimed(client *http.Client, req *http.Request) (*http.Response, error) {
+	if s3.timeouts == (Timeouts{}) {
+		return client.Do(req)
+	}
+
+	tw := newTimeoutWatch(req.Context(), s3.timeouts)
+
+	timed := req.WithContext(httptrace.WithClientTrace(tw.ctx, tw.trace()))
+	if req.Body != nil && req.Body != http.NoBody {
+		timed.Body = &timedBody{ReadCloser: req.Body, watch: tw, sending: true}
+	}
+
+	tw.arm(tw.timeouts.Connect, "connecting", false)
+
+	resp, er := client.Do(timed)
+	if er != nil {
+		tw.stop()
+
+		if cause := tw.expired(); cause != nil {
+			return nil, cause
+		}
+
+		return nil, er
+	}
+
+	resp.Body = &timedBody{ReadCloser: resp.Body, watch: tw}
+	return resp, nil
+}
+
+// timeoutWatch enforces the timeouts of a single attempt at sending a request, cancelling its
+// context with an error wrapping ErrTimeout when one of them expires. The phases of a request
+// follow one another, so a single timer is armed at a time.
+type timeoutWatch struct {
+	timeouts Timeouts
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+
+	lock      sync.Mutex
+	timer     *time.Timer
+	responded bool // Whether the response has begun, after which only the Stall timeout applies.
+}
+
+func newTimeoutWatch(ctx context.Context, timeouts Timeouts) *timeoutWatch {
+	tw := &timeoutWatch{timeouts: timeouts}
+	tw.ctx, tw.cancel = context.WithCancelCause(ctx)
+
+	return tw
+}
+
+// trace returns the hooks that move the watch through the phases of the request.
+func (tw *timeoutWatch) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			tw.disarm(false)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			tw.arm(tw.timeouts.FirstByte, "waiting for the response", false)
+		},
+		GotFirstResponseByte: func() {
+			tw.disarm(true)
+		},
+	}
+}
+
+// arm starts the timer for a phase lasting up to d, replacing that of the previous phase. Once
+// the response has begun, only timers for the response (as forResponse says) are started.
+func (tw *timeoutWatch) arm(d time.Duration, phase string, forResponse bool) {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	if tw.responded && !forResponse {
+		return
+	}
+
+	if tw.timer != nil {
+		tw.timer.Stop()
+		tw.timer = nil
+	}
+
+	if d > 0 {
+		tw.timer = time.AfterFunc(d, func() {
+			tw.cancel(fmt.Errorf("%w: %s for %v", ErrTimeout, phase, d))
+		})
+	}
+}
+
+// disarm stops the timer of the current phase, noting whether the response has begun.
+func (tw *timeoutWatch) disarm(responded bool) {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	if tw.responded && !responded {
+		return
+	}
+
+	tw.responded = tw.responded || responded
+
+	if tw.timer != nil {
+		tw.timer.Stop()
+		tw.timer = nil
+	}
+}
+
+// stop ends the watch once the request is over.
+func (tw *timeoutWatch) stop() {
+	tw.disarm(true)
+	tw.cancel(nil)
+}
+
+// expired returns the error the request was abandoned with, if one of its timeouts expired.
+func (tw *timeoutWatch) expired() error {
+	if cause := context.Cause(tw.ctx); errors.Is(cause, ErrTimeout) {
+		return cause
+	}
+
+	return nil
+}
+
+// timedBody is the body of a request being sent, or of its response, whose transfer is abandoned
+// if it stalls for the Stall timeout.
+type timedBody struct {
+	io.ReadCloser
+	watch   *timeoutWatch
+	sending bool
+}
+
+func (tb *timedBody) Read(p []byte) (int, error) {
+	tw := tb.watch
+
+	/* Time spent by the caller between reads of the response isn't a stall, but time spent by
+	 * the transport writing what was read of the request is */
+	if !tb.sending {
+		tw.arm(tw.timeouts.Stall, "receiving stalled", true)
+	}
+
+	n, er := tb.ReadCloser.Read(p)
+
+	switch {
+	case tb.sending && er == nil:
+		tw.arm(tw.timeouts.Stall, "sending stalled", false)
+	case tb.sending:
+		tw.disarm(false)
+	default:
+		tw.disarm(true)
+	}
+
+	if er != nil && er != io.EOF {
+		if cause := tw.expired(); cause != nil {
+			return n, cause
+		}
+	}
+
+	return n, er
+}
+
+func (tb *timedBody) Close() error {
+	er := tb.ReadCloser.Close()
+	if !tb.sending {
+		tb.watch.stop()
+	}
+
+	return er
+}
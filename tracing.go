@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Tracer starts the spans of a distributed trace. See the s3otel package for a Tracer recording
+// them with OpenTelemetry.
+type Tracer interface {
+	// Start begins a span named name, as a child of the span ctx carries, if any, returning a
+	// context carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttribute records an attribute of the span, whose value is a string or an int.
+	SetAttribute(key string, value interface{})
+
+	// End ends the span, which failed with er unless it is nil.
+	End(er error)
+}
+
+// SetTracer has the S3 trace its operations with tracer, passing nil disables tracing. Put,
+// PutStream, Get, Head, Delete, Copy, StartMultipart, and Complete and Abort of multipart uploads
+// each have a span (named "s3.Put" and so on), with a child span for each part uploaded, and
+// beneath those a span for each attempt at sending a request, named after the S3 API operation
+// ("s3.PutObject", "s3.UploadPart", ...). Spans carry the bucket and key, and attempts the status
+// code S3 responded with and the ID it assigned the request. The span of Get ends once the
+// response has arrived, not once its body has been read.
+func (s3 *S3) SetTracer(tracer Tracer) {
+	s3.tracer = tracer
+}
+
+// startSpan starts the span of an operation on key, returning the context to make its requests
+// with and the function that ends it.
+func (s3 *S3) startSpan(ctx context.Context, operation, key string) (context.Context, func(er error)) {
+	if s3.tracer == nil {
+		return ctx, func(error) {}
+	}
+
+	ctx, span := s3.tracer.Start(ctx, "s3."+operation)
+	span.SetAttribute("aws.s3.bucket", s3.bucket)
+	if key != "" {
+		span.SetAttribute("aws.s3.key", key)
+	}
+
+	return ctx, span.End
+}
+
+// traceAttempt starts the span of an attempt at sending req, returning the function that ends it
+// with the outcome.
+func (s3 *S3) traceAttempt(req *http.Request, attempt int) func(resp *http.Response, er error) {
+	if s3.tracer == nil {
+		return func(*http.Response, error) {}
+	}
+
+	key := s3.requestKey(req)
+
+	_, span := s3.tracer.Start(req.Context(), "s3."+requestOperation(req, key))
+	span.SetAttribute("aws.s3.bucket", s3.bucket)
+	if key != "" {
+		span.SetAttribute("aws.s3.key", key)
+	}
+
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("http.request.resend_count", attempt-1)
+
+	return func(resp *http.Response, er error) {
+		var s3er *S3Error
+
+		switch {
+		case er == nil:
+			span.SetAttribute("http.response.status_code", resp.StatusCode)
+			span.SetAttribute("aws.request_id", resp.Header.Get("x-amz-request-id"))
+		case errors.As(er, &s3er):
+			span.SetAttribute("http.response.status_code", s3er.Code)
+			span.SetAttribute("aws.request_id", s3er.RequestId)
+		}
+
+		span.End(er)
+	}
+}
@@ -0,0 +1,234 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Transfer directions for TransferPlan.
+const (
+	TransferUpload   = "upload"
+	TransferDownload = "download"
+)
+
+// TransferPlan describes the transfer of one large object split into tasks, each moving a range
+// of the object, that can be carried out independently. Plans (and tasks) marshal to JSON, so a
+// coordinator can make a plan with PlanUpload or PlanDownload, hand its tasks out to a fleet of
+// workers that each run some of them with RunTask, collect the completed tasks they report back
+// with Record, and finally call FinishTransfer. A plan saved along the way doubles as a checkpoint
+// to resume the transfer from.
+type TransferPlan struct {
+	Direction   string         `json:"direction"`
+	Bucket      string         `json:"bucket"`
+	Key         string         `json:"key"`
+	Size        int64          `json:"size"`
+	ETag        string         `json:"etag,omitempty"`         // Of the object being downloaded.
+	UploadId    string         `json:"upload_id,omitempty"`    // Of the multipart upload.
+	ContentType string         `json:"content_type,omitempty"` // Of the object being uploaded.
+	Tasks       []TransferTask `json:"tasks"`
+}
+
+// TransferTask is a single range of a TransferPlan. Done and ETag are filled in by RunTask.
+type TransferTask struct {
+	Part   int    `json:"part"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	Done   bool   `json:"done"`
+	ETag   string `json:"etag,omitempty"` // Of the uploaded part.
+}
+
+// planTasks splits size bytes into tasks of taskSize, or of partSize if taskSize isn't positive.
+func planTasks(size, taskSize int64) []TransferTask {
+	if taskSize <= 0 {
+		taskSize = partSize
+	}
+
+	tasks := []TransferTask{}
+
+	for offset := int64(0); offset < size || len(tasks) == 0; offset += taskSize {
+		length := taskSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		tasks = append(tasks, TransferTask{Part: len(tasks) + 1, Offset: offset, Length: length})
+	}
+
+	return tasks
+}
+
+// PlanUpload starts a multipart upload of size bytes to path, and returns the plan for uploading
+// them in tasks of taskSize bytes (the part size, see SetPartSize, if taskSize is zero). Every
+// task but the last must be at least 5MB, and there can be no more than 10,000 of them. The
+// upload is left in place if ctx is cancelled, since workers carry it on independently; abort it
+// with AbortTransfer if it is abandoned. Any opts are applied to the initiation of the upload.
+func (s3 *S3) PlanUpload(ctx context.Context, path string, size, taskSize int64, contentType string, opts ...RequestOption) (*TransferPlan, error) {
+	if taskSize <= 0 {
+		taskSize = s3.uploadPartSize()
+	}
+
+	tasks := planTasks(size, taskSize)
+	if len(tasks) > 10000 {
+		return nil, fmt.Errorf("s3: cannot upload %d bytes in %d parts; the limit is 10,000", size, len(tasks))
+	}
+
+	/* Plans record the ETags of the parts alone, so they are uploaded without checksums (see
+	 * SetChecksumAlgorithm), but S3 only takes the Content-Type when the upload is started */
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	mp, er := s3.startMultipart(ctx, path, header, append(opts, KeepOnCancel()))
+	if er != nil {
+		return nil, er
+	}
+
+	return &TransferPlan{
+		Direction:   TransferUpload,
+		Bucket:      s3.bucket,
+		Key:         path,
+		Size:        size,
+		UploadId:    mp.UploadId(),
+		ContentType: contentType,
+		Tasks:       tasks,
+	}, nil
+}
+
+// PlanDownload returns the plan for downloading the object at path in tasks of taskSize bytes
+// (7MB if taskSize is zero). Every task is made conditional on the object's current ETag, so that
+// a download can't mix bytes from two versions of it.
+func (s3 *S3) PlanDownload(ctx context.Context, path string, taskSize int64) (*TransferPlan, error) {
+	header, er := s3.Head(ctx, path)
+	if er != nil {
+		return nil, er
+	}
+
+	size, er := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if er != nil {
+		return nil, fmt.Errorf("s3: missing size of %s", path)
+	}
+
+	return &TransferPlan{
+		Direction: TransferDownload,
+		Bucket:    s3.bucket,
+		Key:       path,
+		Size:      size,
+		ETag:      header.Get("ETag"),
+		Tasks:     planTasks(size, taskSize),
+	}, nil
+}
+
+// RunTask carries out task of plan, uploading its range from (or downloading it to) the same
+// offsets of data, which must be an io.ReaderAt for uploads and an io.WriterAt for downloads. It
+// returns the completed task, to be reported back to whoever is recording the plan's progress.
+func (s3 *S3) RunTask(ctx context.Context, plan *TransferPlan, task TransferTask, data interface{}) (TransferTask, error) {
+	if plan.Bucket != s3.bucket {
+		return task, fmt.Errorf("s3: the plan is for bucket %s, not %s", plan.Bucket, s3.bucket)
+	}
+
+	switch plan.Direction {
+	case TransferUpload:
+		r, ok := data.(io.ReaderAt)
+		if !ok {
+			return task, fmt.Errorf("s3: uploading requires an io.ReaderAt, not %T", data)
+		}
+
+		mp := newMultipart(ctx, s3, plan.Key, plan.UploadId, []RequestOption{KeepOnCancel()})
+
+		/* A part that fails is read again and retried, as the part retry policy allows */
+		er := s3.retryPart(ctx, func() error {
+			md5sum, er := sectionMD5(r, task.Offset, task.Length)
+			if er != nil {
+				return er
+			}
+
+			task.ETag, _, er = mp.sendPart(task.Part, io.NewSectionReader(r, task.Offset, task.Length), task.Length, md5sum)
+			return er
+		})
+
+		if er != nil {
+			return task, er
+		}
+
+	case TransferDownload:
+		w, ok := data.(io.WriterAt)
+		if !ok {
+			return task, fmt.Errorf("s3: downloading requires an io.WriterAt, not %T", data)
+		}
+
+		if task.Length == 0 {
+			break
+		}
+
+		opts := []RequestOption{}
+		if plan.ETag != "" {
+			opts = append(opts, WithHeader("If-Match", plan.ETag))
+		}
+
+		if er := s3.downloadRange(ctx, plan.Key, w, task, opts, nil); er != nil {
+			return task, er
+		}
+
+	default:
+		return task, fmt.Errorf("s3: unknown transfer direction %#v", plan.Direction)
+	}
+
+	task.Done = true
+	return task, nil
+}
+
+// Record stores a task completed by RunTask in the plan.
+func (plan *TransferPlan) Record(task TransferTask) error {
+	if task.Part < 1 || task.Part > len(plan.Tasks) {
+		return fmt.Errorf("s3: the plan has no part %d", task.Part)
+	}
+
+	plan.Tasks[task.Part-1] = task
+	return nil
+}
+
+// Remaining returns the tasks that haven't been completed yet.
+func (plan *TransferPlan) Remaining() []TransferTask {
+	remaining := []TransferTask{}
+
+	for _, task := range plan.Tasks {
+		if !task.Done {
+			remaining = append(remaining, task)
+		}
+	}
+
+	return remaining
+}
+
+// FinishTransfer concludes plan once every task has been recorded as done, completing the
+// multipart upload for uploads.
+func (s3 *S3) FinishTransfer(ctx context.Context, plan *TransferPlan) error {
+	if remaining := plan.Remaining(); len(remaining) > 0 {
+		return fmt.Errorf("s3: cannot finish the transfer of %s with %d tasks outstanding", plan.Key, len(remaining))
+	}
+
+	if plan.Direction != TransferUpload {
+		return nil
+	}
+
+	mp := newMultipart(ctx, s3, plan.Key, plan.UploadId, []RequestOption{KeepOnCancel()})
+	for _, task := range plan.Tasks {
+		mp.etags = append(mp.etags, task.ETag)
+	}
+
+	return mp.Complete(plan.ContentType)
+}
+
+// AbortTransfer abandons plan, aborting the multipart upload for uploads so that the parts
+// already sent stop accruing storage charges.
+func (s3 *S3) AbortTransfer(ctx context.Context, plan *TransferPlan) error {
+	if plan.Direction != TransferUpload {
+		return nil
+	}
+
+	return newMultipart(ctx, s3, plan.Key, plan.UploadId, []RequestOption{KeepOnCancel()}).abort(ctx)
+}
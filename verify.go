@@ -0,0 +1,140 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	pathpkg "path"
+	"strings"
+)
+
+// ErrVerification is returned (wrapped) by GetVerified and Ed25519Verifier when a signature or
+// checksum doesn't match, as opposed to failing to be checked at all.
+var ErrVerification = errors.New("s3: verification failed")
+
+// Verifier checks a detached signature over a checksum manifest (such as a SHA256SUMS file),
+// returning an error if the signature is not valid.
+type Verifier interface {
+	Verify(manifest, signature []byte) error
+}
+
+// Ed25519Verifier is a Verifier for raw Ed25519 signatures made with the private key
+// corresponding to the wrapped public key.
+type Ed25519Verifier ed25519.PublicKey
+
+// Verify checks that signature is a valid Ed25519 signature of manifest.
+func (key Ed25519Verifier) Verify(manifest, signature []byte) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("s3: invalid Ed25519 public key (%d bytes)", len(key))
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(key), manifest, signature) {
+		return fmt.Errorf("%w: manifest signature is not valid", ErrVerification)
+	}
+
+	return nil
+}
+
+// manifestDigest finds the SHA-256 digest recorded for path in a manifest in the format written
+// by sha256sum: one "<hex digest>  <name>" line per file, where the name may be prefixed with
+// "*" to indicate binary mode. Entries may name either the full key or just its last element.
+func manifestDigest(manifest []byte, path string) ([]byte, error) {
+	base := pathpkg.Base(path)
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.TrimSpace(fields[1]), "*")
+		if name != path && name != base {
+			continue
+		}
+
+		digest, er := hex.DecodeString(fields[0])
+		if er != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("s3: malformed manifest entry for %#v", name)
+		}
+
+		return digest, nil
+	}
+
+	if er := scanner.Err(); er != nil {
+		return nil, er
+	}
+
+	return nil, fmt.Errorf("s3: manifest has no entry for %#v", path)
+}
+
+// verifyingReader hashes everything read through it, and fails the read that reaches EOF if the
+// content did not match the expected digest, which came from source.
+type verifyingReader struct {
+	io.ReadCloser
+	hash     hash.Hash
+	expected []byte
+	path     string
+	source   string
+}
+
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	n, er := vr.ReadCloser.Read(p)
+	vr.hash.Write(p[:n])
+
+	if er == io.EOF && !bytes.Equal(vr.hash.Sum(nil), vr.expected) {
+		return n, fmt.Errorf("%w: content of %#v does not match %s", ErrVerification, vr.path, vr.source)
+	}
+
+	return n, er
+}
+
+// GetVerified is like Get, but checks the object against a signed checksum manifest stored in
+// the bucket, as is common when distributing software artifacts. The manifest at manifestPath
+// lists SHA-256 digests in sha256sum format; the detached signature at signaturePath is checked
+// with verifier before the manifest is trusted.
+//
+// The object itself is verified as it is read: the returned reader reports an error instead of
+// io.EOF if the content does not match its manifest entry. Callers must therefore read to the
+// end and check for errors before acting on the data.
+func (s3 *S3) GetVerified(ctx context.Context, path, manifestPath, signaturePath string, verifier Verifier) (io.ReadCloser, http.Header, error) {
+	manifest, _, er := s3.getBytes(ctx, manifestPath)
+	if er != nil {
+		return nil, http.Header{}, er
+	}
+
+	signature, _, er := s3.getBytes(ctx, signaturePath)
+	if er != nil {
+		return nil, http.Header{}, er
+	}
+
+	if er := verifier.Verify(manifest, signature); er != nil {
+		return nil, http.Header{}, er
+	}
+
+	digest, er := manifestDigest(manifest, path)
+	if er != nil {
+		return nil, http.Header{}, er
+	}
+
+	r, header, er := s3.Get(ctx, path, WithAcceptEncoding("identity"))
+	if er != nil {
+		return nil, header, er
+	}
+
+	return &verifyingReader{
+		ReadCloser: r,
+		hash:       sha256.New(),
+		expected:   digest,
+		path:       path,
+		source:     "the signed manifest",
+	}, header, nil
+}
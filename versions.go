@@ -0,0 +1,178 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ObjectVersion describes one version of an object, as returned by ListObjectVersions.
+type ObjectVersion struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	DeleteMarker bool // The version records the object's deletion, and has no content.
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	StorageClass string
+}
+
+type s3versionsResp struct {
+	XMLName             xml.Name `xml:"ListVersionsResult"`
+	EncodingType        string
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIdMarker string
+
+	/* Versions and delete markers are interleaved in the order they are listed in, which is
+	 * lost if they are unmarshalled into separate slices */
+	Entries []struct {
+		XMLName      xml.Name
+		Key          string
+		VersionId    string
+		IsLatest     bool
+		Size         int64
+		ETag         string
+		LastModified time.Time
+		StorageClass string
+	} `xml:",any"`
+}
+
+// WithVersionId makes Get, Head, Stat or Delete operate on the version versionId of the object,
+// rather than the latest. Deleting a version removes it for good, rather than adding a delete
+// marker.
+func WithVersionId(versionId string) RequestOption {
+	return WithQuery("versionId", versionId)
+}
+
+// ReceiveVersionId stores the version ID S3 gives the object created by the operation (a Put,
+// Copy or multipart upload) in *versionId, or that of the delete marker created by a Delete. It is
+// left alone if the bucket doesn't have versioning enabled.
+func ReceiveVersionId(versionId *string) RequestOption {
+	return func(config *requestConfig) {
+		config.versionId = versionId
+	}
+}
+
+// receiveVersionId stores the version ID in header where config asks for it.
+func (config *requestConfig) receiveVersionId(header http.Header) {
+	if id := header.Get("x-amz-version-id"); id != "" && config.versionId != nil {
+		*config.versionId = id
+	}
+}
+
+// ListObjectVersions lists every version of the objects whose keys begin with prefix, including
+// delete markers, in order of key and then from newest to oldest. ListStartAfter and
+// ListPageSize are supported.
+func (s3 *S3) ListObjectVersions(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectVersion, error) {
+	versions := []ObjectVersion{}
+
+	er := s3.walkVersions(ctx, prefix, func(version ObjectVersion) error {
+		versions = append(versions, version)
+		return nil
+	}, opts...)
+
+	return versions, er
+}
+
+// walkVersions calls fn for every version of the objects whose keys begin with prefix, as
+// ListObjectVersions lists them, fetching the listing one page at a time. It stops at the first
+// error fn returns, and returns it.
+func (s3 *S3) walkVersions(ctx context.Context, prefix string, fn func(ObjectVersion) error, opts ...ListOption) error {
+	options := listOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	keyMarker, versionMarker := options.startAfter, ""
+
+	for {
+		values := url.Values{}
+		values.Set("versions", "")
+		values.Set("encoding-type", "url")
+
+		if prefix != "" {
+			values.Set("prefix", prefix)
+		}
+
+		if keyMarker != "" {
+			values.Set("key-marker", keyMarker)
+		}
+
+		if versionMarker != "" {
+			values.Set("version-id-marker", versionMarker)
+		}
+
+		if options.pageSize > 0 {
+			values.Set("max-keys", fmt.Sprintf("%d", options.pageSize))
+		}
+
+		req, er := http.NewRequestWithContext(ctx, "GET", s3.resource("", values), nil)
+		if er != nil {
+			return er
+		}
+
+		req.Header.Set("Host", req.URL.Host)
+
+		resp, er := s3.do(req)
+		if er != nil {
+			return er
+		}
+
+		xmlBytes, er := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if er != nil {
+			return er
+		}
+
+		var xmlResp s3versionsResp
+		if er := xml.Unmarshal(xmlBytes, &xmlResp); er != nil {
+			return er
+		}
+
+		for _, entry := range xmlResp.Entries {
+			if entry.XMLName.Local != "Version" && entry.XMLName.Local != "DeleteMarker" {
+				continue
+			}
+
+			key := entry.Key
+			if xmlResp.EncodingType == "url" {
+				if key, er = url.QueryUnescape(key); er != nil {
+					return fmt.Errorf("s3: invalid key in listing: %w", er)
+				}
+			}
+
+			er := fn(ObjectVersion{
+				Key:          key,
+				VersionId:    entry.VersionId,
+				IsLatest:     entry.IsLatest,
+				DeleteMarker: entry.XMLName.Local == "DeleteMarker",
+				Size:         entry.Size,
+				ETag:         entry.ETag,
+				LastModified: entry.LastModified,
+				StorageClass: entry.StorageClass,
+			})
+
+			if er != nil {
+				return er
+			}
+		}
+
+		if !xmlResp.IsTruncated {
+			return nil
+		}
+
+		keyMarker, versionMarker = xmlResp.NextKeyMarker, xmlResp.NextVersionIdMarker
+		if xmlResp.EncodingType == "url" {
+			if keyMarker, er = url.QueryUnescape(keyMarker); er != nil {
+				return fmt.Errorf("s3: invalid key marker in listing: %w", er)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+)
+
+// BucketWebsite is the static website configuration of a bucket, which serves its objects from
+// the bucket's website endpoint. Either IndexDocument, or RedirectAllTo, must be set.
+type BucketWebsite struct {
+	IndexDocument string // The suffix served for requests for directories, such as "index.html".
+	ErrorDocument string // The key of the object served with 4xx errors, such as "404.html".
+
+	// RedirectAllTo is the host name every request is redirected to instead, using the protocol
+	// RedirectProtocol ("http" or "https"; the protocol of the request if empty).
+	RedirectAllTo    string
+	RedirectProtocol string
+
+	RoutingRules []WebsiteRoutingRule
+}
+
+// WebsiteRoutingRule redirects the requests that match its conditions. Empty fields are left out.
+type WebsiteRoutingRule struct {
+	/* Conditions; a rule without any applies to every request */
+	KeyPrefixEquals             string
+	HttpErrorCodeReturnedEquals string // Such as "404".
+
+	/* The redirect */
+	HostName             string
+	Protocol             string
+	HttpRedirectCode     string // Such as "301"; 301 if empty.
+	ReplaceKeyPrefixWith string
+	ReplaceKeyWith       string
+}
+
+type s3routingRule struct {
+	Condition *struct {
+		KeyPrefixEquals             string `xml:",omitempty"`
+		HttpErrorCodeReturnedEquals string `xml:",omitempty"`
+	} `xml:",omitempty"`
+	Redirect struct {
+		HostName             string `xml:",omitempty"`
+		Protocol             string `xml:",omitempty"`
+		HttpRedirectCode     string `xml:",omitempty"`
+		ReplaceKeyPrefixWith string `xml:",omitempty"`
+		ReplaceKeyWith       string `xml:",omitempty"`
+	}
+}
+
+type s3website struct {
+	XMLName       xml.Name `xml:"WebsiteConfiguration"`
+	IndexDocument *struct {
+		Suffix string
+	} `xml:",omitempty"`
+	ErrorDocument *struct {
+		Key string
+	} `xml:",omitempty"`
+	RedirectAllRequestsTo *struct {
+		HostName string
+		Protocol string `xml:",omitempty"`
+	} `xml:",omitempty"`
+	RoutingRules []s3routingRule `xml:"RoutingRules>RoutingRule"`
+}
+
+// WithWebsiteRedirect makes requests for the object being uploaded to the bucket's website
+// endpoint redirect to location, another key in the bucket (beginning with a slash) or a URL.
+func WithWebsiteRedirect(location string) RequestOption {
+	return WithHeader("x-amz-website-redirect-location", location)
+}
+
+// GetBucketWebsite returns the website configuration of the bucket, or nil if it isn't
+// configured as a website.
+func (s3 *S3) GetBucketWebsite(ctx context.Context) (*BucketWebsite, error) {
+	var xmlResp s3website
+	if er := s3.getConfig(ctx, "website", &xmlResp); isNoConfig(er) {
+		return nil, nil
+
+	} else if er != nil {
+		return nil, er
+	}
+
+	website := &BucketWebsite{}
+
+	if xmlResp.IndexDocument != nil {
+		website.IndexDocument = xmlResp.IndexDocument.Suffix
+	}
+
+	if xmlResp.ErrorDocument != nil {
+		website.ErrorDocument = xmlResp.ErrorDocument.Key
+	}
+
+	if redirect := xmlResp.RedirectAllRequestsTo; redirect != nil {
+		website.RedirectAllTo = redirect.HostName
+		website.RedirectProtocol = redirect.Protocol
+	}
+
+	for _, xmlRule := range xmlResp.RoutingRules {
+		rule := WebsiteRoutingRule{
+			HostName:             xmlRule.Redirect.HostName,
+			Protocol:             xmlRule.Redirect.Protocol,
+			HttpRedirectCode:     xmlRule.Redirect.HttpRedirectCode,
+			ReplaceKeyPrefixWith: xmlRule.Redirect.ReplaceKeyPrefixWith,
+			ReplaceKeyWith:       xmlRule.Redirect.ReplaceKeyWith,
+		}
+
+		if cond := xmlRule.Condition; cond != nil {
+			rule.KeyPrefixEquals = cond.KeyPrefixEquals
+			rule.HttpErrorCodeReturnedEquals = cond.HttpErrorCodeReturnedEquals
+		}
+
+		website.RoutingRules = append(website.RoutingRules, rule)
+	}
+
+	return website, nil
+}
+
+// PutBucketWebsite configures the bucket as a static website. The objects must also be made
+// readable by everyone, with a bucket policy (see PutBucketPolicy) or ACLs, for the website to
+// serve them.
+func (s3 *S3) PutBucketWebsite(ctx context.Context, website BucketWebsite) error {
+	body := s3website{}
+
+	if website.IndexDocument != "" {
+		body.IndexDocument = &struct {
+			Suffix string
+		}{website.IndexDocument}
+	}
+
+	if website.ErrorDocument != "" {
+		body.ErrorDocument = &struct {
+			Key string
+		}{website.ErrorDocument}
+	}
+
+	if website.RedirectAllTo != "" {
+		body.RedirectAllRequestsTo = &struct {
+			HostName string
+			Protocol string `xml:",omitempty"`
+		}{website.RedirectAllTo, website.RedirectProtocol}
+	}
+
+	for _, rule := range website.RoutingRules {
+		xmlRule := s3routingRule{}
+		xmlRule.Redirect.HostName = rule.HostName
+		xmlRule.Redirect.Protocol = rule.Protocol
+		xmlRule.Redirect.HttpRedirectCode = rule.HttpRedirectCode
+		xmlRule.Redirect.ReplaceKeyPrefixWith = rule.ReplaceKeyPrefixWith
+		xmlRule.Redirect.ReplaceKeyWith = rule.ReplaceKeyWith
+
+		if rule.KeyPrefixEquals != "" || rule.HttpErrorCodeReturnedEquals != "" {
+			xmlRule.Condition = &struct {
+				KeyPrefixEquals             string `xml:",omitempty"`
+				HttpErrorCodeReturnedEquals string `xml:",omitempty"`
+			}{rule.KeyPrefixEquals, rule.HttpErrorCodeReturnedEquals}
+		}
+
+		body.RoutingRules = append(body.RoutingRules, xmlRule)
+	}
+
+	return s3.putConfig(ctx, "website", body, nil)
+}
+
+// DeleteBucketWebsite stops the bucket from being served as a website.
+func (s3 *S3) DeleteBucketWebsite(ctx context.Context) error {
+	return s3.deleteConfig(ctx, "website")
+}
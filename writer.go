@@ -0,0 +1,89 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ObjectWriter uploads everything written to it to an object, implementing io.WriteCloser so that
+// uploads compose with io.Copy and writers such as gzip.Writer and tar.Writer. What is written is
+// collected into parts (of 7MB, unless SetPartSize says otherwise) that are uploaded as they fill,
+// up to the number set with SetUploadConcurrency at once, just as PutStream uploads what it reads;
+// content that ends within the first part is uploaded with a single request when the writer is
+// closed.
+//
+// The object only appears once Close has returned without error. If the upload fails, Write and
+// Close return the error; use CloseWithError to abandon an upload, such as when producing the
+// content fails. Write may not be called concurrently.
+type ObjectWriter struct {
+	pipe *io.PipeWriter
+	done chan struct{}
+
+	once sync.Once
+	er   error // The outcome of the upload, once done is closed.
+}
+
+var _ io.WriteCloser = (*ObjectWriter)(nil)
+
+// NewWriter returns an ObjectWriter uploading to path, whose requests are made with ctx and opts
+// (see PutStream). Cancelling ctx abandons the upload.
+func (s3 *S3) NewWriter(ctx context.Context, path, contentType string, opts ...RequestOption) *ObjectWriter {
+	pr, pw := io.Pipe()
+	w := &ObjectWriter{pipe: pw, done: make(chan struct{})}
+
+	go func() {
+		defer close(w.done)
+
+		w.er = s3.PutStream(ctx, pr, path, contentType, opts...)
+
+		/* Writes that follow a failure return it, rather than blocking forever */
+		if w.er != nil {
+			pr.CloseWithError(w.er)
+		} else {
+			pr.CloseWithError(io.ErrClosedPipe)
+		}
+	}()
+
+	return w
+}
+
+// Write implements io.Writer, returning once p has been buffered or uploaded.
+func (w *ObjectWriter) Write(p []byte) (int, error) {
+	return w.pipe.Write(p)
+}
+
+// Close finishes the upload, returning once the object has been created or the upload has
+// failed.
+func (w *ObjectWriter) Close() error {
+	w.once.Do(func() {
+		w.pipe.Close()
+	})
+
+	<-w.done
+	return w.er
+}
+
+// CloseWithError abandons the upload because of er, aborting the multipart upload if one was
+// started, so that nothing is created. It returns once the upload has been abandoned: nil if it
+// was, or the error the upload had already failed with, which reports an upload that couldn't be
+// aborted and so leaves its parts in storage. Calling Close afterwards returns er (or ErrAborted,
+// if er is nil).
+func (w *ObjectWriter) CloseWithError(er error) error {
+	if er == nil {
+		er = ErrAborted
+	}
+
+	w.once.Do(func() {
+		w.pipe.CloseWithError(er)
+	})
+
+	<-w.done
+
+	if w.er == nil || errors.Is(w.er, er) && !errors.Is(w.er, errAbortFailed) {
+		return nil
+	}
+
+	return w.er
+}